@@ -2,8 +2,9 @@ package app
 
 import (
 	"crypto/tls"
-	"log"
+	"encoding/json"
 	"net"
+	"net/http"
 	"sync"
 	"time"
 
@@ -15,8 +16,11 @@ import (
 	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
 	"code.cloudfoundry.org/scalable-syslog/adapter/internal/ingress"
 	"code.cloudfoundry.org/scalable-syslog/adapter/internal/timeoutwaitgroup"
+	"code.cloudfoundry.org/scalable-syslog/internal/api"
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
 	"code.cloudfoundry.org/scalable-syslog/internal/health"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
+	"code.cloudfoundry.org/scalable-syslog/internal/metrics"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -46,6 +50,8 @@ type Adapter struct {
 
 	adapterServer          *grpc.Server
 	bindingManager         *binding.BindingManager
+	syslogConnector        *egress.SyslogConnector
+	subscriber             *ingress.Subscriber
 	maxBindings            int
 	logsAPIConnCount       int
 	logsAPIConnTTL         time.Duration
@@ -55,10 +61,24 @@ type Adapter struct {
 	syslogDialTimeout      time.Duration
 	syslogIOTimeout        time.Duration
 	skipCertVerify         bool
+	syslogTLSConfig        *tls.Config
+	diskBufferDir          string
+	diskBufferMaxBytes     int64
+	bufferSize             int
+	maxBufferSize          int
+	memoryBudget           int64
+	breakerFailures        int
+	breakerOpenDuration    time.Duration
+	retryPolicy            egress.RetryPolicy
+	drainFlushTimeout      time.Duration
+	schedulerAddr          string
+	advertiseAddr          string
+	schedulerTLSConfig     *tls.Config
 	health                 *health.Health
 	timeoutWaitGroup       *timeoutwaitgroup.TimeoutWaitGroup
 	sourceIndex            string
 	metricsToSyslogEnabled bool
+	logger                 *logging.Logger
 }
 
 // AdapterOption is a type that will manipulate a config
@@ -133,6 +153,107 @@ func WithSyslogSkipCertVerify(b bool) AdapterOption {
 	}
 }
 
+// WithSyslogTLSConfig sets the TLS policy, such as the minimum version,
+// cipher suites and curve preferences, used to connect to syslog-tls and
+// https drains.
+func WithSyslogTLSConfig(c *tls.Config) AdapterOption {
+	return func(a *Adapter) {
+		a.syslogTLSConfig = c
+	}
+}
+
+// WithDiskBuffer queues the envelopes of each binding in dir, using up to
+// maxBytes of disk per binding, so that they survive slow drains and
+// restarts. The disk buffer is disabled by default.
+func WithDiskBuffer(dir string, maxBytes int64) AdapterOption {
+	return func(a *Adapter) {
+		a.diskBufferDir = dir
+		a.diskBufferMaxBytes = maxBytes
+	}
+}
+
+// WithBufferSize sets the number of envelopes buffered in memory for each
+// binding, and the largest size a drain may ask for with the `buffer-size`
+// query parameter.
+func WithBufferSize(size, maxSize int) AdapterOption {
+	return func(a *Adapter) {
+		a.bufferSize = size
+		a.maxBufferSize = maxSize
+	}
+}
+
+// WithMemoryBudget limits the memory used by the buffers of all bindings to
+// the given number of bytes. When the budget is nearly used up envelopes are
+// dropped from the bindings with the largest backlog first. A budget of zero
+// disables the limit.
+func WithMemoryBudget(bytes int64) AdapterOption {
+	return func(a *Adapter) {
+		a.memoryBudget = bytes
+	}
+}
+
+// WithCircuitBreaker disables a drain for openDuration after failures
+// consecutive attempts to write to it failed. The drain is probed before it is
+// enabled again. A failure count of zero disables the circuit breaker.
+func WithCircuitBreaker(failures int, openDuration time.Duration) AdapterOption {
+	return func(a *Adapter) {
+		a.breakerFailures = failures
+		a.breakerOpenDuration = openDuration
+	}
+}
+
+// WithRetryBackoff sets how long to wait between attempts to write to a
+// drain. By default the wait is chosen at random with
+// egress.FullJitterBackoff so that drains that fail together are not retried
+// in lockstep.
+func WithRetryBackoff(b egress.Backoff) AdapterOption {
+	return func(a *Adapter) {
+		a.retryPolicy.Backoff = b
+	}
+}
+
+// WithRetryLimits sets the base and cap durations of the retry backoff and
+// the maximum number of attempts to write an envelope to a drain. Drains can
+// lower these limits but not raise them.
+func WithRetryLimits(base, cap time.Duration, maxRetries int) AdapterOption {
+	return func(a *Adapter) {
+		a.retryPolicy.Base = base
+		a.retryPolicy.Cap = cap
+		a.retryPolicy.MaxRetries = maxRetries
+	}
+}
+
+// WithDrainErrorLogInterval sets how often at most an app is told why writes
+// to its drain fail. It defaults to egress.DefaultErrorLogInterval.
+func WithDrainErrorLogInterval(d time.Duration) AdapterOption {
+	return func(a *Adapter) {
+		a.retryPolicy.ErrorLogInterval = d
+	}
+}
+
+// WithDrainFlushTimeout sets how long the envelopes buffered for a binding
+// are still written to its drain after the binding is deleted or the adapter
+// is stopped. Envelopes still buffered after the timeout are abandoned. It
+// defaults to 10 seconds.
+func WithDrainFlushTimeout(d time.Duration) AdapterOption {
+	return func(a *Adapter) {
+		a.drainFlushTimeout = d
+	}
+}
+
+// WithSchedulerWatch makes the adapter watch its bindings on the scheduler
+// at schedulerAddr, which knows the adapter by advertiseAddr. The bindings
+// are applied as soon as the scheduler streams them, in addition to the
+// bindings the scheduler sends to the adapter server. The adapter does not
+// watch its bindings by default.
+func WithSchedulerWatch(schedulerAddr, advertiseAddr string, tlsConfig *tls.Config) AdapterOption {
+	return func(a *Adapter) {
+		a.schedulerAddr = schedulerAddr
+		a.advertiseAddr = advertiseAddr
+		a.schedulerTLSConfig = tlsConfig
+	}
+}
+
 // WithEnableMetricsToSyslog returns a AdapterOption to override the
 // default setting for writing metrics to syslog. By default this feature is
 // disabled.
@@ -142,8 +263,13 @@ func WithMetricsToSyslogEnabled(enabled bool) AdapterOption {
 	}
 }
 
-// maxRetries for the backoff, results in around an hour of total delay
-const maxRetries int = 22
+// WithLogger sets where the adapter and its bindings write their logs. Every
+// line is tagged with the adapter index. Logs are discarded by default.
+func WithLogger(l *logging.Logger) AdapterOption {
+	return func(a *Adapter) {
+		a.logger = l
+	}
+}
 
 // NewAdapter returns an Adapter
 func NewAdapter(
@@ -171,6 +297,19 @@ func NewAdapter(
 		syslogDialTimeout:      5 * time.Second,
 		syslogIOTimeout:        60 * time.Second,
 		skipCertVerify:         true,
+		syslogTLSConfig:        api.NewTLSConfig(),
+		bufferSize:             egress.DefaultDiodeSize,
+		maxBufferSize:          egress.DefaultDiodeSize,
+		breakerFailures:        20,
+		breakerOpenDuration:    time.Minute,
+		retryPolicy: egress.RetryPolicy{
+			Backoff:          egress.FullJitterBackoff,
+			Base:             time.Millisecond,
+			Cap:              15 * time.Second,
+			MaxRetries:       22,
+			ErrorLogInterval: egress.DefaultErrorLogInterval,
+		},
+		drainFlushTimeout:      10 * time.Second,
 		health:                 health.NewHealth(),
 		timeoutWaitGroup:       timeoutwaitgroup.New(time.Minute),
 		sourceIndex:            sourceIndex,
@@ -180,6 +319,10 @@ func NewAdapter(
 	for _, o := range opts {
 		o(a)
 	}
+	a.logger = a.logger.With(logging.Fields{"adapter_index": sourceIndex})
+
+	registry := metrics.NewRegistry("drain_adapter")
+	metricClient = metrics.NewFanOutClient(metricClient, registry)
 
 	balancers := []ingress.Balancer{
 		ingress.NewIPBalancer(logsEgressAPIAddrWithAZ),
@@ -197,52 +340,142 @@ func NewAdapter(
 		time.Second,
 	)
 
-	constructors := map[string]egress.WriterConstructor{
-		"https": egress.RetryWrapper(
-			egress.NewHTTPSWriter,
-			egress.ExponentialDuration,
-			maxRetries,
-			logClient,
-			sourceIndex,
-		),
-		"syslog": egress.RetryWrapper(
-			egress.NewTCPWriter,
-			egress.ExponentialDuration,
-			maxRetries,
-			logClient,
-			sourceIndex,
-		),
-		"syslog-tls": egress.RetryWrapper(
-			egress.NewTLSWriter,
-			egress.ExponentialDuration,
-			maxRetries,
-			logClient,
-			sourceIndex,
-		),
-	}
+	// metric-documentation-v2: (adapter.egress_bytes) Number of bytes posted
+	// to https drains before compression.
+	bytesMetric := buildMetric(metricClient, "egress_bytes")
+	// metric-documentation-v2: (adapter.egress_sent_bytes) Number of bytes
+	// posted to https drains after compression. The difference from
+	// egress_bytes is the amount saved by drains with compress=gzip.
+	sentBytesMetric := buildMetric(metricClient, "egress_sent_bytes")
 
 	droppedMetrics := map[string]pulseemitter.CounterMetric{
 		// metric-documentation-v2: (adapter.dropped) Number of envelopes dropped
-		// when sending to a syslog drain over https.
-		"https": buildMetric(metricClient, "dropped"),
+		// when sending to a syslog drain over https, tagged with protocol.
+		"https": buildMetric(metricClient, "dropped", protocolTag("https")),
+		// metric-documentation-v2: (adapter.dropped) Number of envelopes dropped
+		// when sending to a syslog drain over syslog, tagged with protocol.
+		"syslog": buildMetric(metricClient, "dropped", protocolTag("syslog")),
 		// metric-documentation-v2: (adapter.dropped) Number of envelopes dropped
-		// when sending to a syslog drain over syslog.
-		"syslog": buildMetric(metricClient, "dropped"),
+		// when sending to a syslog drain over syslog-tls, tagged with protocol.
+		"syslog-tls": buildMetric(metricClient, "dropped", protocolTag("syslog-tls")),
 		// metric-documentation-v2: (adapter.dropped) Number of envelopes dropped
-		// when sending to a syslog drain over syslog-tls.
-		"syslog-tls": buildMetric(metricClient, "dropped"),
+		// when sending to a syslog drain over syslog-udp, tagged with protocol.
+		"syslog-udp": buildMetric(metricClient, "dropped", protocolTag("syslog-udp")),
+	}
+
+	// metric-documentation-v2: (adapter.open_circuit_breakers) Number of
+	// drains that are disabled because writing to them keeps failing.
+	openCircuitsMetric := metricClient.NewGaugeMetric(
+		"open_circuit_breakers",
+		"count",
+		pulseemitter.WithVersion(2, 0),
+	)
+	breakers := egress.NewCircuitBreakers(
+		a.breakerFailures,
+		a.breakerOpenDuration,
+		logClient,
+		sourceIndex,
+		egress.WithOpenCircuitsMetric(openCircuitsMetric),
+	)
+	retryWrapper := func(scheme string, wc egress.WriterConstructor) egress.WriterConstructor {
+		if a.breakerFailures > 0 {
+			wc = breakers.Wrap(wc)
+		}
+
+		// Prometheus only: (adapter.write_duration_seconds) Histogram of how
+		// long each attempt to write to a syslog drain takes, tagged with
+		// protocol.
+		wc = egress.WriteDurationWrapper(wc, registry.NewHistogramMetric(
+			"write_duration_seconds",
+			metrics.DefaultLatencyBuckets,
+			protocolTag(scheme),
+		))
+
+		wc = egress.RetryPolicyWrapper(
+			wc,
+			a.retryPolicy,
+			logClient,
+			sourceIndex,
+		)
+
+		// Prometheus only: (adapter.delivery_lag_seconds) Histogram of the
+		// time between the creation of an envelope and its write to a syslog
+		// drain, or the post of its batch to an https drain, tagged with
+		// protocol.
+		wc = egress.DeliveryLagWrapper(wc, registry.NewHistogramMetric(
+			"delivery_lag_seconds",
+			metrics.DefaultLagBuckets,
+			protocolTag(scheme),
+		))
+
+		// metric-documentation-v2: (adapter.writes) Number of envelopes
+		// written to a syslog drain, tagged with protocol and with result
+		// "success" or "failure". An envelope that is written after retries
+		// counts once.
+		return egress.WriteResultWrapper(
+			wc,
+			buildMetric(metricClient, "writes", protocolTag(scheme), resultTag("success")),
+			buildMetric(metricClient, "writes", protocolTag(scheme), resultTag("failure")),
+		)
+	}
+
+	constructors := map[string]egress.WriterConstructor{
+		"https": retryWrapper("https", egress.HTTPSWriterConstructor(
+			a.syslogTLSConfig,
+			bytesMetric,
+			sentBytesMetric,
+		)),
+		"syslog":     retryWrapper("syslog", egress.NewTCPWriter),
+		"syslog-tls": retryWrapper("syslog-tls", egress.TLSWriterConstructor(a.syslogTLSConfig)),
+		"syslog-udp": retryWrapper("syslog-udp", egress.NewUDPWriter),
 	}
 
 	egressMetrics := map[string]pulseemitter.CounterMetric{
 		// metric-documentation-v2: (adapter.egress) Number of envelopes sent out
-		// to a syslog drain over https.
-		"https": buildMetric(metricClient, "egress"),
+		// to a syslog drain over https, tagged with protocol.
+		"https": buildMetric(metricClient, "egress", protocolTag("https")),
 		// metric-documentation-v2: (adapter.egress) Number of envelopes sent out
-		// to a syslog drain over syslog.
-		"syslog": buildMetric(metricClient, "egress"),
+		// to a syslog drain over syslog, tagged with protocol.
+		"syslog": buildMetric(metricClient, "egress", protocolTag("syslog")),
 		// metric-documentation-v2: (adapter.egress) Number of envelopes sent out
-		// to a syslog drain over syslog-tls.
-		"syslog-tls": buildMetric(metricClient, "egress"),
+		// to a syslog drain over syslog-tls, tagged with protocol.
+		"syslog-tls": buildMetric(metricClient, "egress", protocolTag("syslog-tls")),
+		// metric-documentation-v2: (adapter.egress) Number of envelopes sent out
+		// to a syslog drain over syslog-udp, tagged with protocol.
+		"syslog-udp": buildMetric(metricClient, "egress", protocolTag("syslog-udp")),
+	}
+
+	connectorOpts := []egress.ConnectorOption{
+		egress.WithConstructors(constructors),
+		egress.WithDroppedMetrics(droppedMetrics),
+		egress.WithEgressMetrics(egressMetrics),
+		egress.WithLogClient(logClient, a.sourceIndex),
+		egress.WithLogger(a.logger),
+		egress.WithBufferSize(a.bufferSize, a.maxBufferSize),
+		egress.WithDrainTimeout(a.drainFlushTimeout),
+		egress.WithDrainMetrics(
+			// metric-documentation-v2: (adapter.flushed) Number of buffered
+			// envelopes written to syslog drains after their binding was
+			// deleted or the adapter was stopped.
+			buildMetric(metricClient, "flushed"),
+			// metric-documentation-v2: (adapter.abandoned) Number of buffered
+			// envelopes that could not be written to syslog drains within the
+			// flush timeout after their binding was deleted or the adapter was
+			// stopped.
+			buildMetric(metricClient, "abandoned"),
+		),
+		// Prometheus only: (adapter.queue_age_seconds) Histogram of how long
+		// envelopes wait in the buffers of their binding, in memory and on
+		// disk.
+		egress.WithQueueAgeMetric(registry.NewHistogramMetric(
+			"queue_age_seconds",
+			metrics.DefaultLagBuckets,
+		)),
+	}
+	if a.diskBufferDir != "" {
+		connectorOpts = append(connectorOpts, egress.WithDiskBuffer(
+			egress.NewDiskBuffer(a.diskBufferDir, a.diskBufferMaxBytes),
+		))
 	}
 
 	syslogConnector := egress.NewSyslogConnector(
@@ -253,44 +486,152 @@ func NewAdapter(
 		},
 		a.skipCertVerify,
 		a.timeoutWaitGroup,
-		egress.WithConstructors(constructors),
-		egress.WithDroppedMetrics(droppedMetrics),
-		egress.WithEgressMetrics(egressMetrics),
-		egress.WithLogClient(logClient, a.sourceIndex),
+		connectorOpts...,
 	)
+	a.syslogConnector = syslogConnector
 	subscriber := ingress.NewSubscriber(
 		a.ctx,
 		clientManager,
 		syslogConnector,
 		metricClient,
 		ingress.WithLogClient(logClient, a.sourceIndex),
+		ingress.WithLogger(a.logger),
 		ingress.WithMetricsToSyslogEnabled(a.metricsToSyslogEnabled),
 	)
+	a.subscriber = subscriber
+
+	bindingManagerOpts := []binding.BindingManagerOption{
+		binding.WithMaxBindings(a.maxBindings),
+		binding.WithLogger(a.logger),
+	}
+	if a.memoryBudget > 0 {
+		bindingManagerOpts = append(bindingManagerOpts, binding.WithMemoryBudget(
+			a.memoryBudget,
+			func() []binding.Buffer {
+				writers := syslogConnector.DiodeWriters()
+				buffers := make([]binding.Buffer, 0, len(writers))
+				for _, dw := range writers {
+					buffers = append(buffers, dw)
+				}
+
+				return buffers
+			},
+		))
+	}
 
 	a.bindingManager = binding.NewBindingManager(
 		subscriber,
 		metricClient,
 		logClient,
 		a.sourceIndex,
-		binding.WithMaxBindings(a.maxBindings),
+		bindingManagerOpts...,
+	)
+	a.healthAddr = health.StartServer(
+		a.health,
+		a.healthAddr,
+		health.WithHandler("/buffers", buffersHandler(syslogConnector, a.logger)),
+		health.WithHandler("/circuit_breakers", circuitBreakersHandler(breakers, a.logger)),
+		health.WithHandler("/stats", statsHandler(syslogConnector, a.logger)),
+		health.WithHandler("/metrics", registry),
+		health.WithLogger(a.logger),
 	)
-	a.healthAddr = health.StartServer(a.health, a.healthAddr)
 
 	return a
 }
 
-func buildMetric(m MetricClient, name string) pulseemitter.CounterMetric {
+// buffersHandler reports the buffer occupancy of every binding as JSON.
+func buffersHandler(c *egress.SyslogConnector, logger *logging.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if err := json.NewEncoder(w).Encode(c.Buffers()); err != nil {
+			logger.Errorf("unable to marshal buffers: %s", err)
+		}
+	})
+}
+
+// circuitBreakersHandler reports the circuit breaker state of every binding
+// as JSON.
+func circuitBreakersHandler(b *egress.CircuitBreakers, logger *logging.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if err := json.NewEncoder(w).Encode(b.Status()); err != nil {
+			logger.Errorf("unable to marshal circuit breakers: %s", err)
+		}
+	})
+}
+
+// statsHandler reports the delivery statistics of every binding as JSON.
+func statsHandler(c *egress.SyslogConnector, logger *logging.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if err := json.NewEncoder(w).Encode(c.Stats()); err != nil {
+			logger.Errorf("unable to marshal stats: %s", err)
+		}
+	})
+}
+
+// connectorStats provides the delivery statistics of bindings to the
+// adapter server.
+type connectorStats struct {
+	connector *egress.SyslogConnector
+}
+
+// Stats returns the statistics of the binding. Bindings that have not been
+// connected yet are reported as disconnected.
+func (c connectorStats) Stats(b *v1.Binding) *v1.BindingStats {
+	s, ok := c.connector.BindingStats(b)
+	if !ok {
+		return &v1.BindingStats{State: egress.DrainDisconnected}
+	}
+
+	return &v1.BindingStats{
+		Ingress:            s.Ingress,
+		Egress:             s.Egress,
+		Dropped:            s.Dropped,
+		LastWriteTimestamp: unixNano(s.LastWrite),
+		LastError:          s.LastError,
+		LastErrorTimestamp: unixNano(s.LastErrorTime),
+		RetryAttempt:       uint32(s.RetryAttempt),
+		State:              s.State,
+		MaxLag:             int64(s.MaxLag),
+	}
+}
+
+// unixNano returns the time in nanoseconds since the epoch, or zero for the
+// zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
+
+func buildMetric(m MetricClient, name string, opts ...pulseemitter.MetricOption) pulseemitter.CounterMetric {
 	return m.NewCounterMetric(
 		name,
-		pulseemitter.WithVersion(2, 0),
+		append([]pulseemitter.MetricOption{pulseemitter.WithVersion(2, 0)}, opts...)...,
 	)
 }
 
+// protocolTag tags a metric with the scheme of the drains it is about.
+func protocolTag(scheme string) pulseemitter.MetricOption {
+	return pulseemitter.WithTags(map[string]string{"protocol": scheme})
+}
+
+// resultTag tags a metric with the result of the operation it counts.
+func resultTag(result string) pulseemitter.MetricOption {
+	return pulseemitter.WithTags(map[string]string{"result": result})
+}
+
 // Start starts the adapter health endpoint and gRPC service.
 func (a *Adapter) Start() error {
 	lis, err := net.Listen("tcp", a.adapterServerAddr) // close this listener
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		a.logger.Fatalf("failed to listen: %v", err)
 	}
 
 	kp := keepalive.EnforcementPolicy{
@@ -298,14 +639,23 @@ func (a *Adapter) Start() error {
 		PermitWithoutStream: true,
 	}
 
-	adapterServer := binding.NewAdapterServer(a.bindingManager, a.health)
+	adapterServer := binding.NewAdapterServer(
+		a.bindingManager,
+		a.health,
+		binding.WithStatsProvider(connectorStats{connector: a.syslogConnector}),
+		binding.WithStreamChecker(a.subscriber),
+	)
 	grpcServer := grpc.NewServer(
 		grpc.Creds(credentials.NewTLS(a.adapterServerTLSConfig)),
 		grpc.KeepaliveEnforcementPolicy(kp),
 	)
 	v1.RegisterAdapterServer(grpcServer, adapterServer)
 
-	log.Printf("Adapter server is listening on %s", lis.Addr().String())
+	if a.schedulerAddr != "" {
+		a.watchBindings()
+	}
+
+	a.logger.Infof("adapter server is listening on %s", lis.Addr().String())
 	a.adapterServer = grpcServer
 
 	a.mu.Lock()
@@ -315,6 +665,28 @@ func (a *Adapter) Start() error {
 	return grpcServer.Serve(lis)
 }
 
+// watchBindings applies the bindings the scheduler streams to the adapter
+// until the adapter stops.
+func (a *Adapter) watchBindings() {
+	conn, err := grpc.Dial(
+		a.schedulerAddr,
+		grpc.WithTransportCredentials(credentials.NewTLS(a.schedulerTLSConfig)),
+	)
+	if err != nil {
+		a.logger.Errorf("failed to dial scheduler: %s", err)
+		return
+	}
+
+	watcher := binding.NewWatcher(
+		v1.NewSchedulerClient(conn),
+		a.advertiseAddr,
+		a.bindingManager,
+		a.health,
+		binding.WithWatchLogger(a.logger.With(logging.Fields{"scheduler": a.schedulerAddr})),
+	)
+	go watcher.Run(a.ctx)
+}
+
 func (a *Adapter) HealthAddr() string {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -327,13 +699,22 @@ func (a *Adapter) ServerAddr() string {
 	return a.adapterServerAddr
 }
 
+// Stop stops receiving bindings and logs, and waits for the logs buffered
+// for every binding to be written to its drain, up to the drain flush
+// timeout.
 func (a *Adapter) Stop() {
-	log.Printf("Draining connections...")
+	a.logger.Infof("draining connections")
 
 	a.adapterServer.Stop()
+	a.bindingManager.Stop()
 	a.cancel()
 	a.timeoutWaitGroup.Wait()
 
-	log.Printf("Done draining connections.")
-	log.Println("Shutting down adapter server")
+	flushed, abandoned := a.syslogConnector.Drained()
+	a.logger.Infof(
+		"done draining connections: flushed %d buffered logs, abandoned %d",
+		flushed,
+		abandoned,
+	)
+	a.logger.Infof("shutting down adapter server")
 }
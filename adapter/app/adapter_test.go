@@ -111,6 +111,102 @@ var _ = Describe("Adapter", func() {
 			Expect(body).To(MatchJSON(`{"drainCount": 1}`))
 		})
 
+		It("reports the buffer of each binding", func() {
+			_, err := client.CreateBinding(context.Background(), &v1.CreateBindingRequest{
+				Binding: binding,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			buffers := func() string {
+				resp, err := http.Get(fmt.Sprintf("http://%s/buffers", adapterHealthAddr))
+				if err != nil {
+					return ""
+				}
+				defer resp.Body.Close()
+
+				body, _ := ioutil.ReadAll(resp.Body)
+				return string(body)
+			}
+
+			Eventually(buffers).Should(MatchJSON(fmt.Sprintf(`[{
+				"app_id": "app-guid",
+				"hostname": "a-hostname",
+				"drain": %q,
+				"len": 0,
+				"cap": 10000,
+				"bytes": 0
+			}]`, binding.Drain)))
+		})
+
+		It("reports the delivery stats of each binding", func() {
+			_, err := client.CreateBinding(context.Background(), &v1.CreateBindingRequest{
+				Binding: binding,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			stats := func() *v1.BindingStats {
+				resp, err := client.ListBindingStats(
+					context.Background(),
+					new(v1.ListBindingStatsRequest),
+				)
+				if err != nil || len(resp.Stats) != 1 {
+					return nil
+				}
+
+				return resp.Stats[0]
+			}
+
+			Eventually(func() uint64 {
+				return stats().GetEgress()
+			}).Should(BeNumerically(">", 0))
+
+			s := stats()
+			Expect(s.State).To(Equal("connected"))
+			Expect(s.Binding).To(Equal(binding))
+			Expect(s.Ingress).To(BeNumerically(">=", s.Egress))
+			Expect(s.LastWriteTimestamp).ToNot(BeZero())
+			Expect(s.MaxLag).To(BeNumerically(">", 0))
+
+			resp, err := http.Get(fmt.Sprintf("http://%s/stats", adapterHealthAddr))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring(`"app_id":"app-guid"`))
+			Expect(string(body)).To(ContainSubstring(`"state":"connected"`))
+			Expect(string(body)).To(MatchRegexp(`"max_lag_ns":[1-9]`))
+		})
+
+		It("exposes its metrics to Prometheus", func() {
+			_, err := client.CreateBinding(context.Background(), &v1.CreateBindingRequest{
+				Binding: binding,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			metrics := func() string {
+				resp, err := http.Get(fmt.Sprintf("http://%s/metrics", adapterHealthAddr))
+				if err != nil {
+					return ""
+				}
+				defer resp.Body.Close()
+
+				body, _ := ioutil.ReadAll(resp.Body)
+				return string(body)
+			}
+
+			Eventually(metrics).Should(And(
+				ContainSubstring("drain_adapter_drain_bindings 1\n"),
+				MatchRegexp(`drain_adapter_egress_total{protocol="syslog"} [1-9]`),
+				MatchRegexp(`drain_adapter_writes_total{protocol="syslog",result="success"} [1-9]`),
+				ContainSubstring(`drain_adapter_dropped_total{protocol="https"} 0`),
+				MatchRegexp(`drain_adapter_write_duration_seconds_count{protocol="syslog"} [1-9]`),
+				MatchRegexp(`drain_adapter_delivery_lag_seconds_count{protocol="syslog"} [1-9]`),
+				MatchRegexp(`drain_adapter_queue_age_seconds_count [1-9]`),
+			))
+		})
+
 		It("deletes a binding", func() {
 			_, err := client.CreateBinding(context.Background(), &v1.CreateBindingRequest{
 				Binding: binding,
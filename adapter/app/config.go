@@ -1,11 +1,12 @@
 package app
 
 import (
-	"log"
+	"os"
 	"strings"
 	"time"
 
 	envstruct "code.cloudfoundry.org/go-envstruct"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 	"golang.org/x/net/idna"
 )
 
@@ -29,8 +30,28 @@ type Config struct {
 	SyslogDialTimeout      time.Duration `env:"SYSLOG_DIAL_TIMEOUT"`
 	SyslogIOTimeout        time.Duration `env:"SYSLOG_IO_TIMEOUT"`
 	SyslogSkipCertVerify   bool          `env:"SYSLOG_SKIP_CERT_VERIFY"`
+	SyslogTLSMinVersion    string        `env:"SYSLOG_TLS_MIN_VERSION"`
+	SyslogTLSCipherSuites  []string      `env:"SYSLOG_TLS_CIPHER_SUITES"`
+	SyslogTLSCurves        []string      `env:"SYSLOG_TLS_CURVES"`
 	MetricsToSyslogEnabled bool          `env:"METRICS_TO_SYSLOG_ENABLED"`
 	MaxBindings            int           `env:"MAX_BINDINGS"`
+	DiskBufferDir          string        `env:"DISK_BUFFER_DIR"`
+	DiskBufferMaxBytes     int64         `env:"DISK_BUFFER_MAX_BYTES"`
+	BufferSize             int           `env:"BUFFER_SIZE"`
+	MaxBufferSize          int           `env:"MAX_BUFFER_SIZE"`
+	MemoryBudgetBytes      int64         `env:"MEMORY_BUDGET_BYTES"`
+	CircuitBreakerFailures int           `env:"CIRCUIT_BREAKER_FAILURES"`
+	CircuitBreakerDuration time.Duration `env:"CIRCUIT_BREAKER_DURATION"`
+	SyslogRetryBackoff     string        `env:"SYSLOG_RETRY_BACKOFF"`
+	SyslogRetryBase        time.Duration `env:"SYSLOG_RETRY_BASE"`
+	SyslogRetryCap         time.Duration `env:"SYSLOG_RETRY_CAP"`
+	SyslogRetryMax         int           `env:"SYSLOG_RETRY_MAX"`
+	DrainErrorLogInterval  time.Duration `env:"DRAIN_ERROR_LOG_INTERVAL"`
+	DrainFlushTimeout      time.Duration `env:"DRAIN_FLUSH_TIMEOUT"`
+	SchedulerAddr          string        `env:"SCHEDULER_ADDR"`
+	SchedulerCommonName    string        `env:"SCHEDULER_COMMON_NAME"`
+	AdvertiseAddr          string        `env:"ADVERTISE_ADDR"`
+	LogLevel               string        `env:"LOG_LEVEL"`
 
 	MetricIngressAddr     string        `env:"METRIC_INGRESS_ADDR,     required"`
 	MetricIngressCN       string        `env:"METRIC_INGRESS_CN,       required"`
@@ -51,16 +72,44 @@ func LoadConfig() *Config {
 		MetricEmitterInterval:  time.Minute,
 		MetricsToSyslogEnabled: false,
 		MaxBindings:            500,
+		DiskBufferMaxBytes:     100 * 1024 * 1024,
+		BufferSize:             10000,
+		MaxBufferSize:          100000,
+		CircuitBreakerFailures: 20,
+		CircuitBreakerDuration: time.Minute,
+		SyslogRetryBackoff:     "full-jitter",
+		SyslogRetryBase:        time.Millisecond,
+		SyslogRetryCap:         15 * time.Second,
+		SyslogRetryMax:         22,
+		DrainErrorLogInterval:  5 * time.Minute,
+		DrainFlushTimeout:      10 * time.Second,
+		LogLevel:               "info",
 	}
+	logger := logging.New(os.Stderr, logging.InfoLevel)
 
 	err := envstruct.Load(&cfg)
 	if err != nil {
-		log.Fatalf("failed to load config from environment: %s", err)
+		logger.Fatalf("failed to load config from environment: %s", err)
 	}
 
 	cfg.LogsAPIAddrWithAZ, err = idna.ToASCII(cfg.LogsAPIAddrWithAZ)
 	if err != nil {
-		log.Fatalf("failed to IDN encode LogAPIAddrWithAZ %s", err)
+		logger.Fatalf("failed to IDN encode LogAPIAddrWithAZ %s", err)
+	}
+
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		logger.Fatalf("invalid LOG_LEVEL: %s", err)
+	}
+
+	// The adapter waits a minute at most for its drains when it stops.
+	if cfg.DrainFlushTimeout < 0 || cfg.DrainFlushTimeout >= time.Minute {
+		logger.Fatalf("DRAIN_FLUSH_TIMEOUT must be between 0 and 1m, got %s", cfg.DrainFlushTimeout)
+	}
+	if cfg.SchedulerAddr != "" && cfg.AdvertiseAddr == "" {
+		logger.Fatalf("ADVERTISE_ADDR is required to watch bindings on SCHEDULER_ADDR")
+	}
+	if cfg.SchedulerAddr != "" && cfg.SchedulerCommonName == "" {
+		logger.Fatalf("SCHEDULER_COMMON_NAME is required to watch bindings on SCHEDULER_ADDR")
 	}
 	cfg.LogsAPIAddrWithAZ = strings.Replace(cfg.LogsAPIAddrWithAZ, "@", "-", -1)
 
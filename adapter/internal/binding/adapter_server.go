@@ -1,6 +1,8 @@
 package binding
 
 import (
+	"net/url"
+
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -13,24 +15,87 @@ type BindingStore interface {
 	Add(binding *v1.Binding) error
 	Delete(binding *v1.Binding)
 	List() (bindings []*v1.Binding)
+	Sync(bindings []*v1.Binding) (added, deleted, rejected int)
 }
 
 type HealthEmitter interface {
 	SetCounter(map[string]int)
 }
 
+// StatsProvider returns the delivery statistics of a binding.
+type StatsProvider interface {
+	Stats(binding *v1.Binding) *v1.BindingStats
+}
+
+// nullStatsProvider reports no statistics for any binding.
+type nullStatsProvider struct{}
+
+// Stats returns empty statistics.
+func (nullStatsProvider) Stats(*v1.Binding) *v1.BindingStats {
+	return &v1.BindingStats{}
+}
+
+// StreamChecker reports whether the logs of a binding are being streamed
+// from the logs provider.
+type StreamChecker interface {
+	Streaming(binding *v1.Binding) bool
+}
+
+// nullStreamChecker reports every binding as streaming.
+type nullStreamChecker struct{}
+
+// Streaming returns true.
+func (nullStreamChecker) Streaming(*v1.Binding) bool {
+	return true
+}
+
 // AdapterServer implements the v1.AdapterServer interface.
 type AdapterServer struct {
-	store  BindingStore
-	health HealthEmitter
+	store   BindingStore
+	health  HealthEmitter
+	stats   StatsProvider
+	streams StreamChecker
+}
+
+// AdapterServerOption is a function that can be used to configure optional
+// settings on an AdapterServer.
+type AdapterServerOption func(*AdapterServer)
+
+// WithStatsProvider sets where ListBindingStats gets the statistics of each
+// binding from.
+func WithStatsProvider(p StatsProvider) AdapterServerOption {
+	return func(c *AdapterServer) {
+		c.stats = p
+	}
+}
+
+// WithStreamChecker sets how BindingStreaming finds out whether the logs of
+// a binding are being streamed. By default every known binding is reported
+// as streaming.
+func WithStreamChecker(s StreamChecker) AdapterServerOption {
+	return func(c *AdapterServer) {
+		c.streams = s
+	}
 }
 
 // New returns a new AdapterServer.
-func NewAdapterServer(store BindingStore, health HealthEmitter) *AdapterServer {
-	return &AdapterServer{
-		store:  store,
-		health: health,
+func NewAdapterServer(
+	store BindingStore,
+	health HealthEmitter,
+	opts ...AdapterServerOption,
+) *AdapterServer {
+	c := &AdapterServer{
+		store:   store,
+		health:  health,
+		stats:   nullStatsProvider{},
+		streams: nullStreamChecker{},
 	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
 }
 
 // ListBindings returns a list of bindings from the binding manager
@@ -60,3 +125,65 @@ func (c *AdapterServer) DeleteBinding(ctx context.Context, req *v1.DeleteBinding
 
 	return &v1.DeleteBindingResponse{}, nil
 }
+
+// ListBindingStats returns the delivery statistics of every binding. The
+// certificates and keys of the bindings and the credentials in their drain
+// URLs are not included.
+func (c *AdapterServer) ListBindingStats(ctx context.Context, req *v1.ListBindingStatsRequest) (*v1.ListBindingStatsResponse, error) {
+	var stats []*v1.BindingStats
+	for _, b := range c.store.List() {
+		s := c.stats.Stats(b)
+		s.Binding = &v1.Binding{
+			AppId:    b.AppId,
+			Hostname: b.Hostname,
+			Drain:    redactDrain(b.Drain),
+		}
+		stats = append(stats, s)
+	}
+
+	return &v1.ListBindingStatsResponse{Stats: stats}, nil
+}
+
+// SyncBindings replaces the bindings of the binding manager with the given
+// bindings at once. Bindings that exceed the maximum number of bindings are
+// rejected and counted in the response rather than failing the call.
+func (c *AdapterServer) SyncBindings(ctx context.Context, req *v1.SyncBindingsRequest) (*v1.SyncBindingsResponse, error) {
+	added, deleted, rejected := c.store.Sync(req.Bindings)
+	c.health.SetCounter(map[string]int{"drainCount": len(c.store.List())})
+
+	return &v1.SyncBindingsResponse{
+		Added:    uint32(added),
+		Deleted:  uint32(deleted),
+		Rejected: uint32(rejected),
+	}, nil
+}
+
+// BindingStreaming reports whether the adapter has the binding and streams
+// its logs from the logs provider. The scheduler uses it to only remove a
+// binding from an adapter once the adapter that takes it over streams it.
+func (c *AdapterServer) BindingStreaming(ctx context.Context, req *v1.BindingStreamingRequest) (*v1.BindingStreamingResponse, error) {
+	if req.Binding == nil {
+		return &v1.BindingStreamingResponse{}, nil
+	}
+
+	for _, b := range c.store.List() {
+		if *b == *req.Binding {
+			return &v1.BindingStreamingResponse{
+				Streaming: c.streams.Streaming(b),
+			}, nil
+		}
+	}
+
+	return &v1.BindingStreamingResponse{}, nil
+}
+
+// redactDrain removes the credentials from a drain URL.
+func redactDrain(drain string) string {
+	u, err := url.Parse(drain)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+
+	return u.String()
+}
@@ -2,17 +2,24 @@ package binding
 
 import (
 	"errors"
+	"sort"
 	"sync"
+	"time"
 
 	loggregator "code.cloudfoundry.org/go-loggregator"
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 )
 
 var (
 	ErrMaxBindingsExceeded = errors.New("Max bindings for adapter exceeded")
 )
 
+// memoryBudgetInterval is how often the memory used by buffers is checked
+// against the memory budget.
+const memoryBudgetInterval = 100 * time.Millisecond
+
 // MetricClient is used to emit metrics.
 type MetricClient interface {
 	NewGaugeMetric(string, string, ...pulseemitter.MetricOption) pulseemitter.GaugeMetric
@@ -31,6 +38,13 @@ type BindingManager struct {
 
 	logClient   LogClient
 	sourceIndex string
+	logger      *logging.Logger
+
+	memoryBudget int64
+	buffers      func() []Buffer
+
+	stopOnce sync.Once
+	done     chan struct{}
 }
 
 // Subscriber reads and writes logs for a specific binding.
@@ -43,6 +57,16 @@ type LogClient interface {
 	EmitLog(message string, opts ...loggregator.EmitLogOption)
 }
 
+// Buffer holds the envelopes of a binding that have not been written yet.
+type Buffer interface {
+	// Bytes returns the memory used by the buffered envelopes.
+	Bytes() int64
+
+	// Evict drops about the given number of bytes of the oldest envelopes
+	// and reports them as dropped for the binding.
+	Evict(bytes int64)
+}
+
 type subscription struct {
 	binding     *v1.Binding
 	unsubscribe func()
@@ -67,42 +91,36 @@ func NewBindingManager(
 		rejectedBindingsMetric: rbm,
 		logClient:              lc,
 		sourceIndex:            sourceIndex,
+		done:                   make(chan struct{}),
 	}
 
 	for _, o := range opts {
 		o(b)
 	}
 
+	if b.memoryBudget > 0 {
+		go b.enforceMemoryBudget()
+	}
+
 	return b
 }
 
+// Stop stops enforcing the memory budget. It does not delete any bindings.
+func (c *BindingManager) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.done)
+	})
+}
+
 // Add stores a new binding subscription to the Binding Manager.
 func (c *BindingManager) Add(binding *v1.Binding) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	key := *binding
-	if _, ok := c.subscriptions[key]; !ok {
-		if len(c.subscriptions) >= c.maxBindings {
-			c.rejectedBindingsMetric.Increment(1)
-			c.logClient.EmitLog(
-				"Syslog adapter has failed to schedule your drain stream",
-				loggregator.WithAppInfo(binding.AppId, "LGR", c.sourceIndex),
-			)
-
-			return ErrMaxBindingsExceeded
-		}
-
-		unsub := c.subscriber.Start(binding)
-		c.subscriptions[key] = subscription{
-			binding:     binding,
-			unsubscribe: unsub,
-		}
-	}
-
+	_, err := c.add(binding)
 	c.drainBindingsMetric.Set(float64(len(c.subscriptions)))
 
-	return nil
+	return err
 }
 
 // Delete removes a binding subscription from the Binding Manager.
@@ -112,15 +130,93 @@ func (c *BindingManager) Delete(binding *v1.Binding) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.delete(binding)
+	c.drainBindingsMetric.Set(float64(len(c.subscriptions)))
+}
+
+// Sync makes the given bindings the bindings of the Binding Manager. Bindings
+// that are not given are deleted before the new ones are added, so that the
+// maximum number of bindings applies to the given bindings only. It returns
+// how many bindings were added, deleted, and rejected because the maximum
+// was exceeded.
+func (c *BindingManager) Sync(bindings []*v1.Binding) (added, deleted, rejected int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	desired := make(map[v1.Binding]*v1.Binding, len(bindings))
+	for _, b := range bindings {
+		desired[*b] = b
+	}
+
+	for key, s := range c.subscriptions {
+		if _, ok := desired[key]; !ok {
+			c.delete(s.binding)
+			deleted++
+		}
+	}
+
+	for _, b := range desired {
+		ok, err := c.add(b)
+		switch {
+		case err != nil:
+			rejected++
+		case ok:
+			added++
+		}
+	}
+
+	c.drainBindingsMetric.Set(float64(len(c.subscriptions)))
+
+	return added, deleted, rejected
+}
+
+// add subscribes to the binding unless it already is. It returns whether the
+// binding was added. The caller must hold the lock.
+func (c *BindingManager) add(binding *v1.Binding) (bool, error) {
+	key := *binding
+	if _, ok := c.subscriptions[key]; ok {
+		return false, nil
+	}
+
+	if len(c.subscriptions) >= c.maxBindings {
+		c.rejectedBindingsMetric.Increment(1)
+		c.bindingLogger(binding).Warnf(
+			"rejected binding, the adapter has the maximum of %d bindings",
+			c.maxBindings,
+		)
+		c.logClient.EmitLog(
+			"Syslog adapter has failed to schedule your drain stream",
+			loggregator.WithAppInfo(binding.AppId, "LGR", c.sourceIndex),
+		)
+
+		return false, ErrMaxBindingsExceeded
+	}
+
+	unsub := c.subscriber.Start(binding)
+	c.subscriptions[key] = subscription{
+		binding:     binding,
+		unsubscribe: unsub,
+	}
+	c.bindingLogger(binding).Debugf("added binding")
+
+	return true, nil
+}
+
+// delete unsubscribes from the binding if it is subscribed to. The caller
+// must hold the lock.
+func (c *BindingManager) delete(binding *v1.Binding) {
 	key := *binding
 	s, ok := c.subscriptions[key]
 	if ok {
 		s.unsubscribe()
+		c.bindingLogger(binding).Debugf("deleted binding")
 	}
 
 	delete(c.subscriptions, key)
+}
 
-	c.drainBindingsMetric.Set(float64(len(c.subscriptions)))
+func (c *BindingManager) bindingLogger(b *v1.Binding) *logging.Logger {
+	return c.logger.With(logging.BindingFields(b.AppId, b.Drain))
 }
 
 // List returns a list of all the bindings in the Binding Manager.
@@ -137,6 +233,62 @@ func (c *BindingManager) List() []*v1.Binding {
 	return bindings
 }
 
+// enforceMemoryBudget evicts envelopes when the buffers of all bindings use
+// more than the memory budget. It evicts from the buffers with the largest
+// backlog first so that a few slow drains do not cause every binding to lose
+// envelopes. It returns once the BindingManager is stopped.
+func (c *BindingManager) enforceMemoryBudget() {
+	high := c.memoryBudget / 10 * 9
+	low := c.memoryBudget / 10 * 8
+
+	t := time.NewTicker(memoryBudgetInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			evictOverBudget(c.buffers(), high, low)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func evictOverBudget(buffers []Buffer, high, low int64) {
+	sizes := make([]int64, len(buffers))
+	var total int64
+	for i, b := range buffers {
+		sizes[i] = b.Bytes()
+		total += sizes[i]
+	}
+
+	if total <= high {
+		return
+	}
+
+	idx := make([]int, len(buffers))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool {
+		return sizes[idx[i]] > sizes[idx[j]]
+	})
+
+	for _, i := range idx {
+		if total <= low {
+			return
+		}
+
+		n := total - low
+		if n > sizes[i] {
+			n = sizes[i]
+		}
+
+		buffers[i].Evict(n)
+		total -= n
+	}
+}
+
 // BindingManagerOption is a function that can be used to configure optional
 // settings on a BindingManager.
 type BindingManagerOption func(*BindingManager)
@@ -147,3 +299,22 @@ func WithMaxBindings(max int) BindingManagerOption {
 		m.maxBindings = max
 	}
 }
+
+// WithLogger writes the logs of the BindingManager to l. Logs are discarded
+// by default.
+func WithLogger(l *logging.Logger) BindingManagerOption {
+	return func(m *BindingManager) {
+		m.logger = l
+	}
+}
+
+// WithMemoryBudget limits the memory used by the buffers of all bindings to
+// maxBytes. The buffers function returns the buffers of the current bindings.
+// When the buffers use more than 90% of the budget envelopes are evicted from
+// the largest buffers until they use no more than 80% of it.
+func WithMemoryBudget(maxBytes int64, buffers func() []Buffer) BindingManagerOption {
+	return func(m *BindingManager) {
+		m.memoryBudget = maxBytes
+		m.buffers = buffers
+	}
+}
@@ -0,0 +1,156 @@
+package binding
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
+)
+
+// Watcher keeps the bindings of a BindingStore in line with the bindings the
+// scheduler streams to the adapter. The scheduler sends a snapshot of the
+// bindings of the adapter whenever the adapter starts watching, so bindings
+// that changed while the stream was down are caught up on.
+type Watcher struct {
+	client           v1.SchedulerClient
+	adapter          string
+	store            BindingStore
+	health           HealthEmitter
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+	logger           *logging.Logger
+}
+
+// WatcherOption is a function that can be used to configure optional
+// settings on a Watcher.
+type WatcherOption func(*Watcher)
+
+// WithWatchRetryInterval sets how long the Watcher waits before it watches
+// again after the stream failed. The wait doubles with every failure in a
+// row, up to max. They default to a second and a minute.
+func WithWatchRetryInterval(d, max time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.retryInterval = d
+		w.maxRetryInterval = max
+	}
+}
+
+// WithWatchLogger writes the logs of the Watcher to l. Logs are discarded by
+// default.
+func WithWatchLogger(l *logging.Logger) WatcherOption {
+	return func(w *Watcher) {
+		w.logger = l
+	}
+}
+
+// NewWatcher returns a Watcher that watches the bindings of the adapter with
+// the given address through c and applies them to s.
+func NewWatcher(
+	c v1.SchedulerClient,
+	adapter string,
+	s BindingStore,
+	h HealthEmitter,
+	opts ...WatcherOption,
+) *Watcher {
+	w := &Watcher{
+		client:           c,
+		adapter:          adapter,
+		store:            s,
+		health:           h,
+		retryInterval:    time.Second,
+		maxRetryInterval: time.Minute,
+	}
+
+	for _, o := range opts {
+		o(w)
+	}
+
+	return w
+}
+
+// Run watches the bindings of the adapter until ctx is done. It stops
+// watching if the scheduler does not serve the stream.
+func (w *Watcher) Run(ctx context.Context) {
+	retryInterval := w.retryInterval
+	for {
+		received, err := w.watch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		if s, ok := status.FromError(err); ok && s.Code() == codes.Unimplemented {
+			w.logger.Warnf("scheduler does not stream bindings, not watching: %s", err)
+			return
+		}
+
+		// A stream that delivered updates was healthy, so the next failure
+		// starts the backoff over.
+		if received {
+			retryInterval = w.retryInterval
+		}
+		w.logger.Warnf("watching bindings failed, retrying in %s: %s", retryInterval, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+
+		retryInterval *= 2
+		if retryInterval > w.maxRetryInterval {
+			retryInterval = w.maxRetryInterval
+		}
+	}
+}
+
+// watch applies the updates of a single stream until it fails. It reports
+// whether any update was received.
+func (w *Watcher) watch(ctx context.Context) (bool, error) {
+	stream, err := w.client.WatchBindings(ctx, &v1.WatchBindingsRequest{
+		Adapter: w.adapter,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	var received bool
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+		received = true
+
+		w.apply(update)
+	}
+}
+
+func (w *Watcher) apply(update *v1.WatchBindingsResponse) {
+	if update.Snapshot {
+		added, deleted, rejected := w.store.Sync(update.Added)
+		w.logger.Infof(
+			"synced bindings with the scheduler: added %d, deleted %d, rejected %d",
+			added,
+			deleted,
+			rejected,
+		)
+	} else {
+		for _, b := range update.Deleted {
+			w.store.Delete(b)
+		}
+
+		// Bindings that exceed the maximum are logged by the store.
+		for _, b := range update.Added {
+			w.store.Add(b)
+		}
+	}
+
+	w.health.SetCounter(map[string]int{"drainCount": len(w.store.List())})
+}
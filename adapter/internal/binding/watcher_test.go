@@ -0,0 +1,219 @@
+package binding_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/binding"
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Watcher", func() {
+	var (
+		ctx     context.Context
+		cancel  func()
+		client  *spySchedulerClient
+		store   *SyncSpyStore
+		health  *SpyHealthEmitter
+		watcher *binding.Watcher
+
+		bindingA *v1.Binding
+		bindingB *v1.Binding
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		client = newSpySchedulerClient()
+		store = &SyncSpyStore{}
+		health = &SpyHealthEmitter{}
+		watcher = binding.NewWatcher(
+			client,
+			"10.0.0.1:4443",
+			store,
+			health,
+			binding.WithWatchRetryInterval(time.Millisecond, time.Millisecond),
+		)
+
+		bindingA = &v1.Binding{AppId: "app-a", Hostname: "host-a", Drain: "a.url"}
+		bindingB = &v1.Binding{AppId: "app-b", Hostname: "host-b", Drain: "b.url"}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("watches the bindings of the adapter", func() {
+		go watcher.Run(ctx)
+
+		var req *v1.WatchBindingsRequest
+		Eventually(client.requests).Should(Receive(&req))
+		Expect(req.Adapter).To(Equal("10.0.0.1:4443"))
+	})
+
+	It("syncs the store with a snapshot", func() {
+		go watcher.Run(ctx)
+		client.updates <- &v1.WatchBindingsResponse{
+			Snapshot: true,
+			Added:    []*v1.Binding{bindingA, bindingB},
+		}
+
+		Eventually(store.Synced).Should(ConsistOf(bindingA, bindingB))
+	})
+
+	It("adds and deletes the bindings of an update", func() {
+		go watcher.Run(ctx)
+		client.updates <- &v1.WatchBindingsResponse{
+			Added:   []*v1.Binding{bindingA},
+			Deleted: []*v1.Binding{bindingB},
+		}
+
+		Eventually(store.Added).Should(ConsistOf(bindingA))
+		Eventually(store.Deleted).Should(ConsistOf(bindingB))
+		Expect(store.Synced()).To(BeNil())
+	})
+
+	It("watches again when the stream fails", func() {
+		go watcher.Run(ctx)
+		Eventually(client.requests).Should(Receive())
+
+		client.errs <- errors.New("some-error")
+
+		Eventually(client.requests).Should(Receive())
+	})
+
+	It("waits longer to watch again after every failure", func() {
+		watcher = binding.NewWatcher(
+			client,
+			"10.0.0.1:4443",
+			store,
+			health,
+			binding.WithWatchRetryInterval(20*time.Millisecond, time.Second),
+		)
+		go watcher.Run(ctx)
+		Eventually(client.requests).Should(Receive())
+
+		var waited time.Duration
+		for i := 0; i < 3; i++ {
+			start := time.Now()
+			client.errs <- errors.New("some-error")
+			Eventually(client.requests).Should(Receive())
+			waited = time.Since(start)
+		}
+
+		Expect(waited).To(BeNumerically(">=", 80*time.Millisecond))
+	})
+
+	It("stops watching if the scheduler does not serve the stream", func() {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			watcher.Run(ctx)
+		}()
+		Eventually(client.requests).Should(Receive())
+
+		client.errs <- grpc.Errorf(codes.Unimplemented, "unknown service")
+
+		Eventually(done).Should(BeClosed())
+		Expect(client.requests).ToNot(Receive())
+	})
+})
+
+type spySchedulerClient struct {
+	requests chan *v1.WatchBindingsRequest
+	updates  chan *v1.WatchBindingsResponse
+	errs     chan error
+}
+
+func newSpySchedulerClient() *spySchedulerClient {
+	return &spySchedulerClient{
+		requests: make(chan *v1.WatchBindingsRequest, 10),
+		updates:  make(chan *v1.WatchBindingsResponse, 10),
+		errs:     make(chan error, 10),
+	}
+}
+
+func (s *spySchedulerClient) WatchBindings(
+	ctx context.Context,
+	req *v1.WatchBindingsRequest,
+	opts ...grpc.CallOption,
+) (v1.Scheduler_WatchBindingsClient, error) {
+	s.requests <- req
+
+	return &spyWatchBindingsClient{ctx: ctx, client: s}, nil
+}
+
+type spyWatchBindingsClient struct {
+	grpc.ClientStream
+	ctx    context.Context
+	client *spySchedulerClient
+}
+
+func (s *spyWatchBindingsClient) Recv() (*v1.WatchBindingsResponse, error) {
+	select {
+	case u := <-s.client.updates:
+		return u, nil
+	case err := <-s.client.errs:
+		return nil, err
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+// SyncSpyStore records what the Watcher applies to it. It is safe to read
+// while the Watcher runs.
+type SyncSpyStore struct {
+	mu      sync.Mutex
+	added   []*v1.Binding
+	deleted []*v1.Binding
+	synced  []*v1.Binding
+}
+
+func (s *SyncSpyStore) Add(binding *v1.Binding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.added = append(s.added, binding)
+	return nil
+}
+
+func (s *SyncSpyStore) Delete(binding *v1.Binding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, binding)
+}
+
+func (s *SyncSpyStore) List() []*v1.Binding {
+	return nil
+}
+
+func (s *SyncSpyStore) Sync(bindings []*v1.Binding) (int, int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.synced = bindings
+	return len(bindings), 0, 0
+}
+
+func (s *SyncSpyStore) Added() []*v1.Binding {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.added
+}
+
+func (s *SyncSpyStore) Deleted() []*v1.Binding {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleted
+}
+
+func (s *SyncSpyStore) Synced() []*v1.Binding {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.synced
+}
@@ -0,0 +1,351 @@
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	loggregator "code.cloudfoundry.org/go-loggregator"
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+)
+
+// ErrCircuitOpen is returned by a CircuitBreakerWriter while its drain is
+// disabled.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a CircuitBreakerWriter.
+type CircuitState int
+
+const (
+	// CircuitClosed lets writes through to the drain.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen rejects writes until the drain has been disabled for the
+	// open duration.
+	CircuitOpen
+
+	// CircuitHalfOpen probes the drain to decide whether to close or reopen
+	// the circuit.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// Prober is implemented by syslog writers that can check whether a drain is
+// reachable without writing to it.
+type Prober interface {
+	Probe() error
+}
+
+// CircuitBreakers creates a circuit breaker for the syslog writer of each
+// binding and keeps track of their state.
+type CircuitBreakers struct {
+	failureThreshold int
+	openDuration     time.Duration
+	logClient        LogClient
+	sourceIndex      string
+	openMetric       pulseemitter.GaugeMetric
+
+	// open is the number of tracked writers whose circuit is not closed.
+	open int64
+
+	mu       sync.Mutex
+	breakers map[*CircuitBreakerWriter]struct{}
+}
+
+// CircuitBreakerOption allows CircuitBreakers to be customized.
+type CircuitBreakerOption func(*CircuitBreakers)
+
+// WithOpenCircuitsMetric sets the gauge that reports the number of drains
+// that are currently disabled.
+func WithOpenCircuitsMetric(m pulseemitter.GaugeMetric) CircuitBreakerOption {
+	return func(c *CircuitBreakers) {
+		c.openMetric = m
+	}
+}
+
+// NewCircuitBreakers returns CircuitBreakers that disable a drain for
+// openDuration after failureThreshold consecutive writes to it failed. The
+// app is told once when its drain is disabled.
+func NewCircuitBreakers(
+	failureThreshold int,
+	openDuration time.Duration,
+	logClient LogClient,
+	sourceIndex string,
+	opts ...CircuitBreakerOption,
+) *CircuitBreakers {
+	c := &CircuitBreakers{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		logClient:        logClient,
+		sourceIndex:      sourceIndex,
+		openMetric:       nullGaugeMetric{},
+		breakers:         make(map[*CircuitBreakerWriter]struct{}),
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
+}
+
+// Wrap wraps a WriterConstructor so that every writer it creates is guarded
+// by a circuit breaker. It should be wrapped by RetryWrapper so that every
+// failed attempt counts towards the failure threshold.
+func (c *CircuitBreakers) Wrap(wc WriterConstructor) WriterConstructor {
+	return WriterConstructor(func(
+		binding *URLBinding,
+		netConf NetworkTimeoutConfig,
+		skipCertVerify bool,
+		egressMetric pulseemitter.CounterMetric,
+	) WriteCloser {
+		writer := wc(
+			binding,
+			netConf,
+			skipCertVerify,
+			egressMetric,
+		)
+
+		w := &CircuitBreakerWriter{
+			writerDelegate: writerDelegate{writer},
+			binding:        binding,
+			breakers:       c,
+		}
+		c.track(w)
+
+		return w
+	})
+}
+
+// CircuitBreakerStatus reports the state of the circuit breaker of a
+// binding.
+type CircuitBreakerStatus struct {
+	AppID     string     `json:"app_id"`
+	Hostname  string     `json:"hostname"`
+	Drain     string     `json:"drain"`
+	State     string     `json:"state"`
+	Failures  int        `json:"failures"`
+	OpenUntil *time.Time `json:"open_until,omitempty"`
+}
+
+// Status returns the state of the circuit breaker of every binding.
+func (c *CircuitBreakers) Status() []CircuitBreakerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	statuses := make([]CircuitBreakerStatus, 0, len(c.breakers))
+	for w := range c.breakers {
+		statuses = append(statuses, w.status())
+	}
+
+	return statuses
+}
+
+func (c *CircuitBreakers) track(w *CircuitBreakerWriter) {
+	c.mu.Lock()
+	c.breakers[w] = struct{}{}
+	c.mu.Unlock()
+
+	if w.binding.Context == nil {
+		return
+	}
+
+	go func() {
+		<-w.binding.Context.Done()
+
+		c.mu.Lock()
+		delete(c.breakers, w)
+		c.mu.Unlock()
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		w.untracked = true
+		if w.state != CircuitClosed {
+			c.addOpen(-1)
+		}
+	}()
+}
+
+func (c *CircuitBreakers) addOpen(delta int64) {
+	c.openMetric.Set(float64(atomic.AddInt64(&c.open, delta)))
+}
+
+// CircuitBreakerWriter stops writing to a drain after too many consecutive
+// failures. While the circuit is open writes fail immediately with
+// ErrCircuitOpen. Once the open duration has passed the drain is probed: if
+// the syslog writer is a Prober it is asked to connect, otherwise the next
+// write is let through. A successful probe closes the circuit, a failed one
+// opens it again.
+type CircuitBreakerWriter struct {
+	writerDelegate
+	binding  *URLBinding
+	breakers *CircuitBreakers
+
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	openUntil time.Time
+	untracked bool
+}
+
+// Write writes the envelope to the syslog writer unless the circuit is
+// open.
+func (w *CircuitBreakerWriter) Write(e *loggregator_v2.Envelope) error {
+	if err := w.allow(); err != nil {
+		return err
+	}
+
+	err := w.writer.Write(e)
+	w.record(err)
+
+	return err
+}
+
+// Flush flushes the syslog writer unless the circuit is open. Flushes that
+// would not send anything to the drain are skipped, so that they neither
+// probe the drain nor close the circuit.
+func (w *CircuitBreakerWriter) Flush() error {
+	f, ok := w.writer.(Flusher)
+	if !ok || !flushPending(w.writer) {
+		return nil
+	}
+
+	if err := w.allow(); err != nil {
+		return err
+	}
+
+	err := f.Flush()
+	w.record(err)
+
+	return err
+}
+
+// State returns the current state of the circuit.
+func (w *CircuitBreakerWriter) State() CircuitState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.state
+}
+
+func (w *CircuitBreakerWriter) allow() error {
+	w.mu.Lock()
+	state := w.state
+	if state == CircuitOpen && !time.Now().Before(w.openUntil) {
+		w.state = CircuitHalfOpen
+		state = CircuitHalfOpen
+	}
+	w.mu.Unlock()
+
+	switch state {
+	case CircuitOpen:
+		return ErrCircuitOpen
+	case CircuitHalfOpen:
+		p, ok := w.writer.(Prober)
+		if !ok {
+			return nil
+		}
+
+		err := p.Probe()
+		w.record(err)
+		if err != nil {
+			return ErrCircuitOpen
+		}
+	}
+
+	return nil
+}
+
+func (w *CircuitBreakerWriter) record(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err == nil {
+		if w.state != CircuitClosed {
+			w.binding.Logger.Infof("syslog drain is reachable again")
+			w.setState(CircuitClosed)
+		}
+		w.failures = 0
+
+		return
+	}
+
+	w.failures++
+	if w.state == CircuitHalfOpen || w.failures >= w.breakers.failureThreshold {
+		w.open()
+	}
+}
+
+// open disables the drain for the open duration. The app is only told when
+// a closed circuit opens, not every time a probe fails.
+func (w *CircuitBreakerWriter) open() {
+	w.openUntil = time.Now().Add(w.breakers.openDuration)
+	if w.state == CircuitClosed {
+		w.binding.Logger.Warnf(
+			"disabling syslog drain until %s after %d failed writes",
+			w.openUntil.Format(time.RFC3339),
+			w.failures,
+		)
+
+		w.breakers.logClient.EmitLog(
+			fmt.Sprintf(
+				"Syslog drain disabled until %s after %d failed writes",
+				w.openUntil.UTC().Format(time.RFC3339),
+				w.failures,
+			),
+			loggregator.WithAppInfo(w.binding.AppID, "LGR", w.breakers.sourceIndex),
+		)
+	}
+
+	w.setState(CircuitOpen)
+}
+
+func (w *CircuitBreakerWriter) setState(s CircuitState) {
+	wasClosed := w.state == CircuitClosed
+	w.state = s
+	w.binding.Stats.setDisabled(s != CircuitClosed)
+
+	if w.untracked || wasClosed == (s == CircuitClosed) {
+		return
+	}
+
+	if wasClosed {
+		w.breakers.addOpen(1)
+	} else {
+		w.breakers.addOpen(-1)
+	}
+}
+
+func (w *CircuitBreakerWriter) status() CircuitBreakerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s := CircuitBreakerStatus{
+		AppID:    w.binding.AppID,
+		Hostname: w.binding.Hostname,
+		Drain:    redactDrain(w.binding.URL.String()),
+		State:    w.state.String(),
+		Failures: w.failures,
+	}
+	if w.state != CircuitClosed {
+		openUntil := w.openUntil
+		s.OpenUntil = &openUntil
+	}
+
+	return s
+}
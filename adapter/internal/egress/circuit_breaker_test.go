@@ -0,0 +1,249 @@
+package egress_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	v2 "code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CircuitBreakers", func() {
+	var (
+		ctx          context.Context
+		cancel       func()
+		binding      *egress.URLBinding
+		logClient    *spyLogClient
+		metricClient *testhelper.SpyMetricClient
+		breakers     *egress.CircuitBreakers
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		binding = &egress.URLBinding{
+			Context:  ctx,
+			AppID:    "app-id",
+			Hostname: "host-name",
+			URL: &url.URL{
+				Scheme: "syslog",
+				User:   url.UserPassword("user", "pass"),
+				Host:   "example.com:514",
+			},
+		}
+		logClient = newSpyLogClient()
+		metricClient = testhelper.NewMetricClient()
+		breakers = egress.NewCircuitBreakers(
+			3,
+			50*time.Millisecond,
+			logClient,
+			"3",
+			egress.WithOpenCircuitsMetric(metricClient.NewGaugeMetric("open_circuit_breakers", "count")),
+		)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("writes through while the drain succeeds", func() {
+		spy := &spyWriteCloser{binding: binding}
+		w := buildBreakerWriter(breakers, spy, binding)
+
+		Expect(w.Write(&v2.Envelope{})).To(Succeed())
+		Expect(spy.WriteAttempts()).To(Equal(1))
+		Expect(w.(*egress.CircuitBreakerWriter).State()).To(Equal(egress.CircuitClosed))
+	})
+
+	It("opens after consecutive failures and rejects writes", func() {
+		spy := &spyWriteCloser{
+			binding:        binding,
+			returnErrCount: 100,
+			writeErr:       errors.New("write error"),
+		}
+		w := buildBreakerWriter(breakers, spy, binding)
+
+		for i := 0; i < 3; i++ {
+			Expect(w.Write(&v2.Envelope{})).To(MatchError("write error"))
+		}
+		Expect(w.(*egress.CircuitBreakerWriter).State()).To(Equal(egress.CircuitOpen))
+
+		Expect(w.Write(&v2.Envelope{})).To(Equal(egress.ErrCircuitOpen))
+		Expect(spy.WriteAttempts()).To(Equal(3))
+		Expect(metricClient.GetMetric("open_circuit_breakers").GaugeValue()).To(Equal(1.0))
+	})
+
+	It("tells the app once that the drain is disabled", func() {
+		spy := &spyWriteCloser{
+			binding:        binding,
+			returnErrCount: 100,
+			writeErr:       errors.New("write error"),
+		}
+		w := buildBreakerWriter(breakers, spy, binding)
+
+		for i := 0; i < 3; i++ {
+			_ = w.Write(&v2.Envelope{})
+		}
+
+		// A failed probe opens the circuit again.
+		time.Sleep(60 * time.Millisecond)
+		_ = w.Write(&v2.Envelope{})
+		Expect(spy.WriteAttempts()).To(Equal(4))
+		Expect(w.(*egress.CircuitBreakerWriter).State()).To(Equal(egress.CircuitOpen))
+
+		Expect(logClient.message()).To(HaveLen(1))
+		Expect(logClient.message()[0]).To(HavePrefix("Syslog drain disabled until "))
+		Expect(logClient.appID()).To(ConsistOf("app-id"))
+		Expect(logClient.sourceType()).To(HaveKey("LGR"))
+	})
+
+	It("probes a Prober instead of writing and closes when it succeeds", func() {
+		spy := &spyProbeWriter{
+			spyWriteCloser: spyWriteCloser{
+				binding:        binding,
+				returnErrCount: 3,
+				writeErr:       errors.New("write error"),
+			},
+			probeErr: errors.New("probe error"),
+		}
+		w := buildBreakerWriter(breakers, spy, binding)
+
+		for i := 0; i < 3; i++ {
+			_ = w.Write(&v2.Envelope{})
+		}
+
+		time.Sleep(60 * time.Millisecond)
+		Expect(w.Write(&v2.Envelope{})).To(Equal(egress.ErrCircuitOpen))
+		Expect(spy.ProbeCalls()).To(Equal(1))
+		Expect(spy.WriteAttempts()).To(Equal(3))
+
+		Expect(w.Write(&v2.Envelope{})).To(Equal(egress.ErrCircuitOpen))
+		Expect(spy.ProbeCalls()).To(Equal(1))
+
+		spy.probeErr = nil
+		time.Sleep(60 * time.Millisecond)
+		Expect(w.Write(&v2.Envelope{})).To(Succeed())
+		Expect(spy.ProbeCalls()).To(Equal(2))
+		Expect(spy.WriteAttempts()).To(Equal(4))
+		Expect(w.(*egress.CircuitBreakerWriter).State()).To(Equal(egress.CircuitClosed))
+		Expect(metricClient.GetMetric("open_circuit_breakers").GaugeValue()).To(Equal(0.0))
+	})
+
+	It("stays open across flushes that would not send anything", func() {
+		drain := newMockRawDrain(http.StatusInternalServerError)
+		b := buildURLBinding(drain.URL, "app-id", "host-name")
+		w := breakers.Wrap(egress.NewHTTPSWriter)(
+			b,
+			egress.NetworkTimeoutConfig{},
+			true,
+			&testhelper.SpyMetric{},
+		)
+
+		for i := 0; i < 3; i++ {
+			Expect(w.Write(buildLogEnvelope("APP", "1", "just a test", v2.Log_OUT))).ToNot(Succeed())
+		}
+		Expect(w.(*egress.CircuitBreakerWriter).State()).To(Equal(egress.CircuitOpen))
+
+		time.Sleep(100 * time.Millisecond)
+		for i := 0; i < 10; i++ {
+			Expect(w.(egress.Flusher).Flush()).To(Succeed())
+		}
+
+		Expect(w.(*egress.CircuitBreakerWriter).State()).To(Equal(egress.CircuitOpen))
+		Expect(drain.getBodies()).To(HaveLen(3))
+	})
+
+	It("reports the state of each binding", func() {
+		spy := &spyWriteCloser{
+			binding:        binding,
+			returnErrCount: 100,
+			writeErr:       errors.New("write error"),
+		}
+		w := buildBreakerWriter(breakers, spy, binding)
+
+		for i := 0; i < 3; i++ {
+			_ = w.Write(&v2.Envelope{})
+		}
+
+		statuses := breakers.Status()
+		Expect(statuses).To(HaveLen(1))
+		Expect(statuses[0].AppID).To(Equal("app-id"))
+		Expect(statuses[0].Hostname).To(Equal("host-name"))
+		Expect(statuses[0].Drain).To(Equal("syslog://example.com:514"))
+		Expect(statuses[0].State).To(Equal("open"))
+		Expect(statuses[0].Failures).To(Equal(3))
+		Expect(statuses[0].OpenUntil).ToNot(BeNil())
+
+		cancel()
+		Eventually(breakers.Status).Should(BeEmpty())
+		Eventually(func() float64 {
+			return metricClient.GetMetric("open_circuit_breakers").GaugeValue()
+		}).Should(Equal(0.0))
+	})
+
+	It("stops the retry writer from retrying while open", func() {
+		spy := &spyWriteCloser{
+			binding:        binding,
+			returnErrCount: 100,
+			writeErr:       errors.New("write error"),
+		}
+		constructor := egress.RetryWrapper(
+			breakers.Wrap(func(
+				*egress.URLBinding,
+				egress.NetworkTimeoutConfig,
+				bool,
+				pulseemitter.CounterMetric,
+			) egress.WriteCloser {
+				return spy
+			}),
+			egress.RetryDuration(buildDelay(0)),
+			10,
+			logClient,
+			"3",
+		)
+		w := constructor(binding, egress.NetworkTimeoutConfig{}, false, nil)
+
+		Expect(w.Write(&v2.Envelope{})).To(Equal(egress.ErrCircuitOpen))
+		Expect(spy.WriteAttempts()).To(Equal(3))
+	})
+})
+
+type spyProbeWriter struct {
+	spyWriteCloser
+	probeErr   error
+	probeCalls int64
+}
+
+func (s *spyProbeWriter) Probe() error {
+	atomic.AddInt64(&s.probeCalls, 1)
+	return s.probeErr
+}
+
+func (s *spyProbeWriter) ProbeCalls() int {
+	return int(atomic.LoadInt64(&s.probeCalls))
+}
+
+func buildBreakerWriter(
+	breakers *egress.CircuitBreakers,
+	w egress.WriteCloser,
+	binding *egress.URLBinding,
+) egress.WriteCloser {
+	constructor := breakers.Wrap(func(
+		*egress.URLBinding,
+		egress.NetworkTimeoutConfig,
+		bool,
+		pulseemitter.CounterMetric,
+	) egress.WriteCloser {
+		return w
+	})
+
+	return constructor(binding, egress.NetworkTimeoutConfig{}, false, nil)
+}
@@ -0,0 +1,80 @@
+package egress
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+)
+
+// DeliveryLagWrapper wraps a WriterConstructor so that the time between the
+// creation of an envelope and its successful write to the drain is observed,
+// in seconds, in the histogram and in the statistics of the binding. For
+// writers that report their posts, such as the https writer, the lag is
+// observed once the envelope is posted rather than when it is batched.
+func DeliveryLagWrapper(
+	wc WriterConstructor,
+	histogram HistogramMetric,
+) WriterConstructor {
+	return WriterConstructor(func(
+		binding *URLBinding,
+		netConf NetworkTimeoutConfig,
+		skipCertVerify bool,
+		egressMetric pulseemitter.CounterMetric,
+	) WriteCloser {
+		writer := wc(
+			binding,
+			netConf,
+			skipCertVerify,
+			egressMetric,
+		)
+
+		w := &DeliveryLagWriter{
+			writerDelegate: writerDelegate{writer},
+			histogram:      histogram,
+			stats:          binding.Stats,
+		}
+		w.onPost = notifyPosted(writer, w.observe)
+
+		return w
+	})
+}
+
+// DeliveryLagWriter measures how far behind a syslog writer is.
+type DeliveryLagWriter struct {
+	writerDelegate
+	histogram HistogramMetric
+	stats     *DrainStats
+
+	// onPost is set when the syslog writer reports its posts, in which case
+	// lag is observed when envelopes are posted.
+	onPost bool
+}
+
+// Write writes the envelope to the syslog writer and observes its lag if the
+// write succeeds. Envelopes without a timestamp are not observed.
+func (w *DeliveryLagWriter) Write(e *loggregator_v2.Envelope) error {
+	err := w.writer.Write(e)
+	if err != nil || w.onPost {
+		return err
+	}
+
+	w.observe([]int64{e.GetTimestamp()})
+
+	return nil
+}
+
+func (w *DeliveryLagWriter) observe(timestamps []int64) {
+	for _, ts := range timestamps {
+		if ts == 0 {
+			continue
+		}
+
+		lag := time.Since(time.Unix(0, ts))
+		if lag < 0 {
+			lag = 0
+		}
+		w.histogram.Observe(lag.Seconds())
+		w.stats.observeLag(lag)
+	}
+}
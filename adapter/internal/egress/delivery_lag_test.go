@@ -0,0 +1,94 @@
+package egress_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	v2 "code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeliveryLagWriter", func() {
+	var (
+		spy       *spyWriteCloser
+		histogram *spyHistogram
+		writer    egress.WriteCloser
+	)
+
+	BeforeEach(func() {
+		spy = &spyWriteCloser{}
+		histogram = &spyHistogram{}
+
+		constructor := egress.DeliveryLagWrapper(
+			func(*egress.URLBinding, egress.NetworkTimeoutConfig, bool, pulseemitter.CounterMetric) egress.WriteCloser {
+				return spy
+			},
+			histogram,
+		)
+		writer = constructor(&egress.URLBinding{}, egress.NetworkTimeoutConfig{}, false, &testhelper.SpyMetric{})
+	})
+
+	It("observes the time since the envelope was created", func() {
+		env := &v2.Envelope{
+			Timestamp: time.Now().Add(-2 * time.Second).UnixNano(),
+		}
+
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(histogram.Observations()).To(ConsistOf(
+			BeNumerically("~", 2, 0.5),
+		))
+	})
+
+	It("does not observe failed writes", func() {
+		spy.writeErr = errors.New("write error")
+		spy.returnErrCount = 1
+
+		Expect(writer.Write(&v2.Envelope{Timestamp: time.Now().UnixNano()})).ToNot(Succeed())
+
+		Expect(histogram.Observations()).To(BeEmpty())
+	})
+
+	It("does not observe envelopes without a timestamp", func() {
+		Expect(writer.Write(&v2.Envelope{})).To(Succeed())
+
+		Expect(histogram.Observations()).To(BeEmpty())
+	})
+
+	It("observes envelopes once they are posted if the writer reports posts", func() {
+		posting := &spyPostingWriteCloser{}
+		writer := egress.DeliveryLagWrapper(
+			func(*egress.URLBinding, egress.NetworkTimeoutConfig, bool, pulseemitter.CounterMetric) egress.WriteCloser {
+				return posting
+			},
+			histogram,
+		)(&egress.URLBinding{}, egress.NetworkTimeoutConfig{}, false, &testhelper.SpyMetric{})
+
+		created := time.Now().Add(-2 * time.Second).UnixNano()
+		Expect(writer.Write(&v2.Envelope{Timestamp: created})).To(Succeed())
+		Expect(histogram.Observations()).To(BeEmpty())
+
+		posting.post([]int64{created, 0})
+		Expect(histogram.Observations()).To(ConsistOf(
+			BeNumerically("~", 2, 0.5),
+		))
+	})
+})
+
+type spyPostingWriteCloser struct {
+	spyWriteCloser
+	posted func([]int64)
+}
+
+func (s *spyPostingWriteCloser) NotifyPosted(f func([]int64)) bool {
+	s.posted = f
+	return true
+}
+
+func (s *spyPostingWriteCloser) post(timestamps []int64) {
+	s.posted(timestamps)
+}
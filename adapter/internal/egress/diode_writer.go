@@ -1,24 +1,115 @@
 package egress
 
 import (
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"golang.org/x/net/context"
 
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
 	"code.cloudfoundry.org/scalable-syslog/internal/diodes"
 	gendiodes "code.cloudfoundry.org/go-diodes"
+	"github.com/golang/protobuf/proto"
 )
 
+// pollingInterval is how long the DiodeWriter waits before checking an empty
+// diode again.
+const pollingInterval = 10 * time.Millisecond
+
+// DefaultDiodeSize is the number of envelopes a DiodeWriter buffers unless
+// configured otherwise.
+const DefaultDiodeSize = 10000
+
+// queueWriter is implemented by writers that queue envelopes themselves,
+// such as the DiskBufferWriter. They are told when an envelope was queued in
+// the diode so that the queue age they observe includes the time it spent
+// there.
+type queueWriter interface {
+	WriteQueued(e *loggregator_v2.Envelope, queued time.Time) error
+}
+
 type WaitGroup interface {
 	Add(delta int)
 	Done()
 }
 
 type DiodeWriter struct {
-	wc    WriteCloser
-	diode *diodes.OneToOne
-	wg    WaitGroup
+	wc       WriteCloser
+	diode    *diodes.TimedOneToOne
+	size     int
+	wg       WaitGroup
+	alerter  gendiodes.Alerter
+	stats    *DrainStats
+	queueAge HistogramMetric
+
+	ctx       context.Context
+	drainCtx  context.Context
+	onDrained func(flushed, abandoned int)
+
+	// flushed is the number of envelopes sent after the context was done.
+	// When the syslog writer reports its posts, envelopes are only counted
+	// once they are posted. It is only used by the writing goroutine.
+	flushed int
+	onPost  bool
+
+	// lost is the number of envelopes the writing goroutine gave up on that
+	// were not reported to the alerter yet. They are reported together so
+	// that a drain that rejects every write does not raise an alert for each
+	// envelope.
+	lost int
+
+	// readMu serializes reads from the diode by the writing goroutine and
+	// Evict.
+	readMu sync.Mutex
+
+	written      int64
+	read         int64
+	dropped      int64
+	envelopeSize int64
+}
+
+// DiodeWriterOption allows a DiodeWriter to be customized.
+type DiodeWriterOption func(*DiodeWriter)
+
+// WithDiodeSize sets the number of envelopes the DiodeWriter buffers before
+// it starts dropping the oldest ones.
+func WithDiodeSize(size int) DiodeWriterOption {
+	return func(d *DiodeWriter) {
+		d.size = size
+	}
+}
+
+// withDiodeStats counts the envelopes written to the DiodeWriter as the
+// ingress of the binding.
+func withDiodeStats(s *DrainStats) DiodeWriterOption {
+	return func(d *DiodeWriter) {
+		d.stats = s
+	}
+}
+
+// WithQueueAgeHistogram observes, in seconds, how long each envelope waited
+// in the diode before it was handed to the syslog writer.
+func WithQueueAgeHistogram(h HistogramMetric) DiodeWriterOption {
+	return func(d *DiodeWriter) {
+		if h != nil {
+			d.queueAge = h
+		}
+	}
+}
 
-	ctx context.Context
+// WithDrain keeps the DiodeWriter writing the envelopes in the diode after
+// the context given to NewDiodeWriter is done, until the diode is empty or
+// ctx is done. Once the syslog writer is closed, report is called with the
+// number of envelopes sent after the context was done and the number that
+// were lost, either left in the diode or failed to write. Without a drain
+// context the DiodeWriter stops as soon as a write fails after the context
+// is done.
+func WithDrain(ctx context.Context, report func(flushed, abandoned int)) DiodeWriterOption {
+	return func(d *DiodeWriter) {
+		d.drainCtx = ctx
+		d.onDrained = report
+	}
 }
 
 func NewDiodeWriter(
@@ -26,13 +117,28 @@ func NewDiodeWriter(
 	wc WriteCloser,
 	alerter gendiodes.Alerter,
 	wg WaitGroup,
+	opts ...DiodeWriterOption,
 ) *DiodeWriter {
 	dw := &DiodeWriter{
-		wc:    wc,
-		diode: diodes.NewOneToOne(10000, alerter, gendiodes.WithPollingContext(ctx)),
-		wg:    wg,
-		ctx:   ctx,
+		wc:        wc,
+		size:      DefaultDiodeSize,
+		wg:        wg,
+		alerter:   alerter,
+		ctx:       ctx,
+		onDrained: func(int, int) {},
+		queueAge:  nullHistogramMetric{},
+	}
+
+	for _, o := range opts {
+		o(dw)
 	}
+	dw.onPost = notifyPosted(wc, dw.posted)
+
+	dw.diode = diodes.NewTimedOneToOne(dw.size, gendiodes.AlertFunc(func(missed int) {
+		atomic.AddInt64(&dw.dropped, int64(missed))
+		alerter.Alert(missed)
+	}))
+
 	wg.Add(1)
 	go dw.start()
 
@@ -41,26 +147,206 @@ func NewDiodeWriter(
 
 // Write writes an envelope into the diode. This can not fail.
 func (d *DiodeWriter) Write(env *loggregator_v2.Envelope) error {
+	// Keep a moving average of the envelope size to estimate how many bytes
+	// are buffered. Write is only called from a single goroutine.
+	size := int64(proto.Size(env))
+	if avg := atomic.LoadInt64(&d.envelopeSize); avg > 0 {
+		size = avg + (size-avg)/16
+	}
+	atomic.StoreInt64(&d.envelopeSize, size)
+
+	atomic.AddInt64(&d.written, 1)
+	d.stats.addIngress(1)
 	d.diode.Set(env)
 
 	return nil
 }
 
+// Len returns the number of envelopes waiting in the diode.
+func (d *DiodeWriter) Len() int {
+	n := atomic.LoadInt64(&d.written) -
+		atomic.LoadInt64(&d.read) -
+		atomic.LoadInt64(&d.dropped)
+
+	// Envelopes that were overwritten are only counted as dropped once the
+	// reader notices.
+	switch {
+	case n < 0:
+		return 0
+	case n > int64(d.size):
+		return d.size
+	default:
+		return int(n)
+	}
+}
+
+// Cap returns the number of envelopes the diode can hold.
+func (d *DiodeWriter) Cap() int {
+	return d.size
+}
+
+// Bytes returns an estimate of the memory used by the envelopes waiting in
+// the diode.
+func (d *DiodeWriter) Bytes() int64 {
+	return int64(d.Len()) * atomic.LoadInt64(&d.envelopeSize)
+}
+
+// Evict drops the oldest envelopes in the diode until about the given number
+// of bytes are freed. Dropped envelopes are reported to the alerter.
+func (d *DiodeWriter) Evict(bytes int64) {
+	size := atomic.LoadInt64(&d.envelopeSize)
+	if size < 1 {
+		size = 1
+	}
+
+	var evicted int
+	for n := (bytes + size - 1) / size; n > 0; n-- {
+		if _, _, ok := d.next(); !ok {
+			break
+		}
+		evicted++
+	}
+
+	if evicted > 0 {
+		d.alerter.Alert(evicted)
+	}
+}
+
 func (d *DiodeWriter) start() {
-	defer d.wc.Close()
 	defer d.wg.Done()
 
+	abandoned := d.run()
+	if err := d.wc.Close(); err != nil {
+		abandoned += d.discard()
+	}
+	d.onDrained(d.flushed, abandoned)
+}
+
+// run writes envelopes from the diode until the context is done and the
+// diode is empty, or until the envelopes are abandoned. It returns the
+// number of envelopes that were lost after the context was done, including
+// those left in the diode.
+func (d *DiodeWriter) run() (abandoned int) {
+	defer d.reportLost()
+
 	for {
-		e := d.diode.Next()
-		if e == nil {
-			return
+		e, set, ok := d.next()
+		if !ok {
+			d.reportLost()
+			if n := d.flush(); contextDone(d.ctx) {
+				return abandoned + n
+			}
+
+			time.Sleep(pollingInterval)
+			continue
+		}
+
+		if d.drainCtx != nil && contextDone(d.drainCtx) {
+			return abandoned + 1 + d.Len()
+		}
+
+		err := d.write(e, set)
+		if err == nil {
+			d.reportLost()
+			if !d.onPost && contextDone(d.ctx) {
+				d.flushed++
+			}
+			continue
 		}
 
-		err := d.wc.Write(e)
-		if err != nil && contextDone(d.ctx) {
-			return
+		// The envelope is lost, along with the envelopes the syslog writer
+		// kept with it. The disk buffer reports the envelopes it has no room
+		// for itself.
+		n := discard(d.wc)
+		if n == 0 {
+			n = 1
+		}
+		if err != ErrDiskQueueFull {
+			d.lost += n
+		}
+		if contextDone(d.ctx) {
+			abandoned += n
+		}
+
+		if contextDone(d.writerCtx()) {
+			return abandoned + d.Len()
+		}
+	}
+}
+
+// posted counts the envelopes the syslog writer posted after the context was
+// done as flushed.
+func (d *DiodeWriter) posted(timestamps []int64) {
+	if contextDone(d.ctx) {
+		d.flushed += len(timestamps)
+	}
+}
+
+// write hands the envelope to the syslog writer and observes how long it
+// was queued, unless the syslog writer queues it further.
+func (d *DiodeWriter) write(e *loggregator_v2.Envelope, set time.Time) error {
+	if qw, ok := d.wc.(queueWriter); ok {
+		return qw.WriteQueued(e, set)
+	}
+
+	d.queueAge.Observe(time.Since(set).Seconds())
+
+	return d.wc.Write(e)
+}
+
+// writerCtx returns the context after which a failed write makes the
+// DiodeWriter give up on the envelopes left in the diode.
+func (d *DiodeWriter) writerCtx() context.Context {
+	if d.drainCtx != nil {
+		return d.drainCtx
+	}
+
+	return d.ctx
+}
+
+func (d *DiodeWriter) next() (*loggregator_v2.Envelope, time.Time, bool) {
+	d.readMu.Lock()
+	defer d.readMu.Unlock()
+
+	e, set, ok := d.diode.TryNext()
+	if ok {
+		atomic.AddInt64(&d.read, 1)
+	}
+
+	return e, set, ok
+}
+
+// flush gives writers that buffer envelopes a chance to send them while the
+// diode is empty. It returns the number of envelopes discarded because the
+// flush failed.
+func (d *DiodeWriter) flush() int {
+	if f, ok := d.wc.(Flusher); ok && flushPending(d.wc) {
+		if err := f.Flush(); err != nil {
+			return d.discard()
 		}
 	}
+
+	return 0
+}
+
+// discard drops the envelopes the syslog writer kept after a write it gave
+// up on and reports them to the alerter. It returns the number dropped.
+func (d *DiodeWriter) discard() int {
+	n := discard(d.wc)
+	if n > 0 {
+		d.alerter.Alert(n)
+	}
+
+	return n
+}
+
+// reportLost reports the envelopes the writing goroutine gave up on since it
+// last reported them to the alerter.
+func (d *DiodeWriter) reportLost() {
+	if d.lost > 0 {
+		d.alerter.Alert(d.lost)
+		d.lost = 0
+	}
 }
 
 func contextDone(ctx context.Context) bool {
@@ -1,7 +1,9 @@
 package egress_test
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,6 +15,8 @@ import (
 
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
 	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+	"github.com/golang/protobuf/proto"
 )
 
 var _ = Describe("DiodeWriter", func() {
@@ -32,6 +36,30 @@ var _ = Describe("DiodeWriter", func() {
 		}))
 	})
 
+	It("observes how long envelopes waited in the diode", func() {
+		spyWaitGroup := &SpyWaitGroup{}
+		spyWriter := &SpyWriter{
+			blockWrites: true,
+		}
+		spyAlerter := &SpyAlerter{}
+		histogram := &spyHistogram{}
+		dw := egress.NewDiodeWriter(
+			context.TODO(),
+			spyWriter,
+			spyAlerter,
+			spyWaitGroup,
+			egress.WithQueueAgeHistogram(histogram),
+		)
+
+		dw.Write(&loggregator_v2.Envelope{})
+		dw.Write(&loggregator_v2.Envelope{})
+		time.Sleep(50 * time.Millisecond)
+		spyWriter.WriteBlocked(false)
+
+		Eventually(histogram.Observations).Should(HaveLen(2))
+		Expect(histogram.Observations()[1]).To(BeNumerically(">=", 0.05))
+	})
+
 	It("dispatches calls to close to the underlying writer", func() {
 		spyWaitGroup := &SpyWaitGroup{}
 		spyWriter := &SpyWriter{}
@@ -76,6 +104,43 @@ var _ = Describe("DiodeWriter", func() {
 		Eventually(spyWriter.calledWith).Should(HaveLen(100))
 	})
 
+	It("reports the envelopes the writer discards after a failed write", func() {
+		spyWaitGroup := &SpyWaitGroup{}
+		spyWriter := &SpyDiscardWriter{
+			SpyWriter: SpyWriter{writeError: errors.New("some-error")},
+			discarded: 3,
+		}
+		spyAlerter := &SpyAlerter{}
+		dw := egress.NewDiodeWriter(context.TODO(), spyWriter, spyAlerter, spyWaitGroup)
+
+		dw.Write(&loggregator_v2.Envelope{})
+
+		Eventually(spyAlerter.missed).Should(Equal(int64(3)))
+	})
+
+	It("reports the envelopes it gives up on after a failed write", func() {
+		spyWriter := &SpyWriter{writeError: egress.ErrCircuitOpen}
+		spyAlerter := &SpyAlerter{}
+		dw := egress.NewDiodeWriter(context.TODO(), spyWriter, spyAlerter, &SpyWaitGroup{})
+
+		for i := 0; i < 3; i++ {
+			dw.Write(&loggregator_v2.Envelope{})
+		}
+
+		Eventually(spyAlerter.missed).Should(Equal(int64(3)))
+	})
+
+	It("does not report envelopes that the disk buffer had no room for", func() {
+		spyWriter := &SpyWriter{writeError: egress.ErrDiskQueueFull}
+		spyAlerter := &SpyAlerter{}
+		dw := egress.NewDiodeWriter(context.TODO(), spyWriter, spyAlerter, &SpyWaitGroup{})
+
+		dw.Write(&loggregator_v2.Envelope{})
+
+		Eventually(spyWriter.calledWith).Should(HaveLen(1))
+		Consistently(spyAlerter.missed).Should(BeZero())
+	})
+
 	It("closes the writer if write returns an error and context is done", func() {
 		spyWaitGroup := &SpyWaitGroup{}
 		spyWriter := &SpyWriter{
@@ -97,6 +162,18 @@ var _ = Describe("DiodeWriter", func() {
 		Eventually(spyWriter.CloseCalled).ShouldNot(BeZero())
 	})
 
+	It("flushes the underlying writer while the diode is empty", func() {
+		spyWaitGroup := &SpyWaitGroup{}
+		spyWriter := &SpyFlushWriter{}
+		spyAlerter := &SpyAlerter{}
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+
+		egress.NewDiodeWriter(ctx, spyWriter, spyAlerter, spyWaitGroup)
+
+		Eventually(spyWriter.FlushCalled).Should(BeNumerically(">", 1))
+	})
+
 	It("registers with the wait group and deregisters when done", func() {
 		spyWaitGroup := &SpyWaitGroup{}
 		spyWriter := &SpyWriter{
@@ -112,6 +189,203 @@ var _ = Describe("DiodeWriter", func() {
 		cancel()
 		Eventually(spyWaitGroup.DoneCalled).Should(Equal(int64(1)))
 	})
+
+	It("reports how many envelopes are buffered", func() {
+		spyWriter := &SpyWriter{blockWrites: true}
+		dw := egress.NewDiodeWriter(
+			context.TODO(),
+			spyWriter,
+			&SpyAlerter{},
+			&SpyWaitGroup{},
+			egress.WithDiodeSize(5),
+		)
+		Expect(dw.Cap()).To(Equal(5))
+
+		for i := 0; i < 3; i++ {
+			dw.Write(&loggregator_v2.Envelope{})
+		}
+		Eventually(dw.Len).Should(Equal(2))
+
+		for i := 0; i < 10; i++ {
+			dw.Write(&loggregator_v2.Envelope{})
+		}
+		Expect(dw.Len()).To(Equal(5))
+	})
+
+	It("evicts the oldest envelopes and reports them as dropped", func() {
+		spyWriter := &SpyWriter{blockWrites: true}
+		spyAlerter := &SpyAlerter{}
+		dw := egress.NewDiodeWriter(
+			context.TODO(),
+			spyWriter,
+			spyAlerter,
+			&SpyWaitGroup{},
+		)
+
+		env := &loggregator_v2.Envelope{
+			Message: &loggregator_v2.Envelope_Log{
+				Log: &loggregator_v2.Log{Payload: make([]byte, 100)},
+			},
+		}
+		size := int64(proto.Size(env))
+
+		for i := 0; i < 4; i++ {
+			dw.Write(env)
+		}
+		Eventually(dw.Len).Should(Equal(3))
+		Expect(dw.Bytes()).To(Equal(3 * size))
+
+		dw.Evict(2 * size)
+
+		Expect(spyAlerter.missed()).To(Equal(int64(2)))
+		Expect(dw.Len()).To(Equal(1))
+		Expect(dw.Bytes()).To(Equal(size))
+	})
+
+	Context("with a drain context", func() {
+		var (
+			spyWriter *SpyWriter
+			reports   chan [2]int
+			report    func(flushed, abandoned int)
+		)
+
+		BeforeEach(func() {
+			spyWriter = &SpyWriter{blockWrites: true}
+			reports = make(chan [2]int, 1)
+			report = func(flushed, abandoned int) {
+				reports <- [2]int{flushed, abandoned}
+			}
+		})
+
+		It("reports the envelopes written after the context is done as flushed", func() {
+			ctx, cancel := context.WithCancel(context.TODO())
+			dw := egress.NewDiodeWriter(
+				ctx,
+				spyWriter,
+				&SpyAlerter{},
+				&SpyWaitGroup{},
+				egress.WithDrain(context.TODO(), report),
+			)
+
+			for i := 0; i < 5; i++ {
+				dw.Write(&loggregator_v2.Envelope{})
+			}
+			Eventually(dw.Len).Should(Equal(4))
+			cancel()
+			spyWriter.WriteBlocked(false)
+
+			Eventually(reports).Should(Receive(Equal([2]int{5, 0})))
+			Expect(spyWriter.calledWith()).To(HaveLen(5))
+			Expect(spyWriter.CloseCalled()).To(Equal(int64(1)))
+		})
+
+		It("keeps writing after failed writes until the diode is empty", func() {
+			spyWriter.writeError = errors.New("some-error")
+			ctx, cancel := context.WithCancel(context.TODO())
+			dw := egress.NewDiodeWriter(
+				ctx,
+				spyWriter,
+				&SpyAlerter{},
+				&SpyWaitGroup{},
+				egress.WithDrain(context.TODO(), report),
+			)
+
+			for i := 0; i < 3; i++ {
+				dw.Write(&loggregator_v2.Envelope{})
+			}
+			Eventually(dw.Len).Should(Equal(2))
+			cancel()
+			spyWriter.WriteBlocked(false)
+
+			Eventually(reports).Should(Receive(Equal([2]int{0, 3})))
+			Expect(spyWriter.calledWith()).To(HaveLen(3))
+		})
+
+		It("abandons the envelope whose write failed once the drain context is done", func() {
+			spyWriter.writeError = errors.New("some-error")
+			ctx, cancel := context.WithCancel(context.TODO())
+			drainCtx, drainCancel := context.WithCancel(context.TODO())
+			dw := egress.NewDiodeWriter(
+				ctx,
+				spyWriter,
+				&SpyAlerter{},
+				&SpyWaitGroup{},
+				egress.WithDrain(drainCtx, report),
+			)
+
+			for i := 0; i < 3; i++ {
+				dw.Write(&loggregator_v2.Envelope{})
+			}
+			Eventually(dw.Len).Should(Equal(2))
+			cancel()
+			drainCancel()
+			spyWriter.WriteBlocked(false)
+
+			Eventually(reports).Should(Receive(Equal([2]int{0, 3})))
+			Expect(spyWriter.calledWith()).To(HaveLen(1))
+		})
+
+		Context("with a writer that batches envelopes", func() {
+			write := func(status int) {
+				drain := newMockRawDrain(status)
+				writer := egress.NewHTTPSWriter(
+					buildURLBinding(drain.URL+"/?batch-size=100", "test-app-id", "test-hostname"),
+					egress.NetworkTimeoutConfig{},
+					true,
+					&testhelper.SpyMetric{},
+				)
+				ctx, cancel := context.WithCancel(context.TODO())
+				dw := egress.NewDiodeWriter(
+					ctx,
+					writer,
+					&SpyAlerter{},
+					&SpyWaitGroup{},
+					egress.WithDrain(context.TODO(), report),
+				)
+
+				for i := 0; i < 3; i++ {
+					dw.Write(buildLogEnvelope("APP", "1", "log", loggregator_v2.Log_OUT))
+				}
+				cancel()
+			}
+
+			It("reports the batched envelopes as flushed once they are posted", func() {
+				write(http.StatusOK)
+
+				Eventually(reports).Should(Receive(Equal([2]int{3, 0})))
+			})
+
+			It("abandons the batched envelopes if the batch fails to post", func() {
+				write(http.StatusInternalServerError)
+
+				Eventually(reports).Should(Receive(Equal([2]int{0, 3})))
+			})
+		})
+
+		It("abandons the envelopes left once the drain context is done", func() {
+			ctx, cancel := context.WithCancel(context.TODO())
+			drainCtx, drainCancel := context.WithCancel(context.TODO())
+			dw := egress.NewDiodeWriter(
+				ctx,
+				spyWriter,
+				&SpyAlerter{},
+				&SpyWaitGroup{},
+				egress.WithDrain(drainCtx, report),
+			)
+
+			for i := 0; i < 5; i++ {
+				dw.Write(&loggregator_v2.Envelope{})
+			}
+			Eventually(dw.Len).Should(Equal(4))
+			cancel()
+			drainCancel()
+			spyWriter.WriteBlocked(false)
+
+			Eventually(reports).Should(Receive(Equal([2]int{1, 4})))
+			Expect(spyWriter.calledWith()).To(HaveLen(1))
+			Expect(spyWriter.CloseCalled()).To(Equal(int64(1)))
+		})
+	})
 })
 
 type SpyWriter struct {
@@ -166,6 +440,36 @@ func (s *SpyWriter) calledWith() []*loggregator_v2.Envelope {
 	return s.calledWith_
 }
 
+type SpyFlushWriter struct {
+	SpyWriter
+	flushCalled int64
+}
+
+func (s *SpyFlushWriter) Flush() error {
+	atomic.AddInt64(&s.flushCalled, 1)
+
+	return nil
+}
+
+func (s *SpyFlushWriter) FlushCalled() int64 {
+	return atomic.LoadInt64(&s.flushCalled)
+}
+
+type SpyDiscardWriter struct {
+	SpyWriter
+	discarded int
+}
+
+func (s *SpyDiscardWriter) Discard() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.discarded
+	s.discarded = 0
+
+	return n
+}
+
 type SpyAlerter struct {
 	missed_ int64
 }
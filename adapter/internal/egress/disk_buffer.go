@@ -0,0 +1,430 @@
+package egress
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	gendiodes "code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
+)
+
+const (
+	// diskQueueRetention is how long the queue of a binding that has no
+	// writer is kept. The queues of bindings that moved to another adapter
+	// are never replayed.
+	diskQueueRetention = 24 * time.Hour
+
+	// diskCollectInterval is how often at most queues are checked against
+	// the retention.
+	diskCollectInterval = time.Hour
+
+	// diskMaxBackoff is the longest a DiskBufferWriter waits before it writes
+	// an envelope that failed to write again.
+	diskMaxBackoff = 5 * time.Second
+)
+
+// DiskBuffer stores the envelopes of each binding in a DiskQueue under a
+// directory so that envelopes survive slow or unavailable drains and
+// adapter restarts. Each binding may use up to maxBytes of disk. Queues that
+// no writer used for a day are removed.
+type DiskBuffer struct {
+	dir      string
+	maxBytes int64
+
+	mu          sync.Mutex
+	locks       map[string]*queueLock
+	lastCollect time.Time
+}
+
+type queueLock struct {
+	sync.Mutex
+	refs int
+}
+
+// NewDiskBuffer returns a DiskBuffer that stores queues under dir.
+func NewDiskBuffer(dir string, maxBytes int64) *DiskBuffer {
+	return &DiskBuffer{
+		dir:      dir,
+		maxBytes: maxBytes,
+		locks:    make(map[string]*queueLock),
+	}
+}
+
+// DiskBufferWriterOption allows a DiskBufferWriter to be customized.
+type DiskBufferWriterOption func(*DiskBufferWriter)
+
+// WithDiskBufferLogger writes the logs of the DiskBufferWriter and its queue
+// to l. Logs are discarded by default.
+func WithDiskBufferLogger(l *logging.Logger) DiskBufferWriterOption {
+	return func(w *DiskBufferWriter) {
+		w.logger = l
+	}
+}
+
+// WithDiskBufferQueueAge observes, in seconds, how long each envelope waited
+// since it was queued before it was handed to the syslog writer.
+func WithDiskBufferQueueAge(h HistogramMetric) DiskBufferWriterOption {
+	return func(w *DiskBufferWriter) {
+		if h != nil {
+			w.queueAge = h
+		}
+	}
+}
+
+// Writer returns a DiskBufferWriter that queues envelopes for the binding on
+// disk and writes them to wc. The alerter is called for every envelope that
+// is dropped because the queue is full.
+func (b *DiskBuffer) Writer(
+	ctx context.Context,
+	binding *v1.Binding,
+	wc WriteCloser,
+	alerter gendiodes.Alerter,
+	opts ...DiskBufferWriterOption,
+) *DiskBufferWriter {
+	w := &DiskBufferWriter{
+		ctx:      ctx,
+		buffer:   b,
+		key:      bindingKey(binding),
+		wc:       wc,
+		alerter:  alerter,
+		queueAge: nullHistogramMetric{},
+		notify:   make(chan struct{}, 1),
+		ready:    make(chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(w)
+	}
+	go w.start()
+
+	return w
+}
+
+// lock waits until no other writer uses the queue of the binding. A binding
+// can briefly have two writers when it reconnects.
+func (b *DiskBuffer) lock(key string) {
+	b.mu.Lock()
+	l, ok := b.locks[key]
+	if !ok {
+		l = &queueLock{}
+		b.locks[key] = l
+	}
+	l.refs++
+	b.mu.Unlock()
+
+	l.Lock()
+}
+
+func (b *DiskBuffer) unlock(key string) {
+	b.mu.Lock()
+	l := b.locks[key]
+	l.refs--
+	if l.refs == 0 {
+		delete(b.locks, key)
+	}
+	b.mu.Unlock()
+
+	l.Unlock()
+}
+
+// collect removes the queues that no writer used for longer than the
+// retention. Holding the lock keeps writers from opening a queue while it is
+// removed.
+func (b *DiskBuffer) collect(logger *logging.Logger) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if time.Since(b.lastCollect) < diskCollectInterval {
+		return
+	}
+	b.lastCollect = time.Now()
+
+	infos, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return
+	}
+
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		if _, ok := b.locks[info.Name()]; ok {
+			continue
+		}
+
+		dir := filepath.Join(b.dir, info.Name())
+		if time.Since(lastModified(dir, info)) < diskQueueRetention {
+			continue
+		}
+
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Errorf("failed to remove stale disk queue %s: %s", dir, err)
+			continue
+		}
+		logger.Infof("removed disk queue %s that was not used for %s", dir, diskQueueRetention)
+	}
+}
+
+// lastModified returns when the directory or any file in it was last
+// modified.
+func lastModified(dir string, info os.FileInfo) time.Time {
+	last := info.ModTime()
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return last
+	}
+
+	for _, i := range infos {
+		if i.ModTime().After(last) {
+			last = i.ModTime()
+		}
+	}
+
+	return last
+}
+
+func bindingKey(b *v1.Binding) string {
+	sum := sha256.Sum256([]byte(b.AppId + "\n" + b.Hostname + "\n" + b.Drain))
+	return hex.EncodeToString(sum[:])
+}
+
+// DiskBufferWriter queues envelopes on disk and writes them to a syslog
+// writer from its own goroutine. Envelopes that have not been written when
+// the writer is closed are replayed by the next writer for the same binding,
+// even after a restart. If the queue cannot be opened envelopes are written
+// directly to the syslog writer.
+type DiskBufferWriter struct {
+	ctx      context.Context
+	buffer   *DiskBuffer
+	key      string
+	wc       WriteCloser
+	alerter  gendiodes.Alerter
+	queue    *DiskQueue
+	logger   *logging.Logger
+	queueAge HistogramMetric
+
+	notify chan struct{}
+	ready  chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// Write adds the envelope to the queue. If the queue is full the envelope is
+// dropped and ErrDiskQueueFull is returned.
+func (w *DiskBufferWriter) Write(env *loggregator_v2.Envelope) error {
+	return w.WriteQueued(env, time.Now())
+}
+
+// WriteQueued adds an envelope that was first queued at the given time to
+// the queue, so that its queue age includes the time it waited before.
+func (w *DiskBufferWriter) WriteQueued(env *loggregator_v2.Envelope, queued time.Time) error {
+	select {
+	case <-w.ready:
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+
+	if w.queue == nil {
+		w.queueAge.Observe(time.Since(queued).Seconds())
+		return w.wc.Write(env)
+	}
+
+	err := w.queue.Push(env, queued)
+	if err == ErrDiskQueueFull {
+		w.alerter.Alert(1)
+	}
+	if err != nil {
+		return err
+	}
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Flush flushes the syslog writer when the queue could not be opened.
+// Otherwise the syslog writer is flushed whenever the queue is empty.
+func (w *DiskBufferWriter) Flush() error {
+	select {
+	case <-w.ready:
+	default:
+		return nil
+	}
+
+	if w.queue != nil {
+		return nil
+	}
+
+	if f, ok := w.wc.(Flusher); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
+
+// FlushPending reports whether Flush would flush the syslog writer.
+func (w *DiskBufferWriter) FlushPending() bool {
+	select {
+	case <-w.ready:
+	default:
+		return false
+	}
+
+	return w.queue == nil && flushPending(w.wc)
+}
+
+// Discard delegates to the syslog writer when the queue could not be opened.
+// Otherwise envelopes the syslog writer kept are discarded whenever a write
+// from the queue fails.
+func (w *DiskBufferWriter) Discard() int {
+	select {
+	case <-w.ready:
+	default:
+		return 0
+	}
+
+	if w.queue != nil {
+		return 0
+	}
+
+	return discard(w.wc)
+}
+
+// Close stops writing envelopes from the queue and closes the syslog writer.
+// Envelopes that were not written are kept on disk.
+func (w *DiskBufferWriter) Close() error {
+	close(w.stop)
+	<-w.done
+
+	return w.wc.Close()
+}
+
+func (w *DiskBufferWriter) start() {
+	defer close(w.done)
+
+	w.buffer.lock(w.key)
+	defer w.buffer.unlock(w.key)
+	w.buffer.collect(w.logger)
+
+	q, err := OpenDiskQueue(
+		filepath.Join(w.buffer.dir, w.key),
+		w.buffer.maxBytes,
+		WithDiskQueueLogger(w.logger),
+	)
+	if err != nil {
+		w.logger.Errorf("failed to open disk buffer, writing to drain directly: %s", err)
+		close(w.ready)
+		return
+	}
+	defer q.Close()
+
+	// Writers that report their posts may keep envelopes in a batch after
+	// the write returns. Those envelopes are only acknowledged once they are
+	// posted, and are left in the queue for the next writer if they are not.
+	acksOnPost := notifyPosted(w.wc, func(timestamps []int64) {
+		q.Ack(len(timestamps))
+	})
+	defer discard(w.wc)
+
+	w.queue = q
+	close(w.ready)
+
+	var attempt int
+	for {
+		env, queued, ok := q.Next()
+		if !ok {
+			if f, ok := w.wc.(Flusher); ok && flushPending(w.wc) {
+				if err := f.Flush(); err != nil {
+					attempt++
+					if !w.retry(attempt) {
+						return
+					}
+					continue
+				}
+			}
+
+			select {
+			case <-w.notify:
+			case <-time.After(pollingInterval):
+			case <-w.ctx.Done():
+				return
+			case <-w.stop:
+				return
+			}
+			continue
+		}
+
+		if attempt == 0 {
+			w.queueAge.Observe(time.Since(queued).Seconds())
+		}
+
+		err := w.wc.Write(env)
+		if err != nil && w.stopped() {
+			// Keep the envelope so that it is written by the next writer.
+			return
+		}
+
+		if err != nil {
+			// Keep the envelope until the drain accepts it. Envelopes are only
+			// dropped once the disk budget runs out.
+			attempt++
+			if !w.retry(attempt) {
+				return
+			}
+			continue
+		}
+		attempt = 0
+
+		if !acksOnPost {
+			q.Ack(1)
+		}
+	}
+}
+
+// retry drops the envelopes the syslog writer kept after a failed write and
+// waits before they are written again from the queue. It returns false if
+// the writer stopped in the meantime.
+func (w *DiskBufferWriter) retry(attempt int) bool {
+	discard(w.wc)
+	w.queue.Rewind()
+
+	return w.wait(exponential(pollingInterval, diskMaxBackoff, 2, attempt))
+}
+
+// wait waits for d. It returns false if the writer stopped in the meantime.
+func (w *DiskBufferWriter) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-w.ctx.Done():
+		return false
+	case <-w.stop:
+		return false
+	}
+}
+
+func (w *DiskBufferWriter) stopped() bool {
+	select {
+	case <-w.stop:
+		return true
+	default:
+		return contextDone(w.ctx)
+	}
+}
@@ -0,0 +1,220 @@
+package egress_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DiskBufferWriter", func() {
+	var (
+		tmpDir  string
+		buffer  *egress.DiskBuffer
+		binding *v1.Binding
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "disk-buffer")
+		Expect(err).ToNot(HaveOccurred())
+
+		buffer = egress.NewDiskBuffer(tmpDir, 1024*1024)
+		binding = &v1.Binding{
+			AppId:    "some-app-id",
+			Hostname: "some-hostname",
+			Drain:    "syslog://some-drain",
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	sourceIDs := func(w *SpyWriter) func() []string {
+		return func() []string {
+			var ids []string
+			for _, env := range w.calledWith() {
+				ids = append(ids, env.GetSourceId())
+			}
+			return ids
+		}
+	}
+
+	It("writes envelopes to the underlying writer", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		spyWriter := &SpyWriter{}
+		w := buffer.Writer(ctx, binding, spyWriter, &SpyAlerter{})
+
+		Expect(w.Write(buildSourceEnvelope(0))).To(Succeed())
+		Expect(w.Write(buildSourceEnvelope(1))).To(Succeed())
+
+		Eventually(sourceIDs(spyWriter)).Should(Equal([]string{"0", "1"}))
+	})
+
+	It("replays envelopes that were not written when it was closed", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		blockedWriter := &SpyWriter{
+			blockWrites: true,
+			writeError:  errors.New("drain is down"),
+		}
+		w := buffer.Writer(ctx, binding, blockedWriter, &SpyAlerter{})
+
+		for i := 0; i < 3; i++ {
+			Expect(w.Write(buildSourceEnvelope(i))).To(Succeed())
+		}
+
+		cancel()
+		blockedWriter.WriteBlocked(false)
+		Expect(w.Close()).To(Succeed())
+		Expect(blockedWriter.CloseCalled()).To(Equal(int64(1)))
+
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		spyWriter := &SpyWriter{}
+		buffer.Writer(ctx, binding, spyWriter, &SpyAlerter{})
+
+		Eventually(sourceIDs(spyWriter)).Should(Equal([]string{"0", "1", "2"}))
+	})
+
+	It("alerts about envelopes dropped when the disk budget runs out", func() {
+		buffer = egress.NewDiskBuffer(tmpDir, 200)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		spyAlerter := &SpyAlerter{}
+		w := buffer.Writer(ctx, binding, &SpyWriter{blockWrites: true}, spyAlerter)
+
+		var err error
+		for i := 0; i < 100; i++ {
+			err = w.Write(buildSourceEnvelope(i))
+		}
+
+		Expect(err).To(Equal(egress.ErrDiskQueueFull))
+		Expect(spyAlerter.missed()).To(BeNumerically(">", 0))
+	})
+
+	It("writes envelopes whose write failed again", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		spyWriter := &SpyWriter{writeError: errors.New("drain is down")}
+		spyAlerter := &SpyAlerter{}
+		w := buffer.Writer(ctx, binding, spyWriter, spyAlerter)
+
+		Expect(w.Write(buildSourceEnvelope(0))).To(Succeed())
+		Eventually(func() int {
+			return len(sourceIDs(spyWriter)())
+		}).Should(BeNumerically(">=", 2))
+
+		spyWriter.mu.Lock()
+		spyWriter.writeError = nil
+		spyWriter.mu.Unlock()
+		Expect(w.Write(buildSourceEnvelope(1))).To(Succeed())
+
+		Eventually(sourceIDs(spyWriter)).Should(ContainElement("1"))
+		ids := sourceIDs(spyWriter)()
+		Expect(ids[len(ids)-2:]).To(Equal([]string{"0", "1"}))
+		Expect(spyAlerter.missed()).To(BeZero())
+	})
+
+	Context("with a batching HTTPS writer", func() {
+		writeBatch := func(status int) *SpyRawDrain {
+			drain := newMockRawDrain(status)
+			b := buildURLBinding(drain.URL+"/?batch-size=2", "test-app-id", "test-hostname")
+			https := egress.NewHTTPSWriter(b, egress.NetworkTimeoutConfig{}, true, &testhelper.SpyMetric{})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			w := buffer.Writer(ctx, binding, https, &SpyAlerter{})
+			Expect(w.Write(buildSourceEnvelope(0))).To(Succeed())
+			Expect(w.Write(buildSourceEnvelope(1))).To(Succeed())
+			Eventually(drain.getBodies).ShouldNot(BeEmpty())
+			Expect(w.Close()).To(Succeed())
+
+			return drain
+		}
+
+		It("keeps envelopes on disk until their batch is posted", func() {
+			writeBatch(http.StatusInternalServerError)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			spyWriter := &SpyWriter{}
+			buffer.Writer(ctx, binding, spyWriter, &SpyAlerter{})
+
+			Eventually(sourceIDs(spyWriter)).Should(Equal([]string{"0", "1"}))
+		})
+
+		It("removes envelopes from disk once their batch is posted", func() {
+			drain := writeBatch(http.StatusOK)
+			Expect(drain.getBodies()).To(HaveLen(1))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			spyWriter := &SpyWriter{}
+			buffer.Writer(ctx, binding, spyWriter, &SpyAlerter{})
+
+			Consistently(sourceIDs(spyWriter)).Should(BeEmpty())
+		})
+	})
+
+	It("removes the queues of bindings that were not used for a day", func() {
+		stale := filepath.Join(tmpDir, "stale")
+		Expect(os.MkdirAll(stale, 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(stale, "cursor"), nil, 0600)).To(Succeed())
+		old := time.Now().Add(-48 * time.Hour)
+		Expect(os.Chtimes(filepath.Join(stale, "cursor"), old, old)).To(Succeed())
+		Expect(os.Chtimes(stale, old, old)).To(Succeed())
+
+		recent := filepath.Join(tmpDir, "recent")
+		Expect(os.MkdirAll(recent, 0700)).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		w := buffer.Writer(ctx, binding, &SpyWriter{}, &SpyAlerter{})
+		Expect(w.Write(buildSourceEnvelope(0))).To(Succeed())
+
+		_, err := os.Stat(stale)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		Expect(recent).To(BeADirectory())
+	})
+
+	It("observes how long envelopes were queued, including before they were written to it", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		histogram := &spyHistogram{}
+		w := buffer.Writer(
+			ctx,
+			binding,
+			&SpyWriter{},
+			&SpyAlerter{},
+			egress.WithDiskBufferQueueAge(histogram),
+		)
+
+		Expect(w.WriteQueued(buildSourceEnvelope(0), time.Now().Add(-2*time.Second))).To(Succeed())
+
+		Eventually(histogram.Observations).Should(ConsistOf(
+			BeNumerically("~", 2, 0.5),
+		))
+	})
+
+	It("flushes the underlying writer while the queue is empty", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		spyWriter := &SpyFlushWriter{}
+		buffer.Writer(ctx, binding, spyWriter, &SpyAlerter{})
+
+		Eventually(spyWriter.FlushCalled).Should(BeNumerically(">", 0))
+	})
+})
@@ -0,0 +1,425 @@
+package egress
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
+	"github.com/golang/protobuf/proto"
+)
+
+// ErrDiskQueueFull is returned when pushing an envelope would grow a
+// DiskQueue past its maximum size.
+var ErrDiskQueueFull = errors.New("disk queue is full")
+
+// errCorruptRecord is returned when the length of a record does not fit in
+// the segment it is read from.
+var errCorruptRecord = errors.New("corrupt disk queue record")
+
+const (
+	// diskSegmentBytes is the size at which a new segment file is started.
+	diskSegmentBytes = 1 << 20
+
+	// diskRecordHeaderBytes is the size of the header of each record: the
+	// length of the envelope and when it was queued, in Unix nanoseconds.
+	diskRecordHeaderBytes = 12
+
+	segmentSuffix = ".seg"
+	cursorFile    = "cursor"
+)
+
+// DiskQueue is a FIFO of envelopes stored in segment files in a directory.
+// The position of the oldest envelope that has not been acknowledged is kept
+// in a cursor file so that a queue opened on the same directory after a
+// restart replays the envelopes that were not yet sent. DiskQueue is safe to
+// push to and read from concurrently.
+//
+// Files are not synced, so envelopes survive a restart of the adapter but not
+// necessarily a crash of the machine. A record that a crash left incomplete
+// at the end of a segment is detected when it is read, and the rest of that
+// segment is skipped. Pushes after a restart always go to a new segment.
+type DiskQueue struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	logger   *logging.Logger
+
+	segments     []uint64
+	segmentSizes []int64
+	total        int64
+
+	writer *os.File
+	reader *os.File
+	cursor *os.File
+
+	// readOffset is the position of the oldest envelope that was not
+	// acknowledged in the first segment. nextOffset is the position of the
+	// next envelope to hand out, and inflight are the lengths of the
+	// envelopes handed out in between.
+	readOffset int64
+	nextOffset int64
+	inflight   []int64
+}
+
+// DiskQueueOption allows a DiskQueue to be customized.
+type DiskQueueOption func(*DiskQueue)
+
+// WithDiskQueueLogger writes the logs of the DiskQueue to l. Logs are
+// discarded by default.
+func WithDiskQueueLogger(l *logging.Logger) DiskQueueOption {
+	return func(q *DiskQueue) {
+		q.logger = l
+	}
+}
+
+// OpenDiskQueue opens the queue stored in dir, creating it if it does not
+// exist. The queue will not grow past maxBytes.
+func OpenDiskQueue(dir string, maxBytes int64, opts ...DiskQueueOption) (*DiskQueue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	q := &DiskQueue{
+		dir:      dir,
+		maxBytes: maxBytes,
+	}
+	for _, o := range opts {
+		o(q)
+	}
+
+	if err := q.loadSegments(); err != nil {
+		return nil, err
+	}
+
+	cursor, err := os.OpenFile(filepath.Join(dir, cursorFile), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	q.cursor = cursor
+
+	if err := q.loadCursor(); err != nil {
+		q.Close()
+		return nil, err
+	}
+
+	// Always start a new segment so that a record left incomplete by a crash
+	// is never followed by new records in the same file.
+	if err := q.startSegment(); err != nil {
+		q.Close()
+		return nil, err
+	}
+
+	if err := q.openReader(); err != nil {
+		q.Close()
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// Push appends the envelope to the queue, along with when it was first
+// queued. It returns ErrDiskQueueFull if there is no room left for it.
+func (q *DiskQueue) Push(env *loggregator_v2.Envelope, queued time.Time) error {
+	b, err := proto.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	record := make([]byte, diskRecordHeaderBytes+len(b))
+	binary.BigEndian.PutUint32(record, uint32(len(b)))
+	binary.BigEndian.PutUint64(record[4:], uint64(queued.UnixNano()))
+	copy(record[diskRecordHeaderBytes:], b)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.size()+int64(len(record)) > q.maxBytes {
+		return ErrDiskQueueFull
+	}
+
+	last := len(q.segments) - 1
+	if q.segmentSizes[last] > 0 && q.segmentSizes[last]+int64(len(record)) > diskSegmentBytes {
+		if err := q.startSegment(); err != nil {
+			return err
+		}
+		last++
+	}
+
+	n, err := q.writer.Write(record)
+	q.segmentSizes[last] += int64(n)
+	q.total += int64(n)
+
+	return err
+}
+
+// Next hands out the oldest envelope that was not handed out yet, along with
+// when it was queued. It returns false if there is none. Envelopes that were
+// handed out stay in the queue until they are acknowledged. Envelopes in the
+// next segment are only handed out once the ones handed out from the current
+// segment are acknowledged.
+func (q *DiskQueue) Next() (*loggregator_v2.Envelope, time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		env, queued, n, err := q.readRecord(q.nextOffset)
+		switch {
+		case err == nil:
+			q.nextOffset += n
+			q.inflight = append(q.inflight, n)
+			return env, queued, true
+		case len(q.segments) == 1, len(q.inflight) > 0:
+			// The reader has caught up with the writer, or the segment can
+			// not be removed yet.
+			return nil, time.Time{}, false
+		default:
+			// The rest of the segment is either read, was left incomplete
+			// by a crash or is corrupt.
+			if err != io.EOF {
+				q.logger.Warnf("skipping rest of disk queue segment in %s: %s", q.dir, err)
+			}
+
+			if err := q.removeSegment(); err != nil {
+				q.logger.Errorf("failed to remove disk queue segment in %s: %s", q.dir, err)
+				return nil, time.Time{}, false
+			}
+		}
+	}
+}
+
+// Ack removes the n oldest envelopes that were handed out from the queue.
+func (q *DiskQueue) Ack(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if n > len(q.inflight) {
+		n = len(q.inflight)
+	}
+	if n == 0 {
+		return
+	}
+
+	for _, l := range q.inflight[:n] {
+		q.readOffset += l
+	}
+	q.inflight = q.inflight[n:]
+
+	if err := q.saveCursor(); err != nil {
+		q.logger.Errorf("failed to save disk queue cursor in %s: %s", q.dir, err)
+	}
+}
+
+// Rewind makes Next hand out the envelopes that were handed out but not
+// acknowledged again.
+func (q *DiskQueue) Rewind() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.nextOffset = q.readOffset
+	q.inflight = nil
+}
+
+// Size returns the number of bytes of envelopes in the queue.
+func (q *DiskQueue) Size() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.size()
+}
+
+// Close closes the files of the queue. If the queue is empty its directory
+// is removed.
+func (q *DiskQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, f := range []*os.File{q.writer, q.reader, q.cursor} {
+		if f != nil {
+			f.Close()
+		}
+	}
+
+	if q.segments != nil && q.size() == 0 {
+		return os.RemoveAll(q.dir)
+	}
+
+	return nil
+}
+
+func (q *DiskQueue) size() int64 {
+	return q.total - q.readOffset
+}
+
+func (q *DiskQueue) readRecord(offset int64) (*loggregator_v2.Envelope, time.Time, int64, error) {
+	header := make([]byte, diskRecordHeaderBytes)
+	if _, err := q.reader.ReadAt(header, offset); err != nil {
+		return nil, time.Time{}, 0, err
+	}
+
+	// The length is read from disk, so it is checked against what is left of
+	// the segment before anything is allocated for it.
+	length := int64(binary.BigEndian.Uint32(header))
+	if length > q.segmentSizes[0]-offset-diskRecordHeaderBytes {
+		return nil, time.Time{}, 0, errCorruptRecord
+	}
+	queued := time.Unix(0, int64(binary.BigEndian.Uint64(header[4:])))
+
+	b := make([]byte, length)
+	if _, err := q.reader.ReadAt(b, offset+diskRecordHeaderBytes); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, time.Time{}, 0, err
+	}
+
+	var env loggregator_v2.Envelope
+	if err := proto.Unmarshal(b, &env); err != nil {
+		return nil, time.Time{}, 0, err
+	}
+
+	return &env, queued, int64(diskRecordHeaderBytes + len(b)), nil
+}
+
+func (q *DiskQueue) loadSegments() error {
+	infos, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		return err
+	}
+
+	sizes := make(map[uint64]int64)
+	for _, info := range infos {
+		name := info.Name()
+		if !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		q.segments = append(q.segments, id)
+		sizes[id] = info.Size()
+	}
+
+	sort.Slice(q.segments, func(i, j int) bool {
+		return q.segments[i] < q.segments[j]
+	})
+
+	for _, id := range q.segments {
+		q.segmentSizes = append(q.segmentSizes, sizes[id])
+		q.total += sizes[id]
+	}
+
+	return nil
+}
+
+func (q *DiskQueue) loadCursor() error {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(q.cursor, b); err != nil {
+		// There is no cursor yet, reading starts at the first segment.
+		return nil
+	}
+
+	id := binary.BigEndian.Uint64(b)
+	offset := int64(binary.BigEndian.Uint64(b[8:]))
+
+	// Segments before the cursor were read before the queue was closed.
+	for len(q.segments) > 0 && q.segments[0] < id {
+		if err := q.removeSegment(); err != nil {
+			return err
+		}
+	}
+
+	if len(q.segments) > 0 && q.segments[0] == id && offset <= q.segmentSizes[0] {
+		q.readOffset = offset
+		q.nextOffset = offset
+	}
+
+	return nil
+}
+
+func (q *DiskQueue) saveCursor() error {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b, q.segments[0])
+	binary.BigEndian.PutUint64(b[8:], uint64(q.readOffset))
+
+	_, err := q.cursor.WriteAt(b, 0)
+	return err
+}
+
+func (q *DiskQueue) startSegment() error {
+	var id uint64
+	if len(q.segments) > 0 {
+		id = q.segments[len(q.segments)-1] + 1
+	}
+
+	f, err := os.OpenFile(q.segmentPath(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if q.writer != nil {
+		q.writer.Close()
+	}
+	q.writer = f
+	q.segments = append(q.segments, id)
+	q.segmentSizes = append(q.segmentSizes, 0)
+
+	return nil
+}
+
+// removeSegment deletes the segment that is being read and moves the reader
+// to the next one.
+func (q *DiskQueue) removeSegment() error {
+	if q.reader != nil {
+		q.reader.Close()
+		q.reader = nil
+	}
+
+	if err := os.Remove(q.segmentPath(q.segments[0])); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	q.total -= q.segmentSizes[0]
+	q.segments = q.segments[1:]
+	q.segmentSizes = q.segmentSizes[1:]
+	q.readOffset = 0
+	q.nextOffset = 0
+
+	if q.writer == nil {
+		// The queue is still being opened.
+		return nil
+	}
+
+	if err := q.openReader(); err != nil {
+		return err
+	}
+
+	return q.saveCursor()
+}
+
+func (q *DiskQueue) openReader() error {
+	f, err := os.Open(q.segmentPath(q.segments[0]))
+	if err != nil {
+		return err
+	}
+	q.reader = f
+
+	return nil
+}
+
+func (q *DiskQueue) segmentPath(id uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", id, segmentSuffix))
+}
@@ -0,0 +1,263 @@
+package egress_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DiskQueue", func() {
+	var (
+		tmpDir string
+		dir    string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "disk-queue")
+		Expect(err).ToNot(HaveOccurred())
+		dir = filepath.Join(tmpDir, "queue")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	readAll := func(q *egress.DiskQueue) []string {
+		var ids []string
+		for {
+			env, _, ok := q.Next()
+			if !ok {
+				return ids
+			}
+			ids = append(ids, env.GetSourceId())
+			q.Ack(1)
+		}
+	}
+
+	It("returns envelopes in the order they were pushed", func() {
+		q, err := egress.OpenDiskQueue(dir, 1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		defer q.Close()
+
+		for i := 0; i < 3; i++ {
+			Expect(q.Push(buildSourceEnvelope(i), time.Now())).To(Succeed())
+		}
+
+		Expect(readAll(q)).To(Equal([]string{"0", "1", "2"}))
+		Expect(q.Size()).To(BeZero())
+	})
+
+	It("hands out envelopes that were not acknowledged again after a rewind", func() {
+		q, err := egress.OpenDiskQueue(dir, 1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		defer q.Close()
+
+		for i := 0; i < 3; i++ {
+			Expect(q.Push(buildSourceEnvelope(i), time.Now())).To(Succeed())
+		}
+
+		env, _, ok := q.Next()
+		Expect(ok).To(BeTrue())
+		Expect(env.GetSourceId()).To(Equal("0"))
+		env, _, ok = q.Next()
+		Expect(ok).To(BeTrue())
+		Expect(env.GetSourceId()).To(Equal("1"))
+
+		q.Ack(1)
+		q.Rewind()
+
+		Expect(readAll(q)).To(Equal([]string{"1", "2"}))
+	})
+
+	It("replays envelopes that were handed out but not acknowledged after it is reopened", func() {
+		q, err := egress.OpenDiskQueue(dir, 1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		for i := 0; i < 3; i++ {
+			Expect(q.Push(buildSourceEnvelope(i), time.Now())).To(Succeed())
+		}
+		q.Next()
+		q.Next()
+		q.Ack(1)
+		Expect(q.Close()).To(Succeed())
+
+		q, err = egress.OpenDiskQueue(dir, 1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		defer q.Close()
+
+		Expect(readAll(q)).To(Equal([]string{"1", "2"}))
+	})
+
+	It("does not hand out envelopes of the next segment before the ones handed out are acknowledged", func() {
+		q, err := egress.OpenDiskQueue(dir, 100*1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		defer q.Close()
+
+		for i := 0; i < 50000; i++ {
+			Expect(q.Push(buildSourceEnvelope(i), time.Now())).To(Succeed())
+		}
+
+		var handedOut int
+		for {
+			if _, _, ok := q.Next(); !ok {
+				break
+			}
+			handedOut++
+		}
+		Expect(handedOut).To(BeNumerically("<", 50000))
+
+		q.Ack(handedOut)
+		env, _, ok := q.Next()
+		Expect(ok).To(BeTrue())
+		Expect(env.GetSourceId()).To(Equal(fmt.Sprint(handedOut)))
+	})
+
+	It("replays envelopes that were not acknowledged after it is reopened", func() {
+		q, err := egress.OpenDiskQueue(dir, 100*1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+
+		for i := 0; i < 50000; i++ {
+			Expect(q.Push(buildSourceEnvelope(i), time.Now())).To(Succeed())
+		}
+		for i := 0; i < 20000; i++ {
+			q.Next()
+			q.Ack(1)
+		}
+		Expect(q.Close()).To(Succeed())
+
+		q, err = egress.OpenDiskQueue(dir, 100*1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		defer q.Close()
+
+		ids := readAll(q)
+		Expect(ids).To(HaveLen(30000))
+		Expect(ids[0]).To(Equal("20000"))
+		Expect(ids[29999]).To(Equal("49999"))
+	})
+
+	It("removes segments once they have been read", func() {
+		q, err := egress.OpenDiskQueue(dir, 100*1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		defer q.Close()
+
+		for i := 0; i < 50000; i++ {
+			Expect(q.Push(buildSourceEnvelope(i), time.Now())).To(Succeed())
+		}
+		segments, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+		Expect(len(segments)).To(BeNumerically(">", 1))
+
+		readAll(q)
+
+		segments, _ = filepath.Glob(filepath.Join(dir, "*.seg"))
+		Expect(segments).To(HaveLen(1))
+	})
+
+	It("returns when each envelope was queued", func() {
+		q, err := egress.OpenDiskQueue(dir, 1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+
+		queued := time.Now().Add(-time.Minute)
+		Expect(q.Push(buildSourceEnvelope(0), queued)).To(Succeed())
+		Expect(q.Close()).To(Succeed())
+
+		q, err = egress.OpenDiskQueue(dir, 1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		defer q.Close()
+
+		_, t, ok := q.Next()
+		Expect(ok).To(BeTrue())
+		Expect(t.Equal(queued)).To(BeTrue())
+	})
+
+	It("returns an error when it is full", func() {
+		q, err := egress.OpenDiskQueue(dir, 200)
+		Expect(err).ToNot(HaveOccurred())
+		defer q.Close()
+
+		var pushErr error
+		for i := 0; i < 100 && pushErr == nil; i++ {
+			pushErr = q.Push(buildSourceEnvelope(i), time.Now())
+		}
+		Expect(pushErr).To(Equal(egress.ErrDiskQueueFull))
+		Expect(q.Size()).To(BeNumerically("<=", 200))
+
+		q.Next()
+		q.Ack(1)
+		Expect(q.Push(buildSourceEnvelope(0), time.Now())).To(Succeed())
+	})
+
+	It("skips a record that was left incomplete", func() {
+		q, err := egress.OpenDiskQueue(dir, 1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		for i := 0; i < 3; i++ {
+			Expect(q.Push(buildSourceEnvelope(i), time.Now())).To(Succeed())
+		}
+		Expect(q.Close()).To(Succeed())
+
+		segments, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+		info, err := os.Stat(segments[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.Truncate(segments[0], info.Size()-2)).To(Succeed())
+
+		q, err = egress.OpenDiskQueue(dir, 1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		defer q.Close()
+		Expect(q.Push(buildSourceEnvelope(3), time.Now())).To(Succeed())
+
+		Expect(readAll(q)).To(Equal([]string{"0", "1", "3"}))
+	})
+
+	It("skips a segment with a corrupt record length", func() {
+		q, err := egress.OpenDiskQueue(dir, 1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		for i := 0; i < 3; i++ {
+			Expect(q.Push(buildSourceEnvelope(i), time.Now())).To(Succeed())
+		}
+		Expect(q.Close()).To(Succeed())
+
+		segments, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+		f, err := os.OpenFile(segments[0], os.O_WRONLY, 0600)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		q, err = egress.OpenDiskQueue(dir, 1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		defer q.Close()
+		Expect(q.Push(buildSourceEnvelope(3), time.Now())).To(Succeed())
+
+		Expect(readAll(q)).To(Equal([]string{"3"}))
+	})
+
+	It("removes its directory when it is closed empty", func() {
+		q, err := egress.OpenDiskQueue(dir, 1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(q.Push(buildSourceEnvelope(0), time.Now())).To(Succeed())
+		readAll(q)
+
+		Expect(q.Close()).To(Succeed())
+
+		_, err = os.Stat(dir)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
+
+func buildSourceEnvelope(i int) *loggregator_v2.Envelope {
+	return &loggregator_v2.Envelope{
+		SourceId: fmt.Sprint(i),
+		Message: &loggregator_v2.Envelope_Log{
+			Log: &loggregator_v2.Log{
+				Payload: []byte("just a test"),
+			},
+		},
+	}
+}
@@ -0,0 +1,105 @@
+package egress
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultErrorLogInterval is how often at most the app of a binding is told
+// that writes to its drain fail.
+const DefaultErrorLogInterval = 5 * time.Minute
+
+// failureClass describes why writing to a drain failed in terms an app
+// developer can act on. The error itself is not included as it may contain
+// the credentials of the drain.
+func failureClass(err error) string {
+	err = unwrapNetError(err)
+
+	switch e := err.(type) {
+	case HTTPStatusError:
+		return fmt.Sprintf("responded with HTTP status %d", e.StatusCode)
+	case *net.DNSError:
+		return "DNS lookup failed"
+	case tls.RecordHeaderError,
+		x509.UnknownAuthorityError,
+		x509.HostnameError,
+		x509.CertificateInvalidError:
+		return "TLS handshake failed"
+	}
+
+	if err == syscall.ECONNREFUSED {
+		return "connection refused"
+	}
+
+	if strings.Contains(err.Error(), "tls:") || strings.Contains(err.Error(), "TLS handshake") {
+		return "TLS handshake failed"
+	}
+
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return "timed out"
+	}
+
+	return "write failed"
+}
+
+// unwrapNetError returns the error underneath the errors the net, net/http
+// and os packages wrap around it.
+func unwrapNetError(err error) error {
+	for {
+		switch e := err.(type) {
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		case *os.SyscallError:
+			err = e.Err
+		default:
+			return err
+		}
+	}
+}
+
+// errorLogLimiter limits how often the app of a binding is told that writes
+// to its drain fail.
+type errorLogLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newErrorLogLimiter(interval time.Duration) *errorLogLimiter {
+	return &errorLogLimiter{
+		interval: interval,
+		last:     make(map[string]time.Time),
+	}
+}
+
+// allow reports whether an error log may be emitted for the key. Keys that
+// have not been seen for an interval are forgotten.
+func (l *errorLogLimiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := l.last[key]; ok && now.Sub(last) < l.interval {
+		return false
+	}
+
+	for k, last := range l.last {
+		if now.Sub(last) >= l.interval {
+			delete(l.last, k)
+		}
+	}
+	l.last[key] = now
+
+	return true
+}
@@ -0,0 +1,207 @@
+package egress
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+)
+
+// statsRetention is how long the statistics of a binding are kept after its
+// last writer is closed. Bindings reconnect whenever their ingress stream is
+// interrupted, which should not reset their statistics.
+const statsRetention = time.Minute
+
+// lagWindow is how long the maximum lag of a binding is kept. The reported
+// maximum covers the last one to two windows.
+const lagWindow = time.Minute
+
+// Drain states reported by DrainStats.
+const (
+	DrainConnected    = "connected"
+	DrainRetrying     = "retrying"
+	DrainDisabled     = "disabled"
+	DrainDisconnected = "disconnected"
+)
+
+// DrainStats counts what happened to the envelopes of a binding. It is safe
+// to use a nil *DrainStats, which records nothing.
+type DrainStats struct {
+	ingress uint64
+	egress  uint64
+	dropped uint64
+
+	mu            sync.Mutex
+	connections   int
+	lastWrite     time.Time
+	lastError     string
+	lastErrorTime time.Time
+	retryAttempt  int
+	disabled      bool
+
+	lagWindowStart time.Time
+	maxLag         time.Duration
+	prevMaxLag     time.Duration
+}
+
+// DrainStatsSnapshot reports the statistics of a binding.
+type DrainStatsSnapshot struct {
+	AppID         string        `json:"app_id"`
+	Hostname      string        `json:"hostname"`
+	Drain         string        `json:"drain"`
+	Ingress       uint64        `json:"ingress"`
+	Egress        uint64        `json:"egress"`
+	Dropped       uint64        `json:"dropped"`
+	LastWrite     time.Time     `json:"last_write"`
+	LastError     string        `json:"last_error"`
+	LastErrorTime time.Time     `json:"last_error_time"`
+	RetryAttempt  int           `json:"retry_attempt"`
+	State         string        `json:"state"`
+	MaxLag        time.Duration `json:"max_lag_ns"`
+}
+
+func (s *DrainStats) addIngress(n uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.ingress, n)
+}
+
+func (s *DrainStats) addEgress(n uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.egress, n)
+}
+
+func (s *DrainStats) addDropped(n uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.dropped, n)
+}
+
+// writeSucceeded records a successful write or flush.
+func (s *DrainStats) writeSucceeded() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastWrite = time.Now()
+	s.retryAttempt = 0
+}
+
+// writeFailed records a failed write and the attempt that will be made next.
+// The error is only recorded by its failure class as it may contain the
+// credentials of the drain.
+func (s *DrainStats) writeFailed(err error, retryAttempt int) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastError = failureClass(err)
+	s.lastErrorTime = time.Now()
+	s.retryAttempt = retryAttempt
+}
+
+// observeLag records how long after it was created an envelope was written
+// to the drain.
+func (s *DrainStats) observeLag(lag time.Duration) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rotateLagWindow(time.Now())
+	if lag > s.maxLag {
+		s.maxLag = lag
+	}
+}
+
+// rotateLagWindow starts a new lag window if the current one has ended.
+func (s *DrainStats) rotateLagWindow(now time.Time) {
+	elapsed := now.Sub(s.lagWindowStart)
+	if elapsed < lagWindow {
+		return
+	}
+
+	s.prevMaxLag = s.maxLag
+	if elapsed >= 2*lagWindow {
+		s.prevMaxLag = 0
+	}
+	s.maxLag = 0
+	s.lagWindowStart = now
+}
+
+// setDisabled records whether the circuit breaker disabled the drain.
+func (s *DrainStats) setDisabled(disabled bool) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.disabled = disabled
+}
+
+// snapshot returns the statistics without the identity of the binding.
+func (s *DrainStats) snapshot() DrainStatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := DrainConnected
+	switch {
+	case s.connections == 0:
+		state = DrainDisconnected
+	case s.disabled:
+		state = DrainDisabled
+	case s.retryAttempt > 0:
+		state = DrainRetrying
+	}
+
+	s.rotateLagWindow(time.Now())
+	maxLag := s.maxLag
+	if s.prevMaxLag > maxLag {
+		maxLag = s.prevMaxLag
+	}
+
+	return DrainStatsSnapshot{
+		Ingress:       atomic.LoadUint64(&s.ingress),
+		Egress:        atomic.LoadUint64(&s.egress),
+		Dropped:       atomic.LoadUint64(&s.dropped),
+		LastWrite:     s.lastWrite,
+		LastError:     s.lastError,
+		LastErrorTime: s.lastErrorTime,
+		RetryAttempt:  s.retryAttempt,
+		State:         state,
+		MaxLag:        maxLag,
+	}
+}
+
+// egressStatsMetric counts envelopes sent to a drain in the statistics of
+// its binding as well as in the egress metric.
+type egressStatsMetric struct {
+	pulseemitter.CounterMetric
+	stats *DrainStats
+}
+
+// Increment increments both the egress metric and the statistics.
+func (m egressStatsMetric) Increment(n uint64) {
+	if m.CounterMetric != nil {
+		m.CounterMetric.Increment(n)
+	}
+	m.stats.addEgress(n)
+}
@@ -0,0 +1,204 @@
+package egress
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/rfc5424"
+)
+
+// messageFormatter encodes an envelope into the syslog messages that are
+// written to a drain.
+type messageFormatter func(
+	env *loggregator_v2.Envelope,
+	hostname string,
+	appID string,
+) ([][]byte, error)
+
+// formatterFor selects a messageFormatter based on the `format` query
+// parameter of the drain URL. It defaults to RFC 5424.
+func formatterFor(u *url.URL) messageFormatter {
+	switch u.Query().Get("format") {
+	case "rfc3164":
+		return formatRFC3164
+	default:
+		return formatRFC5424
+	}
+}
+
+func formatRFC5424(
+	env *loggregator_v2.Envelope,
+	hostname string,
+	appID string,
+) ([][]byte, error) {
+	msgs := generateRFC5424Messages(env, hostname, appID)
+	encoded := make([][]byte, 0, len(msgs))
+	for _, msg := range msgs {
+		b, err := msg.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		encoded = append(encoded, b)
+	}
+
+	return encoded, nil
+}
+
+// formatRFC3164 encodes an envelope as BSD syslog messages. It uses the same
+// hostname, app name and process ID mapping as RFC 5424: the app ID is the
+// tag and the process ID is its bracketed suffix. Structured data is not part
+// of RFC 3164 and is written at the start of the message content instead.
+func formatRFC3164(
+	env *loggregator_v2.Envelope,
+	hostname string,
+	appID string,
+) ([][]byte, error) {
+	msgs := generateRFC5424Messages(env, hostname, appID)
+	encoded := make([][]byte, 0, len(msgs))
+	for _, msg := range msgs {
+		encoded = append(encoded, marshalRFC3164(msg))
+	}
+
+	return encoded, nil
+}
+
+func marshalRFC3164(m rfc5424.Message) []byte {
+	b := bytes.NewBuffer(nil)
+	fmt.Fprintf(b, "<%d>%s %s %s%s: ",
+		m.Priority,
+		m.Timestamp.Format(time.Stamp),
+		m.Hostname,
+		m.AppName,
+		m.ProcessID,
+	)
+
+	for _, sd := range m.StructuredData {
+		fmt.Fprintf(b, "[%s", sd.ID)
+		for _, p := range sd.Parameters {
+			fmt.Fprintf(b, " %s=%q", p.Name, p.Value)
+		}
+		fmt.Fprint(b, "] ")
+	}
+
+	b.Write(m.Message)
+
+	return b.Bytes()
+}
+
+// messageFramer delimits an encoded syslog message so that it can be written
+// to a stream.
+type messageFramer func(msg []byte) []byte
+
+// framerFor selects a messageFramer based on the `framing` query parameter of
+// the drain URL. It defaults to octet counting.
+func framerFor(u *url.URL) messageFramer {
+	switch u.Query().Get("framing") {
+	case "lf":
+		return frameWithLF
+	case "nul":
+		return frameWithNUL
+	default:
+		return frameWithOctetCount
+	}
+}
+
+// frameWithOctetCount prefixes the message with its length as described in
+// RFC 6587 section 3.4.1.
+func frameWithOctetCount(msg []byte) []byte {
+	frame := strconv.AppendInt(nil, int64(len(msg)), 10)
+	frame = append(frame, ' ')
+	return append(frame, msg...)
+}
+
+// frameWithLF terminates the message with a line feed as described in RFC
+// 6587 section 3.4.2. Line feeds within the message are escaped so that a
+// single message is never read as multiple frames.
+func frameWithLF(msg []byte) []byte {
+	msg = bytes.TrimSuffix(msg, []byte("\n"))
+	frame := bytes.Replace(msg, []byte("\n"), []byte(`\n`), -1)
+	return append(frame, '\n')
+}
+
+// frameWithNUL terminates the message with a NUL character. Payloads have
+// their NUL characters removed before they are written.
+func frameWithNUL(msg []byte) []byte {
+	return append(msg, 0)
+}
+
+// jsonDocument is the JSON representation of an envelope written to drains
+// with `format=json`.
+type jsonDocument struct {
+	Timestamp  string                    `json:"timestamp"`
+	AppID      string                    `json:"app_id"`
+	Hostname   string                    `json:"hostname"`
+	SourceType string                    `json:"source_type"`
+	InstanceID string                    `json:"instance_id"`
+	LogType    string                    `json:"log_type,omitempty"`
+	Payload    string                    `json:"payload,omitempty"`
+	Tags       map[string]string         `json:"tags,omitempty"`
+	Gauge      map[string]jsonGaugeValue `json:"gauge,omitempty"`
+	Counter    *jsonCounter              `json:"counter,omitempty"`
+}
+
+type jsonGaugeValue struct {
+	Unit  string  `json:"unit"`
+	Value float64 `json:"value"`
+}
+
+type jsonCounter struct {
+	Name  string `json:"name"`
+	Delta uint64 `json:"delta"`
+	Total uint64 `json:"total"`
+}
+
+// formatJSON encodes an envelope as a single JSON document. Envelopes other
+// than logs, gauges and counters are ignored.
+func formatJSON(
+	env *loggregator_v2.Envelope,
+	hostname string,
+	appID string,
+) ([][]byte, error) {
+	doc := jsonDocument{
+		Timestamp:  time.Unix(0, env.GetTimestamp()).UTC().Format(time.RFC3339Nano),
+		AppID:      appID,
+		Hostname:   hostname,
+		SourceType: env.GetTags()["source_type"],
+		InstanceID: env.GetInstanceId(),
+		Tags:       env.GetTags(),
+	}
+
+	switch env.GetMessage().(type) {
+	case *loggregator_v2.Envelope_Log:
+		doc.LogType = env.GetLog().GetType().String()
+		doc.Payload = string(removeNulls(env.GetLog().GetPayload()))
+	case *loggregator_v2.Envelope_Gauge:
+		doc.Gauge = make(map[string]jsonGaugeValue)
+		for name, g := range env.GetGauge().GetMetrics() {
+			doc.Gauge[name] = jsonGaugeValue{
+				Unit:  g.GetUnit(),
+				Value: g.GetValue(),
+			}
+		}
+	case *loggregator_v2.Envelope_Counter:
+		doc.Counter = &jsonCounter{
+			Name:  env.GetCounter().GetName(),
+			Delta: env.GetCounter().GetDelta(),
+			Total: env.GetCounter().GetTotal(),
+		}
+	default:
+		return nil, nil
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{b}, nil
+}
@@ -2,12 +2,15 @@ package egress
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
@@ -15,66 +18,409 @@ import (
 	"code.cloudfoundry.org/scalable-syslog/internal/api"
 )
 
+const (
+	// maxBatchSize is the largest number of messages that will be posted in a
+	// single request.
+	maxBatchSize = 1000
+
+	// maxBatchBytes is the largest request body that will be built up before
+	// a batch is posted.
+	maxBatchBytes = 256 * 1024
+
+	defaultBatchInterval = time.Second
+)
+
+// adapterParams are the query parameters of a drain URL that configure the
+// adapter. They are not sent to HTTPS drains.
+var adapterParams = []string{
+	"format",
+	"compress",
+	"framing",
+	"batch-size",
+	"batch-interval",
+	"buffer-size",
+	"retry-base",
+	"retry-cap",
+	"retry-max",
+}
+
+// HTTPSWriter posts syslog messages to an HTTPS drain. By default every
+// message is posted in its own request. When the drain URL has a
+// `batch-size` greater than one, messages are buffered and posted as a
+// newline delimited body once the batch is full or `batch-interval` has
+// elapsed. Drains with `format=json` receive a JSON document per envelope
+// instead of syslog messages. Drains with `compress=gzip` receive gzip
+// compressed request bodies.
 type HTTPSWriter struct {
 	hostname     string
 	appID        string
 	url          *url.URL
 	client       *http.Client
+	format       messageFormatter
+	contentType  string
+	compress     bool
 	egressMetric pulseemitter.CounterMetric
+
+	bytesMetric     pulseemitter.CounterMetric
+	sentBytesMetric pulseemitter.CounterMetric
+
+	batchSize     int
+	batchInterval time.Duration
+	batch         bytes.Buffer
+	batchCount    int
+	batchStart    time.Time
+
+	// batchTimestamps are the timestamps of the envelopes in the batch.
+	batchTimestamps []int64
+	posted          []func(timestamps []int64)
+
+	// buffered is the last envelope added to the batch. It is used to avoid
+	// adding an envelope twice when a failed write is retried.
+	buffered *loggregator_v2.Envelope
 }
 
+// NewHTTPSWriter creates a new HTTPS writer that does not record the number
+// of bytes it sends.
 func NewHTTPSWriter(
 	binding *URLBinding,
 	netConf NetworkTimeoutConfig,
 	skipCertVerify bool,
 	egressMetric pulseemitter.CounterMetric,
 ) WriteCloser {
+	return newHTTPSWriter(
+		binding,
+		netConf,
+		skipCertVerify,
+		egressMetric,
+		api.NewTLSConfig(),
+		nullMetric{},
+		nullMetric{},
+	)
+}
+
+// HTTPSWriterConstructor returns a WriterConstructor for HTTPS writers that
+// connect with the given TLS policy. They record the size of every request
+// body before compression in bytesMetric and the number of bytes actually
+// sent in sentBytesMetric.
+func HTTPSWriterConstructor(
+	policy *tls.Config,
+	bytesMetric pulseemitter.CounterMetric,
+	sentBytesMetric pulseemitter.CounterMetric,
+) WriterConstructor {
+	return func(
+		binding *URLBinding,
+		netConf NetworkTimeoutConfig,
+		skipCertVerify bool,
+		egressMetric pulseemitter.CounterMetric,
+	) WriteCloser {
+		return newHTTPSWriter(
+			binding,
+			netConf,
+			skipCertVerify,
+			egressMetric,
+			policy,
+			bytesMetric,
+			sentBytesMetric,
+		)
+	}
+}
+
+func newHTTPSWriter(
+	binding *URLBinding,
+	netConf NetworkTimeoutConfig,
+	skipCertVerify bool,
+	egressMetric pulseemitter.CounterMetric,
+	policy *tls.Config,
+	bytesMetric pulseemitter.CounterMetric,
+	sentBytesMetric pulseemitter.CounterMetric,
+) WriteCloser {
+	client := httpClient(netConf, drainTLSConfig(policy, binding, skipCertVerify))
+	size, interval := batchSettings(binding.URL)
 
-	client := httpClient(netConf, skipCertVerify)
+	format := formatterFor(binding.URL)
+	contentType := "text/plain"
+	if binding.URL.Query().Get("format") == "json" {
+		format = formatJSON
+		contentType = "application/json"
+		if size > 1 {
+			contentType = "application/x-ndjson"
+		}
+	}
 
 	return &HTTPSWriter{
-		url:          binding.URL,
-		appID:        binding.AppID,
-		hostname:     binding.Hostname,
-		client:       client,
-		egressMetric: egressMetric,
+		url:             postURL(binding.URL),
+		appID:           binding.AppID,
+		hostname:        binding.Hostname,
+		client:          client,
+		format:          format,
+		contentType:     contentType,
+		compress:        binding.URL.Query().Get("compress") == "gzip",
+		egressMetric:    egressMetric,
+		bytesMetric:     bytesMetric,
+		sentBytesMetric: sentBytesMetric,
+		batchSize:       size,
+		batchInterval:   interval,
 	}
 }
 
+// Write posts the envelope to the drain, or adds it to the current batch if
+// batching is enabled. If posting a batch fails the batch is kept so that
+// retrying the write will post it again, until it is discarded.
 func (w *HTTPSWriter) Write(env *loggregator_v2.Envelope) error {
-	msgs := generateRFC5424Messages(env, w.hostname, w.appID)
-	for _, msg := range msgs {
-		b, err := msg.MarshalBinary()
+	if w.batchSize <= 1 {
+		return w.writeEach(env)
+	}
+
+	if env != w.buffered {
+		msgs, err := w.format(env, w.hostname, w.appID)
 		if err != nil {
 			return err
 		}
 
-		resp, err := w.client.Post(w.url.String(), "text/plain", bytes.NewBuffer(b))
-		if err != nil {
+		if w.wouldOverflow(msgs) {
+			if err := w.flushBatch(); err != nil {
+				return err
+			}
+		}
+
+		w.addToBatch(msgs, env.GetTimestamp())
+		w.buffered = env
+	}
+
+	if w.batchCount >= w.batchSize || w.batch.Len() >= maxBatchBytes {
+		return w.flushBatch()
+	}
+
+	return w.Flush()
+}
+
+// Flush posts the current batch if the batch interval has elapsed since the
+// first message was added to it.
+func (w *HTTPSWriter) Flush() error {
+	if !w.FlushPending() {
+		return nil
+	}
+
+	return w.flushBatch()
+}
+
+// FlushPending reports whether Flush would post the current batch.
+func (w *HTTPSWriter) FlushPending() bool {
+	return w.batchCount > 0 && time.Since(w.batchStart) >= w.batchInterval
+}
+
+// NotifyPosted calls f with the timestamps of the envelopes of every
+// request that is posted successfully. Envelopes without messages, such as
+// non-log envelopes, are reported with a zero timestamp along with the
+// envelopes written before them. f must not keep the timestamps.
+func (w *HTTPSWriter) NotifyPosted(f func(timestamps []int64)) bool {
+	w.posted = append(w.posted, f)
+
+	return true
+}
+
+// Close posts any remaining batched messages.
+func (w *HTTPSWriter) Close() error {
+	return w.flushBatch()
+}
+
+// Discard drops the batch that failed to post once the write is given up on,
+// so that it does not hold up the envelopes that follow. It returns the
+// number of messages dropped.
+func (w *HTTPSWriter) Discard() int {
+	n := w.batchCount
+	w.batch.Reset()
+	w.batchCount = 0
+	w.batchTimestamps = w.batchTimestamps[:0]
+	w.buffered = nil
+
+	return n
+}
+
+func (w *HTTPSWriter) writeEach(env *loggregator_v2.Envelope) error {
+	msgs, err := w.format(env, w.hostname, w.appID)
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range msgs {
+		if err := w.post(msg); err != nil {
 			return err
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode < 200 || resp.StatusCode > 299 {
-			return fmt.Errorf("Syslog Writer: Post responded with %d status code", resp.StatusCode)
+		w.egressMetric.Increment(1)
+	}
+
+	timestamp := env.GetTimestamp()
+	if len(msgs) == 0 {
+		timestamp = 0
+	}
+	w.notifyPosted([]int64{timestamp})
+
+	return nil
+}
+
+// postURL returns the drain URL without the query parameters that configure
+// the adapter.
+func postURL(drain *url.URL) *url.URL {
+	u := *drain
+	q := u.Query()
+	for _, p := range adapterParams {
+		q.Del(p)
+	}
+	u.RawQuery = q.Encode()
+
+	return &u
+}
+
+func (w *HTTPSWriter) wouldOverflow(msgs [][]byte) bool {
+	if w.batchCount == 0 {
+		return false
+	}
+
+	size := w.batch.Len()
+	for _, msg := range msgs {
+		size += len(msg) + 1
+	}
+
+	return w.batchCount+len(msgs) > w.batchSize || size > maxBatchBytes
+}
+
+func (w *HTTPSWriter) addToBatch(msgs [][]byte, timestamp int64) {
+	if len(msgs) == 0 {
+		w.skip()
+		return
+	}
+
+	if w.batchCount == 0 {
+		w.batchStart = time.Now()
+	}
+
+	for _, msg := range msgs {
+		w.batch.Write(msg)
+		if !bytes.HasSuffix(msg, []byte("\n")) {
+			w.batch.WriteByte('\n')
 		}
+	}
+	w.batchCount += len(msgs)
+	w.batchTimestamps = append(w.batchTimestamps, timestamp)
+}
 
-		io.Copy(ioutil.Discard, resp.Body)
+// skip reports an envelope without messages as posted once the envelopes
+// before it are.
+func (w *HTTPSWriter) skip() {
+	if w.batchCount == 0 {
+		w.notifyPosted([]int64{0})
+		return
+	}
 
-		w.egressMetric.Increment(1)
+	w.batchTimestamps = append(w.batchTimestamps, 0)
+}
+
+func (w *HTTPSWriter) flushBatch() error {
+	if w.batchCount == 0 {
+		return nil
 	}
 
+	if err := w.post(w.batch.Bytes()); err != nil {
+		return err
+	}
+
+	w.egressMetric.Increment(uint64(w.batchCount))
+	w.notifyPosted(w.batchTimestamps)
+	w.batch.Reset()
+	w.batchCount = 0
+	w.batchTimestamps = w.batchTimestamps[:0]
+
 	return nil
 }
 
-func (*HTTPSWriter) Close() error {
+func (w *HTTPSWriter) notifyPosted(timestamps []int64) {
+	for _, f := range w.posted {
+		f(timestamps)
+	}
+}
+
+func (w *HTTPSWriter) post(body []byte) error {
+	sent := body
+	if w.compress {
+		var err error
+		sent, err = gzipBody(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url.String(), bytes.NewReader(sent))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", w.contentType)
+	if w.compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	w.bytesMetric.Increment(uint64(len(body)))
+	w.sentBytesMetric.Increment(uint64(len(sent)))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return HTTPStatusError{StatusCode: resp.StatusCode}
+	}
+
+	io.Copy(ioutil.Discard, resp.Body)
+
 	return nil
 }
 
-func httpClient(netConf NetworkTimeoutConfig, skipCertVerify bool) *http.Client {
-	tlsConfig := api.NewTLSConfig()
-	tlsConfig.InsecureSkipVerify = skipCertVerify
+// HTTPStatusError is returned when an HTTPS drain responds with a status
+// code outside of 2xx.
+type HTTPStatusError struct {
+	StatusCode int
+}
+
+func (e HTTPStatusError) Error() string {
+	return fmt.Sprintf("Syslog Writer: Post responded with %d status code", e.StatusCode)
+}
+
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func batchSettings(u *url.URL) (int, time.Duration) {
+	q := u.Query()
+
+	size, err := strconv.Atoi(q.Get("batch-size"))
+	if err != nil || size < 1 {
+		size = 1
+	}
+	if size > maxBatchSize {
+		size = maxBatchSize
+	}
+
+	interval, err := time.ParseDuration(q.Get("batch-interval"))
+	if err != nil || interval <= 0 {
+		interval = defaultBatchInterval
+	}
+
+	return size, interval
+}
 
+func httpClient(netConf NetworkTimeoutConfig, tlsConfig *tls.Config) *http.Client {
 	tr := &http.Transport{
 		DialContext: (&net.Dialer{
 			Timeout:   netConf.DialTimeout,
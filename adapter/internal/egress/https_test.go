@@ -1,14 +1,23 @@
 package egress_test
 
 import (
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
 	"code.cloudfoundry.org/rfc5424"
 	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/test_util"
+	"code.cloudfoundry.org/scalable-syslog/internal/api"
 	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -230,6 +239,434 @@ var _ = Describe("HTTPWriter", func() {
 		Expect(metric.Delta()).To(Equal(uint64(1)))
 	})
 
+	It("writes RFC 3164 messages when the drain asks for them", func() {
+		drain := newMockRawDrain(http.StatusOK)
+
+		b := buildURLBinding(
+			drain.URL+"/?format=rfc3164",
+			"test-app-id",
+			"test-hostname",
+		)
+
+		writer := egress.NewHTTPSWriter(
+			b,
+			netConf,
+			true,
+			&testhelper.SpyMetric{},
+		)
+
+		env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(drain.getBodies()).To(ConsistOf(
+			"<14>Jan  1 00:00:00 test-hostname test-app-id[APP/1]: just a test\n",
+		))
+	})
+
+	Describe("json format", func() {
+		It("posts log envelopes as JSON documents", func() {
+			drain := newMockRawDrain(http.StatusOK)
+			metric := &testhelper.SpyMetric{}
+
+			b := buildURLBinding(
+				drain.URL+"/?format=json",
+				"test-app-id",
+				"test-hostname",
+			)
+			writer := egress.NewHTTPSWriter(b, netConf, true, metric)
+
+			env := buildLogEnvelope("APP/PROC/WEB", "1", "just a test", loggregator_v2.Log_ERR)
+			Expect(writer.Write(env)).To(Succeed())
+
+			Expect(drain.getBodies()).To(HaveLen(1))
+			Expect(drain.getBodies()[0]).To(MatchJSON(`{
+				"timestamp": "1970-01-01T00:00:00.012345678Z",
+				"app_id": "test-app-id",
+				"hostname": "test-hostname",
+				"source_type": "APP/PROC/WEB",
+				"instance_id": "1",
+				"log_type": "ERR",
+				"payload": "just a test",
+				"tags": {"source_type": "APP/PROC/WEB"}
+			}`))
+			Expect(drain.getHeaders()[0].Get("Content-Type")).To(Equal("application/json"))
+			Expect(metric.Delta()).To(Equal(uint64(1)))
+		})
+
+		It("posts all the gauge metrics of an envelope in one JSON document", func() {
+			drain := newMockRawDrain(http.StatusOK)
+
+			b := buildURLBinding(
+				drain.URL+"/?format=json",
+				"test-app-id",
+				"test-hostname",
+			)
+			writer := egress.NewHTTPSWriter(b, netConf, true, &testhelper.SpyMetric{})
+
+			Expect(writer.Write(buildGaugeEnvelope("1"))).To(Succeed())
+
+			Expect(drain.getBodies()).To(HaveLen(1))
+			Expect(drain.getBodies()[0]).To(MatchJSON(`{
+				"timestamp": "1970-01-01T00:00:00.012345678Z",
+				"app_id": "test-app-id",
+				"hostname": "test-hostname",
+				"source_type": "",
+				"instance_id": "1",
+				"gauge": {
+					"cpu": {"unit": "percentage", "value": 0.23},
+					"disk": {"unit": "bytes", "value": 1234},
+					"disk_quota": {"unit": "bytes", "value": 1024},
+					"memory": {"unit": "bytes", "value": 5423},
+					"memory_quota": {"unit": "bytes", "value": 8000}
+				}
+			}`))
+		})
+
+		It("posts counter envelopes as JSON documents", func() {
+			drain := newMockRawDrain(http.StatusOK)
+
+			b := buildURLBinding(
+				drain.URL+"/?format=json",
+				"test-app-id",
+				"test-hostname",
+			)
+			writer := egress.NewHTTPSWriter(b, netConf, true, &testhelper.SpyMetric{})
+
+			Expect(writer.Write(buildCounterEnvelope("1"))).To(Succeed())
+
+			Expect(drain.getBodies()).To(HaveLen(1))
+			Expect(drain.getBodies()[0]).To(MatchJSON(`{
+				"timestamp": "1970-01-01T00:00:00.012345678Z",
+				"app_id": "test-app-id",
+				"hostname": "test-hostname",
+				"source_type": "",
+				"instance_id": "1",
+				"counter": {"name": "some-counter", "delta": 1, "total": 99}
+			}`))
+		})
+
+		It("posts batches of JSON documents as newline delimited JSON", func() {
+			drain := newMockRawDrain(http.StatusOK)
+
+			b := buildURLBinding(
+				drain.URL+"/?format=json&batch-size=2",
+				"test-app-id",
+				"test-hostname",
+			)
+			writer := egress.NewHTTPSWriter(b, netConf, true, &testhelper.SpyMetric{})
+
+			Expect(writer.Write(buildCounterEnvelope("1"))).To(Succeed())
+			Expect(writer.Write(buildCounterEnvelope("2"))).To(Succeed())
+
+			Expect(drain.getBodies()).To(HaveLen(1))
+			Expect(strings.Split(strings.TrimSuffix(drain.getBodies()[0], "\n"), "\n")).To(HaveLen(2))
+			Expect(drain.getHeaders()[0].Get("Content-Type")).To(Equal("application/x-ndjson"))
+		})
+	})
+
+	It("presents the client certificate of the binding", func() {
+		peerCerts := make(chan int, 1)
+		drain := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peerCerts <- len(r.TLS.PeerCertificates)
+		}))
+		drain.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+		drain.StartTLS()
+		defer drain.Close()
+
+		clientCert, err := tls.LoadX509KeyPair(
+			test_util.Cert("adapter.crt"),
+			test_util.Cert("adapter.key"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		b := buildURLBinding(drain.URL, "test-app-id", "test-hostname")
+		b.Certificates = []tls.Certificate{clientCert}
+		writer := egress.NewHTTPSWriter(b, netConf, true, &testhelper.SpyMetric{})
+
+		env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(Succeed())
+		Expect(peerCerts).To(Receive(Equal(1)))
+	})
+
+	It("trusts the CA certificates of the binding", func() {
+		drain := newMockOKDrain()
+
+		pool := x509.NewCertPool()
+		pool.AddCert(drain.Certificate())
+
+		b := buildURLBinding(drain.URL, "test-app-id", "test-hostname")
+		b.RootCAs = pool
+		writer := egress.NewHTTPSWriter(b, netConf, false, &testhelper.SpyMetric{})
+
+		env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(Succeed())
+	})
+
+	It("connects with the TLS policy of the constructor", func() {
+		drain := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		drain.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+		drain.StartTLS()
+		defer drain.Close()
+
+		policy := api.NewTLSConfig()
+		policy.MinVersion = tls.VersionTLS13
+
+		b := buildURLBinding(drain.URL, "test-app-id", "test-hostname")
+		constructor := egress.HTTPSWriterConstructor(policy, &testhelper.SpyMetric{}, &testhelper.SpyMetric{})
+		writer := constructor(b, netConf, true, &testhelper.SpyMetric{})
+
+		env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(HaveOccurred())
+		Expect(policy.InsecureSkipVerify).To(BeFalse())
+	})
+
+	Describe("gzip compression", func() {
+		It("compresses request bodies when the drain asks for it", func() {
+			drain := newMockRawDrain(http.StatusOK)
+
+			b := buildURLBinding(
+				drain.URL+"/?compress=gzip",
+				"test-app-id",
+				"test-hostname",
+			)
+			writer := egress.NewHTTPSWriter(b, netConf, true, &testhelper.SpyMetric{})
+
+			env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+
+			Expect(drain.getHeaders()).To(HaveLen(1))
+			Expect(drain.getHeaders()[0].Get("Content-Encoding")).To(Equal("gzip"))
+
+			r, err := gzip.NewReader(strings.NewReader(drain.getBodies()[0]))
+			Expect(err).ToNot(HaveOccurred())
+			body, err := ioutil.ReadAll(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(Equal(
+				"<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/1] - - just a test\n",
+			))
+		})
+
+		It("does not compress request bodies by default", func() {
+			drain := newMockRawDrain(http.StatusOK)
+
+			b := buildURLBinding(drain.URL, "test-app-id", "test-hostname")
+			writer := egress.NewHTTPSWriter(b, netConf, true, &testhelper.SpyMetric{})
+
+			env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+
+			Expect(drain.getHeaders()[0].Get("Content-Encoding")).To(BeEmpty())
+		})
+
+		It("records the bytes before and after compression", func() {
+			drain := newMockRawDrain(http.StatusOK)
+			bytesMetric := &testhelper.SpyMetric{}
+			sentBytesMetric := &testhelper.SpyMetric{}
+
+			b := buildURLBinding(
+				drain.URL+"/?compress=gzip&batch-size=100",
+				"test-app-id",
+				"test-hostname",
+			)
+			constructor := egress.HTTPSWriterConstructor(api.NewTLSConfig(), bytesMetric, sentBytesMetric)
+			writer := constructor(b, netConf, true, &testhelper.SpyMetric{})
+
+			for i := 0; i < 100; i++ {
+				env := buildLogEnvelope("APP", "1", strings.Repeat("compress me ", 50), loggregator_v2.Log_OUT)
+				Expect(writer.Write(env)).To(Succeed())
+			}
+
+			Expect(drain.getBodies()).To(HaveLen(1))
+			Expect(sentBytesMetric.Delta()).To(Equal(uint64(len(drain.getBodies()[0]))))
+			Expect(bytesMetric.Delta()).To(BeNumerically(">", 10*sentBytesMetric.Delta()))
+		})
+	})
+
+	Describe("batching", func() {
+		It("posts a batch once it reaches the batch size", func() {
+			drain := newMockRawDrain(http.StatusOK)
+			metric := &testhelper.SpyMetric{}
+
+			b := buildURLBinding(
+				drain.URL+"/?batch-size=3",
+				"test-app-id",
+				"test-hostname",
+			)
+			writer := egress.NewHTTPSWriter(b, netConf, true, metric)
+
+			for i := 0; i < 2; i++ {
+				env := buildLogEnvelope("APP", "1", fmt.Sprintf("log %d", i), loggregator_v2.Log_OUT)
+				Expect(writer.Write(env)).To(Succeed())
+			}
+			Expect(drain.getBodies()).To(BeEmpty())
+			Expect(metric.Delta()).To(BeZero())
+
+			env := buildLogEnvelope("APP", "1", "log 2", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+
+			Expect(drain.getBodies()).To(ConsistOf(
+				"<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/1] - - log 0\n" +
+					"<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/1] - - log 1\n" +
+					"<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/1] - - log 2\n",
+			))
+			Expect(metric.Delta()).To(Equal(uint64(3)))
+		})
+
+		It("posts all the gauge metrics of an envelope in a single request", func() {
+			drain := newMockRawDrain(http.StatusOK)
+
+			b := buildURLBinding(
+				drain.URL+"/?batch-size=5",
+				"test-app-id",
+				"test-hostname",
+			)
+			writer := egress.NewHTTPSWriter(b, netConf, true, &testhelper.SpyMetric{})
+
+			Expect(writer.Write(buildGaugeEnvelope("1"))).To(Succeed())
+
+			Expect(drain.getBodies()).To(HaveLen(1))
+			Expect(strings.Split(strings.TrimSuffix(drain.getBodies()[0], "\n"), "\n")).To(HaveLen(5))
+		})
+
+		It("posts a partial batch when flushed after the batch interval", func() {
+			drain := newMockRawDrain(http.StatusOK)
+
+			b := buildURLBinding(
+				drain.URL+"/?batch-size=100&batch-interval=10ms",
+				"test-app-id",
+				"test-hostname",
+			)
+			writer := egress.NewHTTPSWriter(b, netConf, true, &testhelper.SpyMetric{})
+			flusher := writer.(egress.Flusher)
+
+			env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+			Expect(flusher.Flush()).To(Succeed())
+			Expect(drain.getBodies()).To(BeEmpty())
+
+			time.Sleep(20 * time.Millisecond)
+			Expect(flusher.Flush()).To(Succeed())
+			Expect(drain.getBodies()).To(HaveLen(1))
+		})
+
+		It("posts a partial batch when closed", func() {
+			drain := newMockRawDrain(http.StatusOK)
+
+			b := buildURLBinding(
+				drain.URL+"/?batch-size=100",
+				"test-app-id",
+				"test-hostname",
+			)
+			writer := egress.NewHTTPSWriter(b, netConf, true, &testhelper.SpyMetric{})
+
+			env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+			Expect(writer.Close()).To(Succeed())
+
+			Expect(drain.getBodies()).To(HaveLen(1))
+		})
+
+		It("keeps the batch when the post fails so that a retry sends it once", func() {
+			drain := newMockRawDrain(http.StatusInternalServerError)
+			metric := &testhelper.SpyMetric{}
+
+			b := buildURLBinding(
+				drain.URL+"/?batch-size=1000",
+				"test-app-id",
+				"test-hostname",
+			)
+			writer := egress.NewHTTPSWriter(b, netConf, true, metric)
+
+			env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+			Expect(writer.Close()).To(HaveOccurred())
+			Expect(metric.Delta()).To(BeZero())
+
+			drain.setStatus(http.StatusOK)
+			Expect(writer.Write(env)).To(Succeed())
+			Expect(writer.Close()).To(Succeed())
+
+			bodies := drain.getBodies()
+			Expect(bodies).To(HaveLen(2))
+			Expect(bodies[1]).To(Equal(
+				"<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/1] - - just a test\n",
+			))
+			Expect(metric.Delta()).To(Equal(uint64(1)))
+		})
+
+		It("drops the batch that failed to post when it is discarded", func() {
+			drain := newMockRawDrain(http.StatusInternalServerError)
+
+			b := buildURLBinding(
+				drain.URL+"/?batch-size=1000",
+				"test-app-id",
+				"test-hostname",
+			)
+			writer := egress.NewHTTPSWriter(b, netConf, true, &testhelper.SpyMetric{})
+
+			env := buildLogEnvelope("APP", "1", "stale", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+			Expect(writer.Close()).To(HaveOccurred())
+			Expect(writer.(egress.Discarder).Discard()).To(Equal(1))
+
+			drain.setStatus(http.StatusOK)
+			env = buildLogEnvelope("APP", "1", "fresh", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+			Expect(writer.Close()).To(Succeed())
+
+			bodies := drain.getBodies()
+			Expect(bodies).To(HaveLen(2))
+			Expect(bodies[1]).To(Equal(
+				"<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/1] - - fresh\n",
+			))
+		})
+		It("reports the timestamps of the envelopes it posted", func() {
+			drain := newMockRawDrain(http.StatusInternalServerError)
+
+			b := buildURLBinding(
+				drain.URL+"/?batch-size=2",
+				"test-app-id",
+				"test-hostname",
+			)
+			writer := egress.NewHTTPSWriter(b, netConf, true, &testhelper.SpyMetric{})
+
+			var posted [][]int64
+			Expect(writer.(egress.PostNotifier).NotifyPosted(func(ts []int64) {
+				posted = append(posted, append([]int64(nil), ts...))
+			})).To(BeTrue())
+
+			first := buildLogEnvelope("APP", "1", "log 0", loggregator_v2.Log_OUT)
+			first.Timestamp = 1
+			Expect(writer.Write(first)).To(Succeed())
+			second := buildLogEnvelope("APP", "1", "log 1", loggregator_v2.Log_OUT)
+			second.Timestamp = 2
+			Expect(writer.Write(second)).To(HaveOccurred())
+			Expect(posted).To(BeEmpty())
+
+			drain.setStatus(http.StatusOK)
+			Expect(writer.Write(second)).To(Succeed())
+			Expect(posted).To(Equal([][]int64{{1, 2}}))
+		})
+	})
+
+	It("does not send the query parameters that configure the adapter", func() {
+		drain := newMockRawDrain(http.StatusOK)
+		b := buildURLBinding(
+			drain.URL+"/_bulk?format=json&compress=gzip&batch-size=2&batch-interval=1s"+
+				"&retry-base=1ms&retry-cap=1s&retry-max=3&buffer-size=10&framing=lf&pipeline=logs",
+			"test-app-id",
+			"test-hostname",
+		)
+		writer := egress.NewHTTPSWriter(b, netConf, true, &testhelper.SpyMetric{})
+
+		env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+
+		Expect(drain.getQueries()).To(Equal([]string{"pipeline=logs"}))
+		Expect(b.URL.Query().Get("batch-size")).To(Equal("2"))
+	})
+
 	It("ignores non-log envelopes", func() {
 		drain := newMockOKDrain()
 
@@ -287,6 +724,60 @@ func newMockDrain(status int) *SpyDrain {
 	return drain
 }
 
+type SpyRawDrain struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	status  int
+	bodies  []string
+	headers []http.Header
+	queries []string
+}
+
+func newMockRawDrain(status int) *SpyRawDrain {
+	drain := &SpyRawDrain{status: status}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		Expect(err).ToNot(HaveOccurred())
+		defer r.Body.Close()
+
+		drain.mu.Lock()
+		drain.bodies = append(drain.bodies, string(body))
+		drain.headers = append(drain.headers, r.Header)
+		drain.queries = append(drain.queries, r.URL.RawQuery)
+		status := drain.status
+		drain.mu.Unlock()
+
+		w.WriteHeader(status)
+	})
+	drain.Server = httptest.NewTLSServer(handler)
+	return drain
+}
+
+func (d *SpyRawDrain) setStatus(status int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status = status
+}
+
+func (d *SpyRawDrain) getBodies() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.bodies
+}
+
+func (d *SpyRawDrain) getQueries() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.queries
+}
+
+func (d *SpyRawDrain) getHeaders() []http.Header {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.headers
+}
+
 func buildURLBinding(u, appID, hostname string) *egress.URLBinding {
 	parsedURL, _ := url.Parse(u)
 
@@ -1,12 +1,16 @@
 package egress
 
 import (
-	"log"
-	"math"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strconv"
 	"time"
 
+	loggregator "code.cloudfoundry.org/go-loggregator"
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 )
 
 // RetryWrapper wraps a WriterConstructer, allowing it to retry writes.
@@ -16,6 +20,51 @@ func RetryWrapper(
 	maxRetries int,
 	logClient LogClient,
 	sourceIndex string,
+) WriterConstructor {
+	return retryWrapper(
+		wc,
+		func(*url.URL) (RetryDuration, int) {
+			return r, maxRetries
+		},
+		newErrorLogLimiter(DefaultErrorLogInterval),
+		logClient,
+		sourceIndex,
+	)
+}
+
+// RetryPolicyWrapper wraps a WriterConstructor, allowing it to retry writes
+// according to the policy. Drains can lower the base and cap durations and
+// the maximum number of retries with the `retry-base`, `retry-cap` and
+// `retry-max` query parameters, but can not raise them.
+func RetryPolicyWrapper(
+	wc WriterConstructor,
+	policy RetryPolicy,
+	logClient LogClient,
+	sourceIndex string,
+) WriterConstructor {
+	interval := policy.ErrorLogInterval
+	if interval <= 0 {
+		interval = DefaultErrorLogInterval
+	}
+
+	return retryWrapper(
+		wc,
+		func(u *url.URL) (RetryDuration, int) {
+			p := policy.forURL(u)
+			return p.Backoff(p.Base, p.Cap), p.MaxRetries
+		},
+		newErrorLogLimiter(interval),
+		logClient,
+		sourceIndex,
+	)
+}
+
+func retryWrapper(
+	wc WriterConstructor,
+	retries func(*url.URL) (RetryDuration, int),
+	limiter *errorLogLimiter,
+	logClient LogClient,
+	sourceIndex string,
 ) WriterConstructor {
 	return WriterConstructor(func(
 		binding *URLBinding,
@@ -29,14 +78,16 @@ func RetryWrapper(
 			skipCertVerify,
 			egressMetric,
 		)
+		r, maxRetries := retries(binding.URL)
 
 		return &RetryWriter{
-			writer:        writer,
-			retryDuration: r,
-			maxRetries:    maxRetries,
-			binding:       binding,
-			logClient:     logClient,
-			sourceIndex:   sourceIndex,
+			writerDelegate: writerDelegate{writer},
+			retryDuration:  r,
+			maxRetries:     maxRetries,
+			binding:        binding,
+			logClient:      logClient,
+			sourceIndex:    sourceIndex,
+			limiter:        limiter,
 		}
 	})
 }
@@ -44,59 +95,218 @@ func RetryWrapper(
 // RetryDuration calculates a duration based on the number of write attempts.
 type RetryDuration func(attempt int) time.Duration
 
+// Backoff builds a RetryDuration that starts at base and never exceeds cap.
+type Backoff func(base, cap time.Duration) RetryDuration
+
+// RetryPolicy configures how writes to a drain are retried.
+// ErrorLogInterval is how often at most the app of a binding is told that
+// writes to its drain fail. It defaults to DefaultErrorLogInterval.
+type RetryPolicy struct {
+	Backoff          Backoff
+	Base             time.Duration
+	Cap              time.Duration
+	MaxRetries       int
+	ErrorLogInterval time.Duration
+}
+
+// forURL returns the policy with the limits lowered by the query parameters
+// of the drain URL.
+func (p RetryPolicy) forURL(u *url.URL) RetryPolicy {
+	q := u.Query()
+
+	if d, err := time.ParseDuration(q.Get("retry-base")); err == nil && d > 0 && d < p.Base {
+		p.Base = d
+	}
+
+	if d, err := time.ParseDuration(q.Get("retry-cap")); err == nil && d > 0 && d < p.Cap {
+		p.Cap = d
+	}
+
+	if n, err := strconv.Atoi(q.Get("retry-max")); err == nil && n > 0 && n < p.MaxRetries {
+		p.MaxRetries = n
+	}
+
+	return p
+}
+
 // RetryWriter wraps a WriteCloser and will retry writes if the first fails.
 type RetryWriter struct {
-	writer        WriteCloser
+	writerDelegate
 	retryDuration RetryDuration
 	maxRetries    int
 	binding       *URLBinding
 	logClient     LogClient
 	sourceIndex   string
+	limiter       *errorLogLimiter
 }
 
 // Write will retry writes unitl maxRetries has been reached.
 func (r *RetryWriter) Write(e *loggregator_v2.Envelope) error {
-	logTemplate := "failed to write to %s, retrying in %s, err: %s"
+	return r.retry(func() error {
+		return r.writer.Write(e)
+	})
+}
+
+// Flush will flush the syslog writer if it buffers envelopes. Failed flushes
+// are retried in the same way as writes. Flushes that would not send anything
+// are skipped, so that they are not recorded as successful writes.
+func (r *RetryWriter) Flush() error {
+	f, ok := r.writer.(Flusher)
+	if !ok || !flushPending(r.writer) {
+		return nil
+	}
 
+	return r.retry(f.Flush)
+}
+
+func (r *RetryWriter) retry(write func() error) error {
 	var err error
 
 	for i := 0; i < r.maxRetries; i++ {
-		err = r.writer.Write(e)
+		err = write()
 		if err == nil {
+			r.binding.Stats.writeSucceeded()
 			return nil
 		}
 
+		if err == ErrCircuitOpen {
+			return err
+		}
+		r.binding.Stats.writeFailed(err, i+1)
+
 		if contextDone(r.binding.Context) {
 			return err
 		}
 
 		sleepDuration := r.retryDuration(i)
-		log.Printf(logTemplate, r.binding.URL.Host, sleepDuration, err)
+		r.binding.Logger.With(logging.Fields{
+			"error":         err,
+			"retry_attempt": i + 1,
+		}).Warnf("failed to write to syslog drain, retrying in %s", sleepDuration)
+		r.emitErrorLog(err)
 
-		time.Sleep(sleepDuration)
+		if !r.wait(sleepDuration) {
+			return err
+		}
 	}
 
 	return err
 }
 
-// Close delegates to the syslog writer.
-func (r *RetryWriter) Close() error {
-	return r.writer.Close()
+// wait waits for d. It returns false if the binding stopped in the meantime.
+func (r *RetryWriter) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-r.binding.Context.Done():
+		return false
+	}
+}
+
+// emitErrorLog tells the app why writing to its drain failed, unless it was
+// told recently.
+func (r *RetryWriter) emitErrorLog(err error) {
+	if !r.limiter.allow(r.binding.AppID + "\n" + r.binding.URL.String()) {
+		return
+	}
+
+	r.logClient.EmitLog(
+		fmt.Sprintf(
+			"Failed to write to syslog drain %s: %s",
+			redactDrain(r.binding.URL.String()),
+			failureClass(err),
+		),
+		loggregator.WithAppInfo(r.binding.AppID, "LGR", r.sourceIndex),
+	)
 }
 
 // ExponentialDuration returns a duration that grows exponentially with each
 // attempt. It is maxed out at 15 seconds.
 func ExponentialDuration(attempt int) time.Duration {
-	if attempt == 0 {
-		return time.Millisecond
+	return ExponentialBackoff(time.Millisecond, 15*time.Second)(attempt)
+}
+
+// backoffs maps the names of backoffs to their Backoff.
+var backoffs = map[string]Backoff{
+	"exponential":         ExponentialBackoff,
+	"full-jitter":         FullJitterBackoff,
+	"decorrelated-jitter": DecorrelatedJitterBackoff,
+}
+
+// BackoffByName returns the Backoff called "exponential", "full-jitter" or
+// "decorrelated-jitter".
+func BackoffByName(name string) (Backoff, error) {
+	b, ok := backoffs[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported retry backoff: %s", name)
+	}
+
+	return b, nil
+}
+
+// ExponentialBackoff returns a RetryDuration that waits base before the
+// second attempt and doubles with every attempt after that, up to cap.
+func ExponentialBackoff(base, cap time.Duration) RetryDuration {
+	return func(attempt int) time.Duration {
+		return exponential(base, cap, 2, attempt)
 	}
+}
+
+// FullJitterBackoff returns a RetryDuration that waits a random duration
+// between zero and the duration of ExponentialBackoff. Drains that fail at
+// the same time are therefore not retried in lockstep.
+func FullJitterBackoff(base, cap time.Duration) RetryDuration {
+	return func(attempt int) time.Duration {
+		return randomDuration(0, exponential(base, cap, 2, attempt))
+	}
+}
+
+// DecorrelatedJitterBackoff returns a RetryDuration that waits a random
+// duration between base and three times the previous wait, up to cap. The
+// first wait of every write is at most three times base. Unlike
+// FullJitterBackoff it never retries immediately.
+//
+// The RetryDuration remembers its previous wait, so it must not be shared by
+// writers. RetryPolicyWrapper builds one for each writer.
+func DecorrelatedJitterBackoff(base, cap time.Duration) RetryDuration {
+	prev := base
+
+	return func(attempt int) time.Duration {
+		if attempt <= 0 {
+			prev = base
+		}
+
+		prev = randomDuration(base, 3*prev)
+		if prev > cap {
+			prev = cap
+		}
 
-	tenthDuration := int(math.Pow(2, float64(attempt-1)) * 100)
-	duration := time.Duration(tenthDuration*10) * time.Microsecond
+		return prev
+	}
+}
+
+// exponential returns base multiplied by factor for every attempt after the
+// first, up to cap.
+func exponential(base, cap time.Duration, factor int64, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < cap; i++ {
+		d *= time.Duration(factor)
+	}
+
+	if d > cap {
+		return cap
+	}
+
+	return d
+}
 
-	if duration > 15*time.Second {
-		return 15 * time.Second
+func randomDuration(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
 	}
 
-	return duration
+	return min + time.Duration(rand.Int63n(int64(max-min)+1))
 }
@@ -1,10 +1,14 @@
 package egress_test
 
 import (
+	"crypto/tls"
 	"errors"
+	"net"
 	"net/url"
+	"os"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	loggregator "code.cloudfoundry.org/go-loggregator"
@@ -14,6 +18,7 @@ import (
 	"golang.org/x/net/context"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -70,6 +75,43 @@ var _ = Describe("Retry Writer", func() {
 			Expect(err).To(HaveOccurred())
 		})
 
+		It("stops waiting to retry once the binding is stopped", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			writeCloser := &spyWriteCloser{
+				returnErrCount: 5,
+				writeErr:       errors.New("write error"),
+				binding: &egress.URLBinding{
+					URL:     &url.URL{},
+					Context: ctx,
+				},
+			}
+			r := buildRetryWriter(writeCloser, 5, time.Minute, newSpyLogClient(), "1")
+			time.AfterFunc(100*time.Millisecond, cancel)
+
+			errs := make(chan error, 1)
+			go func() {
+				errs <- r.Write(&v2.Envelope{})
+			}()
+
+			Eventually(errs, time.Second).Should(Receive(HaveOccurred()))
+			Expect(writeCloser.WriteAttempts()).To(Equal(2))
+		})
+
+		It("retries writes that the drain rejected with a 4xx status", func() {
+			writeCloser := &spyWriteCloser{
+				returnErrCount: 3,
+				writeErr:       egress.HTTPStatusError{StatusCode: 400},
+				binding: &egress.URLBinding{
+					URL:     &url.URL{},
+					Context: context.Background(),
+				},
+			}
+			r := buildRetryWriter(writeCloser, 3, 0, newSpyLogClient(), "1")
+
+			Expect(r.Write(&v2.Envelope{})).To(HaveOccurred())
+			Expect(writeCloser.WriteAttempts()).To(Equal(3))
+		})
+
 		It("continues retrying when context is done", func() {
 			ctx, cancel := context.WithCancel(context.Background())
 			writeCloser := &spyWriteCloser{
@@ -91,6 +133,113 @@ var _ = Describe("Retry Writer", func() {
 		})
 	})
 
+	Describe("app error logs", func() {
+		buildFailingWriter := func(err error) *spyWriteCloser {
+			return &spyWriteCloser{
+				returnErrCount: 100,
+				writeErr:       err,
+				binding: &egress.URLBinding{
+					AppID: "app-id",
+					URL: &url.URL{
+						Scheme: "https",
+						User:   url.UserPassword("user", "pass"),
+						Host:   "example.com",
+						Path:   "/drain",
+					},
+					Context: context.Background(),
+				},
+			}
+		}
+
+		It("tells the app once per interval why writes to its drain fail", func() {
+			writeCloser := buildFailingWriter(egress.HTTPStatusError{StatusCode: 503})
+			logClient := newSpyLogClient()
+			r := buildRetryWriter(writeCloser, 3, 0, logClient, "3")
+
+			Expect(r.Write(&v2.Envelope{})).To(HaveOccurred())
+			Expect(r.Write(&v2.Envelope{})).To(HaveOccurred())
+
+			Expect(writeCloser.WriteAttempts()).To(Equal(6))
+			Expect(logClient.message()).To(Equal([]string{
+				"Failed to write to syslog drain https://example.com/drain: responded with HTTP status 503",
+			}))
+			Expect(logClient.appID()).To(ConsistOf("app-id"))
+			Expect(logClient.sourceType()).To(HaveKey("LGR"))
+			Expect(logClient.sourceInstance()).To(HaveKey("3"))
+		})
+
+		DescribeTable("describes the class of failure",
+			func(err error, class string) {
+				logClient := newSpyLogClient()
+				r := buildRetryWriter(buildFailingWriter(err), 1, 0, logClient, "3")
+
+				Expect(r.Write(&v2.Envelope{})).To(HaveOccurred())
+				Expect(logClient.message()).To(ConsistOf(
+					"Failed to write to syslog drain https://example.com/drain: " + class,
+				))
+			},
+			Entry("DNS", &net.OpError{Op: "dial", Err: &net.DNSError{Name: "example.com"}}, "DNS lookup failed"),
+			Entry("connection refused", &net.OpError{
+				Op:  "dial",
+				Err: os.NewSyscallError("connect", syscall.ECONNREFUSED),
+			}, "connection refused"),
+			Entry("TLS handshake", tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, "TLS handshake failed"),
+			Entry("timeout", &net.OpError{Op: "write", Err: timeoutError{}}, "timed out"),
+			Entry("HTTP status", &url.Error{Op: "Post", Err: egress.HTTPStatusError{StatusCode: 404}}, "responded with HTTP status 404"),
+			Entry("other", errors.New("some error"), "write failed"),
+		)
+	})
+
+	Describe("Flush()", func() {
+		It("retries flushes if the syslog writer fails to flush", func() {
+			writeCloser := &spyWriteCloser{
+				returnErrCount: 1,
+				writeErr:       errors.New("flush error"),
+				binding: &egress.URLBinding{
+					URL:     &url.URL{},
+					Context: context.Background(),
+				},
+			}
+			logClient := newSpyLogClient()
+			r := buildRetryWriter(writeCloser, 3, 0, logClient, "1")
+
+			Expect(r.(egress.Flusher).Flush()).To(Succeed())
+			Expect(writeCloser.FlushAttempts()).To(Equal(2))
+		})
+
+		It("does not flush a syslog writer that has nothing to flush", func() {
+			writeCloser := &spyPendingWriteCloser{
+				spyWriteCloser: spyWriteCloser{
+					binding: &egress.URLBinding{
+						URL:     &url.URL{},
+						Context: context.Background(),
+					},
+				},
+			}
+			r := egress.RetryWrapper(
+				func(
+					*egress.URLBinding,
+					egress.NetworkTimeoutConfig,
+					bool,
+					pulseemitter.CounterMetric,
+				) egress.WriteCloser {
+					return writeCloser
+				},
+				egress.RetryDuration(buildDelay(0)),
+				3,
+				newSpyLogClient(),
+				"1",
+			)(writeCloser.binding, egress.NetworkTimeoutConfig{}, false, nil)
+
+			Expect(r.(egress.Flusher).Flush()).To(Succeed())
+			Expect(writeCloser.FlushAttempts()).To(BeZero())
+
+			writeCloser.pending = true
+			Expect(r.(egress.Flusher).Flush()).To(Succeed())
+			Expect(writeCloser.FlushAttempts()).To(Equal(1))
+		})
+	})
+
 	Describe("Close()", func() {
 		It("delegates to the syslog writer", func() {
 			writeCloser := &spyWriteCloser{
@@ -132,13 +281,155 @@ var _ = Describe("Retry Writer", func() {
 			}
 		})
 	})
+
+	Describe("RetryPolicyWrapper", func() {
+		var (
+			backoffBase time.Duration
+			backoffCap  time.Duration
+			policy      egress.RetryPolicy
+		)
+
+		BeforeEach(func() {
+			policy = egress.RetryPolicy{
+				Backoff: func(base, cap time.Duration) egress.RetryDuration {
+					backoffBase, backoffCap = base, cap
+					return buildDelay(0)
+				},
+				Base:       time.Second,
+				Cap:        time.Minute,
+				MaxRetries: 5,
+			}
+		})
+
+		DescribeTable("lets drains lower but not raise the limits",
+			func(query string, base, cap time.Duration, attempts int) {
+				writeCloser := &spyWriteCloser{
+					returnErrCount: 100,
+					writeErr:       errors.New("write error"),
+					binding: &egress.URLBinding{
+						URL:     &url.URL{RawQuery: query},
+						Context: context.Background(),
+					},
+				}
+				constructor := egress.RetryPolicyWrapper(
+					func(
+						binding *egress.URLBinding,
+						netConf egress.NetworkTimeoutConfig,
+						skipCertVerify bool,
+						egressMetric pulseemitter.CounterMetric,
+					) egress.WriteCloser {
+						return writeCloser
+					},
+					policy,
+					newSpyLogClient(),
+					"1",
+				)
+				w := constructor(writeCloser.binding, egress.NetworkTimeoutConfig{}, false, nil)
+
+				Expect(w.Write(&v2.Envelope{})).To(HaveOccurred())
+				Expect(writeCloser.WriteAttempts()).To(Equal(attempts))
+				Expect(backoffBase).To(Equal(base))
+				Expect(backoffCap).To(Equal(cap))
+			},
+			Entry("defaults", "", time.Second, time.Minute, 5),
+			Entry("lower", "retry-base=10ms&retry-cap=1s&retry-max=2", 10*time.Millisecond, time.Second, 2),
+			Entry("higher", "retry-base=2s&retry-cap=1h&retry-max=10", time.Second, time.Minute, 5),
+			Entry("invalid", "retry-base=fast&retry-cap=-1s&retry-max=0", time.Second, time.Minute, 5),
+		)
+	})
+
+	Describe("Backoff", func() {
+		It("backs off exponentially from the base up to the cap", func() {
+			backoff := egress.ExponentialBackoff(10*time.Millisecond, time.Second)
+
+			Expect(backoff(0)).To(Equal(10 * time.Millisecond))
+			Expect(backoff(1)).To(Equal(10 * time.Millisecond))
+			Expect(backoff(2)).To(Equal(20 * time.Millisecond))
+			Expect(backoff(7)).To(Equal(640 * time.Millisecond))
+			Expect(backoff(8)).To(Equal(time.Second))
+			Expect(backoff(1000)).To(Equal(time.Second))
+		})
+
+		It("jitters between zero and the exponential backoff", func() {
+			backoff := egress.FullJitterBackoff(10*time.Millisecond, time.Second)
+
+			seen := make(map[time.Duration]struct{})
+			for i := 0; i < 100; i++ {
+				d := backoff(3)
+				Expect(d).To(BeNumerically(">=", 0))
+				Expect(d).To(BeNumerically("<=", 40*time.Millisecond))
+				seen[d] = struct{}{}
+			}
+			Expect(len(seen)).To(BeNumerically(">", 1))
+
+			Expect(backoff(1000)).To(BeNumerically("<=", time.Second))
+		})
+
+		It("waits between the base and three times the previous wait", func() {
+			backoff := egress.DecorrelatedJitterBackoff(10*time.Millisecond, time.Second)
+
+			for i := 0; i < 100; i++ {
+				prev := backoff(0)
+				Expect(prev).To(BeNumerically(">=", 10*time.Millisecond))
+				Expect(prev).To(BeNumerically("<=", 30*time.Millisecond))
+
+				for attempt := 1; attempt < 10; attempt++ {
+					d := backoff(attempt)
+					Expect(d).To(BeNumerically(">=", 10*time.Millisecond))
+					Expect(d).To(BeNumerically("<=", 3*prev))
+					Expect(d).To(BeNumerically("<=", time.Second))
+					prev = d
+				}
+			}
+		})
+
+		It("grows decorrelated waits up to the cap", func() {
+			backoff := egress.DecorrelatedJitterBackoff(10*time.Millisecond, 50*time.Millisecond)
+
+			seen := make(map[time.Duration]struct{})
+			for attempt := 0; attempt < 1000; attempt++ {
+				seen[backoff(attempt)] = struct{}{}
+			}
+
+			Expect(seen).To(HaveKey(50 * time.Millisecond))
+			Expect(len(seen)).To(BeNumerically(">", 1))
+		})
+
+		It("starts decorrelated waits over with every write", func() {
+			backoff := egress.DecorrelatedJitterBackoff(10*time.Millisecond, time.Hour)
+
+			for attempt := 0; attempt < 50; attempt++ {
+				backoff(attempt)
+			}
+
+			Expect(backoff(0)).To(BeNumerically("<=", 30*time.Millisecond))
+		})
+
+		It("looks up backoffs by name", func() {
+			for _, name := range []string{"exponential", "full-jitter", "decorrelated-jitter"} {
+				b, err := egress.BackoffByName(name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).ToNot(BeNil())
+			}
+
+			_, err := egress.BackoffByName("linear")
+			Expect(err).To(MatchError("unsupported retry backoff: linear"))
+		})
+	})
 })
 
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
 type spyWriteCloser struct {
 	binding       *egress.URLBinding
 	writeCalled   bool
 	writeEnvelope *v2.Envelope
 	writeAttempts int64
+	flushAttempts int64
 
 	returnErrCount int
 	writeErr       error
@@ -158,6 +449,20 @@ func (s *spyWriteCloser) Write(env *v2.Envelope) error {
 	return err
 }
 
+func (s *spyWriteCloser) Flush() error {
+	var err error
+	if s.FlushAttempts() < s.returnErrCount {
+		err = s.writeErr
+	}
+	atomic.AddInt64(&s.flushAttempts, 1)
+
+	return err
+}
+
+func (s *spyWriteCloser) FlushAttempts() int {
+	return int(atomic.LoadInt64(&s.flushAttempts))
+}
+
 func (s *spyWriteCloser) Close() error {
 	s.closeCalled = true
 
@@ -168,6 +473,15 @@ func (s *spyWriteCloser) WriteAttempts() int {
 	return int(atomic.LoadInt64(&s.writeAttempts))
 }
 
+type spyPendingWriteCloser struct {
+	spyWriteCloser
+	pending bool
+}
+
+func (s *spyPendingWriteCloser) FlushPending() bool {
+	return s.pending
+}
+
 type spyLogClient struct {
 	mu       sync.Mutex
 	_message []string
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/context"
@@ -14,6 +17,7 @@ import (
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 )
 
 // Write is the interface for all diode writers.
@@ -27,6 +31,103 @@ type WriteCloser interface {
 	io.Closer
 }
 
+// Flusher is implemented by writers that buffer envelopes before sending
+// them to a drain. Flush is called whenever there are no envelopes waiting to
+// be written.
+type Flusher interface {
+	Flush() error
+}
+
+// PendingFlusher is implemented by Flushers that can tell whether Flush would
+// send anything to the drain.
+type PendingFlusher interface {
+	Flusher
+	FlushPending() bool
+}
+
+// flushPending reports whether flushing w would send buffered envelopes to
+// the drain. Flushers that can not tell are assumed to send something.
+func flushPending(w Writer) bool {
+	if p, ok := w.(PendingFlusher); ok {
+		return p.FlushPending()
+	}
+
+	_, ok := w.(Flusher)
+	return ok
+}
+
+// Discarder is implemented by writers that keep envelopes after a failed
+// write so that retrying the write sends them. Discard drops them once the
+// write is given up on and returns the number of messages dropped.
+type Discarder interface {
+	Discard() int
+}
+
+// discard drops the envelopes w kept after a failed write, if it keeps any.
+func discard(w Writer) int {
+	if d, ok := w.(Discarder); ok {
+		return d.Discard()
+	}
+
+	return 0
+}
+
+// PostNotifier is implemented by writers that send envelopes to the drain
+// some time after they are written to them, such as the https writer when it
+// batches envelopes. NotifyPosted arranges for f to be called with the
+// timestamps of the envelopes of every post that succeeds, and reports
+// whether the writer will do so.
+type PostNotifier interface {
+	NotifyPosted(f func(timestamps []int64)) bool
+}
+
+// notifyPosted arranges for f to be called whenever w posts envelopes. It
+// returns false if w does not report its posts, in which case an envelope is
+// sent once its write succeeds.
+func notifyPosted(w Writer, f func(timestamps []int64)) bool {
+	if n, ok := w.(PostNotifier); ok {
+		return n.NotifyPosted(f)
+	}
+
+	return false
+}
+
+// writerDelegate passes the optional methods of a syslog writer through the
+// writers that wrap it. Wrappers embed it and override what they change.
+type writerDelegate struct {
+	writer WriteCloser
+}
+
+// Flush flushes the syslog writer if it buffers envelopes.
+func (d writerDelegate) Flush() error {
+	if f, ok := d.writer.(Flusher); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
+
+// FlushPending reports whether flushing the syslog writer would send
+// anything.
+func (d writerDelegate) FlushPending() bool {
+	return flushPending(d.writer)
+}
+
+// NotifyPosted reports the posts of the syslog writer to f.
+func (d writerDelegate) NotifyPosted(f func(timestamps []int64)) bool {
+	return notifyPosted(d.writer, f)
+}
+
+// Discard drops the envelopes the syslog writer kept.
+func (d writerDelegate) Discard() int {
+	return discard(d.writer)
+}
+
+// Close closes the syslog writer.
+func (d writerDelegate) Close() error {
+	return d.writer.Close()
+}
+
 // LogClient is used to emit logs.
 type LogClient interface {
 	EmitLog(message string, opts ...loggregator.EmitLogOption)
@@ -39,8 +140,29 @@ type nullLogClient struct{}
 func (nullLogClient) EmitLog(message string, opts ...loggregator.EmitLogOption) {
 }
 
+// nullMetric ensures that metrics are in fact optional.
+type nullMetric struct{}
+
+// Increment does nothing.
+func (nullMetric) Increment(uint64) {}
+
+// Emit does nothing.
+func (nullMetric) Emit(pulseemitter.LogClient) {}
+
+// nullGaugeMetric ensures that gauge metrics are in fact optional.
+type nullGaugeMetric struct{}
+
+// Set does nothing.
+func (nullGaugeMetric) Set(float64) {}
+
+// Emit does nothing.
+func (nullGaugeMetric) Emit(pulseemitter.LogClient) {}
+
 // SyslogConnector creates the various egress syslog writers.
 type SyslogConnector struct {
+	flushed   uint64
+	abandoned uint64
+
 	skipCertVerify bool
 	keepalive      time.Duration
 	ioTimeout      time.Duration
@@ -49,8 +171,32 @@ type SyslogConnector struct {
 	droppedMetrics map[string]pulseemitter.CounterMetric
 	egressMetrics  map[string]pulseemitter.CounterMetric
 	logClient      LogClient
+	logger         *logging.Logger
 	wg             WaitGroup
 	sourceIndex    string
+	diskBuffer     *DiskBuffer
+	bufferSize     int
+	maxBufferSize  int
+	queueAgeMetric HistogramMetric
+
+	drainTimeout    time.Duration
+	flushedMetric   pulseemitter.CounterMetric
+	abandonedMetric pulseemitter.CounterMetric
+
+	mu      sync.Mutex
+	buffers map[*DiodeWriter]*v1.Binding
+	stats   map[v1.Binding]*DrainStats
+}
+
+// BufferStatus reports how many envelopes are waiting to be written to the
+// drain of a binding.
+type BufferStatus struct {
+	AppID    string `json:"app_id"`
+	Hostname string `json:"hostname"`
+	Drain    string `json:"drain"`
+	Len      int    `json:"len"`
+	Cap      int    `json:"cap"`
+	Bytes    int64  `json:"bytes"`
 }
 
 // NewSyslogConnector configures and returns a new SyslogConnector.
@@ -61,15 +207,21 @@ func NewSyslogConnector(
 	opts ...ConnectorOption,
 ) *SyslogConnector {
 	sc := &SyslogConnector{
-		keepalive:      netConf.Keepalive,
-		ioTimeout:      netConf.WriteTimeout,
-		dialTimeout:    netConf.DialTimeout,
-		skipCertVerify: skipCertVerify,
-		wg:             wg,
-		logClient:      nullLogClient{},
-		constructors:   make(map[string]WriterConstructor),
-		droppedMetrics: make(map[string]pulseemitter.CounterMetric),
-		egressMetrics:  make(map[string]pulseemitter.CounterMetric),
+		keepalive:       netConf.Keepalive,
+		ioTimeout:       netConf.WriteTimeout,
+		dialTimeout:     netConf.DialTimeout,
+		skipCertVerify:  skipCertVerify,
+		wg:              wg,
+		logClient:       nullLogClient{},
+		constructors:    make(map[string]WriterConstructor),
+		droppedMetrics:  make(map[string]pulseemitter.CounterMetric),
+		egressMetrics:   make(map[string]pulseemitter.CounterMetric),
+		bufferSize:      DefaultDiodeSize,
+		maxBufferSize:   DefaultDiodeSize,
+		flushedMetric:   nullMetric{},
+		abandonedMetric: nullMetric{},
+		buffers:         make(map[*DiodeWriter]*v1.Binding),
+		stats:           make(map[v1.Binding]*DrainStats),
 	}
 	for _, o := range opts {
 		o(sc)
@@ -123,8 +275,63 @@ func WithLogClient(logClient LogClient, sourceIndex string) ConnectorOption {
 	}
 }
 
+// WithLogger returns a ConnectorOption that writes the logs of the connector
+// and of the writers of every binding to l. Logs are discarded by default.
+func WithLogger(l *logging.Logger) ConnectorOption {
+	return func(sc *SyslogConnector) {
+		sc.logger = l
+	}
+}
+
+// WithDiskBuffer returns a ConnectorOption that queues the envelopes of each
+// binding on disk before they are written to the drain.
+func WithDiskBuffer(b *DiskBuffer) ConnectorOption {
+	return func(sc *SyslogConnector) {
+		sc.diskBuffer = b
+	}
+}
+
+// WithBufferSize returns a ConnectorOption that sets the number of envelopes
+// buffered for each binding. Drains can ask for a different size with the
+// `buffer-size` query parameter, up to maxSize.
+func WithBufferSize(size, maxSize int) ConnectorOption {
+	return func(sc *SyslogConnector) {
+		sc.bufferSize = size
+		sc.maxBufferSize = maxSize
+	}
+}
+
+// WithQueueAgeMetric returns a ConnectorOption that observes, in seconds, how
+// long each envelope waited in the buffer of its binding.
+func WithQueueAgeMetric(h HistogramMetric) ConnectorOption {
+	return func(sc *SyslogConnector) {
+		sc.queueAgeMetric = h
+	}
+}
+
+// WithDrainTimeout returns a ConnectorOption that keeps writing the
+// envelopes buffered for a binding for up to d after the context given to
+// Connect is done. Envelopes still buffered after d are abandoned. By
+// default the envelopes buffered when the context is done are abandoned.
+func WithDrainTimeout(d time.Duration) ConnectorOption {
+	return func(sc *SyslogConnector) {
+		sc.drainTimeout = d
+	}
+}
+
+// WithDrainMetrics returns a ConnectorOption that counts the envelopes that
+// were written, and the ones that were abandoned, after the context given to
+// Connect was done.
+func WithDrainMetrics(flushed, abandoned pulseemitter.CounterMetric) ConnectorOption {
+	return func(sc *SyslogConnector) {
+		sc.flushedMetric = flushed
+		sc.abandonedMetric = abandoned
+	}
+}
+
 // Connect returns an egress writer based on the scheme of the binding drain
-// URL.
+// URL. The writer stops buffering envelopes once ctx is done, but keeps
+// writing the envelopes it buffered for the drain timeout.
 func (w *SyslogConnector) Connect(ctx context.Context, b *v1.Binding) (Writer, error) {
 	urlBinding, err := buildBinding(ctx, b)
 	if err != nil {
@@ -132,15 +339,40 @@ func (w *SyslogConnector) Connect(ctx context.Context, b *v1.Binding) (Writer, e
 		// a binding with an invalid URL would make it this far. Nonetheless,
 		// we handle the error case all the same.
 		w.emitErrorLog(b.AppId, "Invalid syslog drain URL: parse failure")
+		w.logger.With(logging.BindingFields(b.AppId, b.Drain)).Warnf("invalid syslog drain URL")
+		return nil, err
+	}
+	urlBinding.Logger = w.logger.With(logging.BindingFields(b.AppId, b.Drain))
+
+	urlBinding.Certificates, err = clientCertificates(b)
+	if err != nil {
+		w.emitErrorLog(b.AppId, "Invalid syslog drain client certificate")
+		urlBinding.Logger.Warnf("invalid syslog drain client certificate: %s", err)
+		return nil, err
+	}
+
+	urlBinding.RootCAs, err = rootCAs(b)
+	if err != nil {
+		w.emitErrorLog(b.AppId, "Invalid syslog drain CA certificate")
+		urlBinding.Logger.Warnf("invalid syslog drain CA certificate: %s", err)
 		return nil, err
 	}
 
 	droppedMetric := w.droppedMetrics[urlBinding.Scheme()]
-	egressMetric := w.egressMetrics[urlBinding.Scheme()]
 	constructor, ok := w.constructors[urlBinding.Scheme()]
 	if !ok {
 		return nil, errors.New("unsupported protocol")
 	}
+
+	writerCtx, stopWriter := w.drainContext(ctx)
+	urlBinding.Context = writerCtx
+
+	stats := w.trackStats(writerCtx, b)
+	urlBinding.Stats = stats
+	egressMetric := egressStatsMetric{
+		CounterMetric: w.egressMetrics[urlBinding.Scheme()],
+		stats:         stats,
+	}
 	netConf := NetworkTimeoutConfig{
 		Keepalive:    w.keepalive,
 		DialTimeout:  w.dialTimeout,
@@ -153,19 +385,242 @@ func (w *SyslogConnector) Connect(ctx context.Context, b *v1.Binding) (Writer, e
 		egressMetric,
 	)
 
-	dw := NewDiodeWriter(ctx, writer, diodes.AlertFunc(func(missed int) {
+	alerter := diodes.AlertFunc(func(missed int) {
 		if droppedMetric != nil {
 			droppedMetric.Increment(uint64(missed))
 		}
+		stats.addDropped(uint64(missed))
 
 		w.emitErrorLog(b.AppId, fmt.Sprintf("%d messages lost in user provided syslog drain", missed))
 
-		log.Printf("Dropped %d %s logs", missed, urlBinding.Scheme())
-	}), w.wg)
+		urlBinding.Logger.Warnf("dropped %d logs", missed)
+	})
+
+	if w.diskBuffer != nil {
+		writer = w.diskBuffer.Writer(
+			writerCtx,
+			b,
+			writer,
+			alerter,
+			WithDiskBufferLogger(urlBinding.Logger),
+			WithDiskBufferQueueAge(w.queueAgeMetric),
+		)
+	}
+
+	dw := NewDiodeWriter(
+		ctx,
+		writer,
+		alerter,
+		w.wg,
+		WithDiodeSize(w.diodeSize(urlBinding.URL)),
+		withDiodeStats(stats),
+		WithQueueAgeHistogram(w.queueAgeMetric),
+		WithDrain(writerCtx, func(flushed, abandoned int) {
+			stopWriter()
+			w.reportDrain(urlBinding.Logger, flushed, abandoned)
+		}),
+	)
+	w.trackBuffer(writerCtx, dw, b)
 
 	return dw, nil
 }
 
+// drainContext returns the context of the writers of a binding. It is done
+// drainTimeout after ctx is done, or once the returned function is called.
+func (w *SyslogConnector) drainContext(ctx context.Context) (context.Context, func()) {
+	writerCtx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-writerCtx.Done():
+			return
+		}
+
+		t := time.NewTimer(w.drainTimeout)
+		defer t.Stop()
+
+		select {
+		case <-t.C:
+			cancel()
+		case <-writerCtx.Done():
+		}
+	}()
+
+	return writerCtx, cancel
+}
+
+// reportDrain records how many of the envelopes buffered for a binding were
+// written after the binding stopped, and how many were abandoned.
+func (w *SyslogConnector) reportDrain(logger *logging.Logger, flushed, abandoned int) {
+	atomic.AddUint64(&w.flushed, uint64(flushed))
+	atomic.AddUint64(&w.abandoned, uint64(abandoned))
+	w.flushedMetric.Increment(uint64(flushed))
+	w.abandonedMetric.Increment(uint64(abandoned))
+
+	if abandoned > 0 {
+		logger.Warnf("abandoned %d buffered logs, flushed %d", abandoned, flushed)
+		return
+	}
+	logger.Debugf("flushed %d buffered logs", flushed)
+}
+
+// Drained returns the total number of envelopes that were written after
+// their binding stopped, and the number that were abandoned.
+func (w *SyslogConnector) Drained() (flushed, abandoned uint64) {
+	return atomic.LoadUint64(&w.flushed), atomic.LoadUint64(&w.abandoned)
+}
+
+// Buffers returns the status of the buffer of every connected binding.
+func (w *SyslogConnector) Buffers() []BufferStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	statuses := make([]BufferStatus, 0, len(w.buffers))
+	for dw, b := range w.buffers {
+		statuses = append(statuses, BufferStatus{
+			AppID:    b.AppId,
+			Hostname: b.Hostname,
+			Drain:    redactDrain(b.Drain),
+			Len:      dw.Len(),
+			Cap:      dw.Cap(),
+			Bytes:    dw.Bytes(),
+		})
+	}
+
+	return statuses
+}
+
+// DiodeWriters returns the in-memory buffers of all connected bindings.
+func (w *SyslogConnector) DiodeWriters() []*DiodeWriter {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	writers := make([]*DiodeWriter, 0, len(w.buffers))
+	for dw := range w.buffers {
+		writers = append(writers, dw)
+	}
+
+	return writers
+}
+
+func (w *SyslogConnector) trackBuffer(ctx context.Context, dw *DiodeWriter, b *v1.Binding) {
+	w.mu.Lock()
+	w.buffers[dw] = b
+	w.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		w.mu.Lock()
+		delete(w.buffers, dw)
+		w.mu.Unlock()
+	}()
+}
+
+// Stats returns the statistics of every binding that is connected or was
+// connected within the last minute.
+func (w *SyslogConnector) Stats() []DrainStatsSnapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	snapshots := make([]DrainStatsSnapshot, 0, len(w.stats))
+	for b, s := range w.stats {
+		snapshots = append(snapshots, bindingSnapshot(&b, s))
+	}
+
+	return snapshots
+}
+
+// BindingStats returns the statistics of the binding. It returns false if
+// the binding has not been connected within the last minute.
+func (w *SyslogConnector) BindingStats(b *v1.Binding) (DrainStatsSnapshot, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s, ok := w.stats[*b]
+	if !ok {
+		return DrainStatsSnapshot{}, false
+	}
+
+	return bindingSnapshot(b, s), true
+}
+
+func bindingSnapshot(b *v1.Binding, s *DrainStats) DrainStatsSnapshot {
+	snapshot := s.snapshot()
+	snapshot.AppID = b.AppId
+	snapshot.Hostname = b.Hostname
+	snapshot.Drain = redactDrain(b.Drain)
+
+	return snapshot
+}
+
+// trackStats returns the statistics of the binding, which are shared by all
+// of its writers. They are forgotten once the binding has had no writer for
+// statsRetention.
+func (w *SyslogConnector) trackStats(ctx context.Context, b *v1.Binding) *DrainStats {
+	key := *b
+
+	w.mu.Lock()
+	s, ok := w.stats[key]
+	if !ok {
+		s = &DrainStats{}
+		w.stats[key] = s
+	}
+	s.mu.Lock()
+	s.connections++
+	s.mu.Unlock()
+	w.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.mu.Lock()
+		s.connections--
+		s.mu.Unlock()
+
+		time.AfterFunc(statsRetention, func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			if s.connections == 0 && w.stats[key] == s {
+				delete(w.stats, key)
+			}
+		})
+	}()
+
+	return s
+}
+
+// diodeSize returns the buffer size asked for by the drain URL, if any,
+// limited to the maximum buffer size.
+func (w *SyslogConnector) diodeSize(u *url.URL) int {
+	size, err := strconv.Atoi(u.Query().Get("buffer-size"))
+	if err != nil || size < 1 {
+		return w.bufferSize
+	}
+
+	if size > w.maxBufferSize {
+		return w.maxBufferSize
+	}
+
+	return size
+}
+
+// redactDrain removes credentials from a drain URL.
+func redactDrain(drain string) string {
+	u, err := url.Parse(drain)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+
+	return u.String()
+}
+
 func (w *SyslogConnector) emitErrorLog(appID, message string) {
 	option := loggregator.WithAppInfo(
 		appID,
@@ -3,7 +3,6 @@ package egress
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"net"
 	"net/url"
 	"strconv"
@@ -13,6 +12,7 @@ import (
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
 	"code.cloudfoundry.org/rfc5424"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 )
 
 // gaugeStructuredDataID contains the registered enterprise ID for the Cloud
@@ -36,7 +36,10 @@ type TCPWriter struct {
 	dialFunc     DialFunc
 	writeTimeout time.Duration
 	scheme       string
+	format       messageFormatter
+	frame        messageFramer
 	conn         net.Conn
+	logger       *logging.Logger
 
 	egressMetric pulseemitter.CounterMetric
 }
@@ -63,6 +66,9 @@ func NewTCPWriter(
 		writeTimeout: netConf.WriteTimeout,
 		dialFunc:     df,
 		scheme:       "syslog",
+		format:       formatterFor(binding.URL),
+		frame:        framerFor(binding.URL),
+		logger:       binding.Logger,
 		egressMetric: egressMetric,
 	}
 
@@ -83,11 +89,18 @@ func (w *TCPWriter) connect() (net.Conn, error) {
 	}
 	w.conn = conn
 
-	log.Printf("created conn to syslog drain: %s", w.url.Host)
+	w.logger.Infof("created conn to syslog drain")
 
 	return conn, nil
 }
 
+// Probe connects to the drain if there is no connection yet. The connection
+// is kept for the next write.
+func (w *TCPWriter) Probe() error {
+	_, err := w.connection()
+	return err
+}
+
 // Close tears down any active connections to the drain and prevents reconnect.
 func (w *TCPWriter) Close() error {
 	if w.conn != nil {
@@ -191,7 +204,11 @@ func generateRFC5424Messages(
 
 // Write writes an envelope to the syslog drain connection.
 func (w *TCPWriter) Write(env *loggregator_v2.Envelope) error {
-	msgs := generateRFC5424Messages(env, w.hostname, w.appID)
+	msgs, err := w.format(env, w.hostname, w.appID)
+	if err != nil {
+		return err
+	}
+
 	conn, err := w.connection()
 	if err != nil {
 		return err
@@ -199,7 +216,7 @@ func (w *TCPWriter) Write(env *loggregator_v2.Envelope) error {
 
 	for _, msg := range msgs {
 		conn.SetWriteDeadline(time.Now().Add(w.writeTimeout))
-		_, err = msg.WriteTo(conn)
+		_, err = conn.Write(w.frame(msg))
 		if err != nil {
 			_ = w.Close()
 
@@ -178,6 +178,108 @@ var _ = Describe("TCPWriter", func() {
 			Expect(egressCounter.Delta()).To(Equal(uint64(1)))
 		})
 
+		It("writes RFC 3164 messages when the drain asks for them", func() {
+			binding.URL.RawQuery = "format=rfc3164"
+			writer = egress.NewTCPWriter(
+				binding,
+				netConf,
+				false,
+				egressCounter,
+			)
+
+			env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_ERR)
+			Expect(writer.Write(env)).To(Succeed())
+
+			conn, err := listener.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			buf := bufio.NewReader(conn)
+
+			actual, err := buf.ReadString('\n')
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(actual).To(Equal(
+				"66 <11>Jan  1 00:00:00 test-hostname test-app-id[APP/2]: just a test\n",
+			))
+		})
+
+		It("writes RFC 3164 counter metrics with their structured data in the message", func() {
+			binding.URL.RawQuery = "format=rfc3164"
+			writer = egress.NewTCPWriter(
+				binding,
+				netConf,
+				false,
+				egressCounter,
+			)
+
+			env := buildCounterEnvelope("1")
+			Expect(writer.Write(env)).To(Succeed())
+
+			conn, err := listener.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			buf := bufio.NewReader(conn)
+
+			actual, err := buf.ReadString('\n')
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(actual).To(Equal(
+				"108 <14>Jan  1 00:00:00 test-hostname test-app-id[1]: [counter@47450 name=\"some-counter\" total=\"99\" delta=\"1\"] \n",
+			))
+		})
+
+		It("terminates messages with a line feed and escapes embedded newlines with lf framing", func() {
+			binding.URL.RawQuery = "framing=lf"
+			writer = egress.NewTCPWriter(
+				binding,
+				netConf,
+				false,
+				egressCounter,
+			)
+
+			env := buildLogEnvelope("APP", "2", "line one\nline two", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+			env = buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+
+			conn, err := listener.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			buf := bufio.NewReader(conn)
+
+			actual, err := buf.ReadString('\n')
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal(
+				"<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/2] - - line one\\nline two\n",
+			))
+
+			actual, err = buf.ReadString('\n')
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal(
+				"<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/2] - - just a test\n",
+			))
+		})
+
+		It("terminates messages with a NUL character with nul framing", func() {
+			binding.URL.RawQuery = "framing=nul"
+			writer = egress.NewTCPWriter(
+				binding,
+				netConf,
+				false,
+				egressCounter,
+			)
+
+			env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+
+			conn, err := listener.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			buf := bufio.NewReader(conn)
+
+			actual, err := buf.ReadString(0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal(
+				"<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/2] - - just a test\n\x00",
+			))
+		})
+
 		It("replaces spaces with dashes in the process ID", func() {
 			env := buildLogEnvelope("MY TASK", "2", "just a test", loggregator_v2.Log_OUT)
 			Expect(writer.Write(env)).To(Succeed())
@@ -6,9 +6,10 @@ import (
 	"time"
 
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	"code.cloudfoundry.org/scalable-syslog/internal/api"
 )
 
-// TLSWriter represents a syslog writer that connects over unencrypted TCP.
+// TLSWriter represents a syslog writer that connects over TCP with TLS.
 type TLSWriter struct {
 	TCPWriter
 }
@@ -20,21 +21,57 @@ type NetworkTimeoutConfig struct {
 	WriteTimeout time.Duration
 }
 
+// NewTLSWriter creates a new TLS syslog writer that uses the default TLS
+// policy of api.NewTLSConfig.
 func NewTLSWriter(
 	binding *URLBinding,
 	netConf NetworkTimeoutConfig,
 	skipCertVerify bool,
 	egressMetric pulseemitter.CounterMetric,
 ) WriteCloser {
+	return newTLSWriter(
+		binding,
+		netConf,
+		skipCertVerify,
+		egressMetric,
+		api.NewTLSConfig(),
+	)
+}
+
+// TLSWriterConstructor returns a WriterConstructor for TLS writers that
+// connect with the given TLS policy. The policy is shared by all drains and
+// is not modified.
+func TLSWriterConstructor(policy *tls.Config) WriterConstructor {
+	return func(
+		binding *URLBinding,
+		netConf NetworkTimeoutConfig,
+		skipCertVerify bool,
+		egressMetric pulseemitter.CounterMetric,
+	) WriteCloser {
+		return newTLSWriter(
+			binding,
+			netConf,
+			skipCertVerify,
+			egressMetric,
+			policy,
+		)
+	}
+}
 
+func newTLSWriter(
+	binding *URLBinding,
+	netConf NetworkTimeoutConfig,
+	skipCertVerify bool,
+	egressMetric pulseemitter.CounterMetric,
+	policy *tls.Config,
+) WriteCloser {
 	dialer := &net.Dialer{
 		Timeout:   netConf.DialTimeout,
 		KeepAlive: netConf.Keepalive,
 	}
+	tlsConfig := drainTLSConfig(policy, binding, skipCertVerify)
 	df := func(addr string) (net.Conn, error) {
-		return tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
-			InsecureSkipVerify: skipCertVerify,
-		})
+		return tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
 	}
 
 	w := &TLSWriter{
@@ -45,9 +82,25 @@ func NewTLSWriter(
 			writeTimeout: netConf.WriteTimeout,
 			dialFunc:     df,
 			scheme:       "syslog-tls",
+			format:       formatterFor(binding.URL),
+			frame:        framerFor(binding.URL),
+			logger:       binding.Logger,
 			egressMetric: egressMetric,
 		},
 	}
 
 	return w
 }
+
+// drainTLSConfig returns a copy of the TLS policy for connecting to the drain
+// of the binding. The server name is taken from the drain host, and the
+// client certificate and CAs from the binding.
+func drainTLSConfig(policy *tls.Config, binding *URLBinding, skipCertVerify bool) *tls.Config {
+	tlsConfig := policy.Clone()
+	tlsConfig.InsecureSkipVerify = skipCertVerify
+	tlsConfig.ServerName = binding.URL.Hostname()
+	tlsConfig.Certificates = binding.Certificates
+	tlsConfig.RootCAs = binding.RootCAs
+
+	return tlsConfig
+}
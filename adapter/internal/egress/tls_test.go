@@ -3,13 +3,16 @@ package egress_test
 import (
 	"bufio"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
+	"net/http/httptest"
 	"net/url"
 	"time"
 
 	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
 	"code.cloudfoundry.org/scalable-syslog/adapter/internal/test_util"
+	"code.cloudfoundry.org/scalable-syslog/internal/api"
 	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
 
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
@@ -85,4 +88,159 @@ var _ = Describe("TLSWriter", func() {
 		By("emit an egress metric for each message")
 		Expect(egressCounter.Delta()).To(Equal(uint64(1)))
 	})
+
+	It("presents the client certificate of the binding", func() {
+		tlsConfig.ClientAuth = tls.RequireAnyClientCert
+		listener, err := tls.Listen("tcp", ":0", tlsConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer listener.Close()
+
+		clientCert, err := tls.LoadX509KeyPair(
+			test_util.Cert("adapter.crt"),
+			test_util.Cert("adapter.key"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		url, _ := url.Parse(fmt.Sprintf("syslog-tls://%s", listener.Addr()))
+		binding := &egress.URLBinding{
+			AppID:        "test-app-id",
+			Hostname:     "test-hostname",
+			URL:          url,
+			Certificates: []tls.Certificate{clientCert},
+		}
+		writer := egress.NewTLSWriter(
+			binding,
+			netConf,
+			true,
+			&testhelper.SpyMetric{},
+		)
+		defer writer.Close()
+
+		peerCerts := make(chan int, 1)
+		go func() {
+			defer GinkgoRecover()
+
+			conn, err := listener.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+
+			tlsConn := conn.(*tls.Conn)
+			Expect(tlsConn.Handshake()).To(Succeed())
+			peerCerts <- len(tlsConn.ConnectionState().PeerCertificates)
+
+			bufio.NewReader(conn).ReadString('\n')
+		}()
+
+		Expect(writer.Write(env)).To(Succeed())
+		Eventually(peerCerts).Should(Receive(Equal(1)))
+	})
+
+	It("trusts the CA certificates of the binding", func() {
+		server := httptest.NewTLSServer(nil)
+		defer server.Close()
+
+		listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+			Certificates: server.TLS.Certificates,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer listener.Close()
+
+		pool := x509.NewCertPool()
+		pool.AddCert(server.Certificate())
+
+		url, _ := url.Parse(fmt.Sprintf("syslog-tls://%s", listener.Addr()))
+		binding := &egress.URLBinding{
+			AppID:    "test-app-id",
+			Hostname: "test-hostname",
+			URL:      url,
+			RootCAs:  pool,
+		}
+		writer := egress.NewTLSWriter(
+			binding,
+			netConf,
+			false,
+			&testhelper.SpyMetric{},
+		)
+		defer writer.Close()
+
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			bufio.NewReader(conn).ReadString('\n')
+		}()
+
+		Expect(writer.Write(env)).To(Succeed())
+	})
+
+	It("sends the drain host as the server name", func() {
+		serverNames := make(chan string, 1)
+		tlsConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverNames <- hello.ServerName
+			return nil, nil
+		}
+		listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer listener.Close()
+
+		_, port, err := net.SplitHostPort(listener.Addr().String())
+		Expect(err).ToNot(HaveOccurred())
+
+		url, _ := url.Parse(fmt.Sprintf("syslog-tls://localhost:%s", port))
+		binding := &egress.URLBinding{
+			AppID:    "test-app-id",
+			Hostname: "test-hostname",
+			URL:      url,
+		}
+		writer := egress.NewTLSWriter(binding, netConf, true, &testhelper.SpyMetric{})
+		defer writer.Close()
+
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			bufio.NewReader(conn).ReadString('\n')
+		}()
+
+		Expect(writer.Write(env)).To(Succeed())
+		Eventually(serverNames).Should(Receive(Equal("localhost")))
+	})
+
+	It("connects with the TLS policy of the constructor", func() {
+		tlsConfig.MaxVersion = tls.VersionTLS12
+		listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer listener.Close()
+
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			conn.(*tls.Conn).Handshake()
+		}()
+
+		policy := api.NewTLSConfig()
+		policy.MinVersion = tls.VersionTLS13
+
+		url, _ := url.Parse(fmt.Sprintf("syslog-tls://%s", listener.Addr()))
+		binding := &egress.URLBinding{
+			AppID:    "test-app-id",
+			Hostname: "test-hostname",
+			URL:      url,
+		}
+		constructor := egress.TLSWriterConstructor(policy)
+		writer := constructor(binding, netConf, true, &testhelper.SpyMetric{})
+		defer writer.Close()
+
+		Expect(writer.Write(env)).To(HaveOccurred())
+	})
 })
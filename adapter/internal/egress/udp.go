@@ -0,0 +1,146 @@
+package egress
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
+)
+
+const (
+	// DefaultMaxDatagramSize is the largest message RFC 5426 recommends that
+	// syslog receivers be able to handle.
+	DefaultMaxDatagramSize = 2048
+
+	// minDatagramSize is the size RFC 5426 requires every IPv4 receiver to
+	// accept.
+	minDatagramSize = 480
+
+	// maxDatagramSize is the largest payload that fits in a single UDP
+	// datagram over IPv4.
+	maxDatagramSize = 65507
+)
+
+// UDPWriter represents a syslog writer that sends each message as a single
+// UDP datagram as described in RFC 5426. Messages larger than the maximum
+// datagram size are truncated. This writer is not meant to be used from
+// multiple goroutines. The same goroutine that calls `.Write()` should be the
+// one that calls `.Close()`.
+type UDPWriter struct {
+	url             *url.URL
+	appID           string
+	hostname        string
+	dialFunc        DialFunc
+	writeTimeout    time.Duration
+	maxDatagramSize int
+	format          messageFormatter
+	conn            net.Conn
+	logger          *logging.Logger
+
+	egressMetric pulseemitter.CounterMetric
+}
+
+// NewUDPWriter creates a new UDP syslog writer. The maximum datagram size
+// can be set with the `max-datagram-size` query parameter on the drain URL.
+func NewUDPWriter(
+	binding *URLBinding,
+	netConf NetworkTimeoutConfig,
+	skipCertVerify bool,
+	egressMetric pulseemitter.CounterMetric,
+) WriteCloser {
+	dialer := &net.Dialer{
+		Timeout: netConf.DialTimeout,
+	}
+	df := func(addr string) (net.Conn, error) {
+		return dialer.Dial("udp", addr)
+	}
+
+	return &UDPWriter{
+		url:             binding.URL,
+		appID:           binding.AppID,
+		hostname:        binding.Hostname,
+		writeTimeout:    netConf.WriteTimeout,
+		dialFunc:        df,
+		maxDatagramSize: datagramSize(binding.URL),
+		format:          formatterFor(binding.URL),
+		logger:          binding.Logger,
+		egressMetric:    egressMetric,
+	}
+}
+
+// Write writes an envelope to the syslog drain, one datagram per message.
+func (w *UDPWriter) Write(env *loggregator_v2.Envelope) error {
+	msgs, err := w.format(env, w.hostname, w.appID)
+	if err != nil {
+		return err
+	}
+
+	conn, err := w.connection()
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range msgs {
+		if len(msg) > w.maxDatagramSize {
+			msg = msg[:w.maxDatagramSize]
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(w.writeTimeout))
+		_, err = conn.Write(msg)
+		if err != nil {
+			_ = w.Close()
+
+			return err
+		}
+
+		w.egressMetric.Increment(1)
+	}
+
+	return nil
+}
+
+// Close tears down the UDP socket.
+func (w *UDPWriter) Close() error {
+	if w.conn != nil {
+		err := w.conn.Close()
+		w.conn = nil
+
+		return err
+	}
+
+	return nil
+}
+
+func (w *UDPWriter) connection() (net.Conn, error) {
+	if w.conn == nil {
+		conn, err := w.dialFunc(w.url.Host)
+		if err != nil {
+			return nil, err
+		}
+		w.conn = conn
+
+		w.logger.Infof("created conn to syslog drain")
+	}
+
+	return w.conn, nil
+}
+
+func datagramSize(u *url.URL) int {
+	size, err := strconv.Atoi(u.Query().Get("max-datagram-size"))
+	if err != nil {
+		return DefaultMaxDatagramSize
+	}
+
+	switch {
+	case size < minDatagramSize:
+		return minDatagramSize
+	case size > maxDatagramSize:
+		return maxDatagramSize
+	default:
+		return size
+	}
+}
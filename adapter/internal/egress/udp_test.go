@@ -0,0 +1,122 @@
+package egress_test
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UDPWriter", func() {
+	var (
+		listener net.PacketConn
+		binding  *egress.URLBinding
+		netConf  = egress.NetworkTimeoutConfig{
+			WriteTimeout: time.Second,
+			DialTimeout:  100 * time.Millisecond,
+		}
+	)
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+
+		binding = &egress.URLBinding{
+			AppID:    "test-app-id",
+			Hostname: "test-hostname",
+		}
+		binding.URL, _ = url.Parse(fmt.Sprintf("syslog-udp://%s", listener.LocalAddr()))
+	})
+
+	AfterEach(func() {
+		listener.Close()
+	})
+
+	readDatagram := func() string {
+		b := make([]byte, 65535)
+		listener.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := listener.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+
+		return string(b[:n])
+	}
+
+	It("writes each message as a datagram without octet counting", func() {
+		egressCounter := &testhelper.SpyMetric{}
+		writer := egress.NewUDPWriter(binding, netConf, false, egressCounter)
+		defer writer.Close()
+
+		env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(readDatagram()).To(Equal(
+			"<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/2] - - just a test\n",
+		))
+		Expect(egressCounter.Delta()).To(Equal(uint64(1)))
+	})
+
+	It("writes a datagram for every gauge metric", func() {
+		writer := egress.NewUDPWriter(binding, netConf, false, &testhelper.SpyMetric{})
+		defer writer.Close()
+
+		Expect(writer.Write(buildGaugeEnvelope("1"))).To(Succeed())
+
+		var msgs []string
+		for i := 0; i < 5; i++ {
+			msgs = append(msgs, readDatagram())
+		}
+		Expect(msgs).To(ContainElement(
+			"<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [1] - [gauge@47450 name=\"cpu\" value=\"0.23\" unit=\"percentage\"] \n",
+		))
+	})
+
+	It("truncates messages to the default maximum datagram size", func() {
+		writer := egress.NewUDPWriter(binding, netConf, false, &testhelper.SpyMetric{})
+		defer writer.Close()
+
+		env := buildLogEnvelope("APP", "2", strings.Repeat("a", 4096), loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(readDatagram()).To(HaveLen(egress.DefaultMaxDatagramSize))
+	})
+
+	It("truncates messages to the maximum datagram size of the drain", func() {
+		binding.URL.RawQuery = "max-datagram-size=600"
+		writer := egress.NewUDPWriter(binding, netConf, false, &testhelper.SpyMetric{})
+		defer writer.Close()
+
+		env := buildLogEnvelope("APP", "2", strings.Repeat("a", 4096), loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(readDatagram()).To(HaveLen(600))
+	})
+
+	It("does not allow a maximum datagram size below the RFC minimum", func() {
+		binding.URL.RawQuery = "max-datagram-size=10"
+		writer := egress.NewUDPWriter(binding, netConf, false, &testhelper.SpyMetric{})
+		defer writer.Close()
+
+		env := buildLogEnvelope("APP", "2", strings.Repeat("a", 4096), loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(readDatagram()).To(HaveLen(480))
+	})
+
+	It("errors on an invalid syslog message", func() {
+		binding.AppID = "test-app-id-012345678901234567890012345678901234567890"
+		writer := egress.NewUDPWriter(binding, netConf, false, &testhelper.SpyMetric{})
+		defer writer.Close()
+
+		env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(HaveOccurred())
+	})
+})
@@ -1,20 +1,32 @@
 package egress
 
-// URLBinding associates a particular application with a syslog URL. The
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"net/url"
 
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 )
 
+// URLBinding associates a particular application with a syslog URL. The
 // application is identified by AppID and Hostname. The syslog URL is
-// identified by URL.
+// identified by URL. Certificates holds the client certificate presented to
+// drains that ask for one, if the binding has one. RootCAs holds the CA
+// certificates trusted when verifying the drain, if the binding has its own.
+// Stats records what happened to the envelopes of the binding, if it is set.
+// Logger writes logs about the binding, if it is set.
 type URLBinding struct {
-	Context  context.Context
-	AppID    string
-	Hostname string
-	URL      *url.URL
+	Context      context.Context
+	AppID        string
+	Hostname     string
+	URL          *url.URL
+	Certificates []tls.Certificate
+	RootCAs      *x509.CertPool
+	Stats        *DrainStats
+	Logger       *logging.Logger
 }
 
 // Scheme is a convenience wrapper around the *url.URL Scheme field
@@ -37,3 +49,38 @@ func buildBinding(c context.Context, b *v1.Binding) (*URLBinding, error) {
 
 	return u, nil
 }
+
+// clientCertificates parses the PEM encoded client certificate and key of
+// the binding. It returns no certificates if the binding does not have them.
+func clientCertificates(b *v1.Binding) ([]tls.Certificate, error) {
+	if b.Cert == "" && b.Key == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.X509KeyPair([]byte(b.Cert), []byte(b.Key))
+	if err != nil {
+		return nil, err
+	}
+
+	return []tls.Certificate{cert}, nil
+}
+
+// rootCAs returns the system CA certificates together with the PEM encoded CA
+// certificates of the binding. It returns nil if the binding does not have
+// any so that the system pool is used.
+func rootCAs(b *v1.Binding) (*x509.CertPool, error) {
+	if b.Ca == "" {
+		return nil, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM([]byte(b.Ca)) {
+		return nil, errors.New("unable to load drain CA certificate")
+	}
+
+	return pool, nil
+}
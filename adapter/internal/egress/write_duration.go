@@ -0,0 +1,84 @@
+package egress
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+)
+
+// HistogramMetric records the distribution of observed values.
+type HistogramMetric interface {
+	Observe(v float64)
+}
+
+// nullHistogramMetric ensures that histogram metrics are in fact optional.
+type nullHistogramMetric struct{}
+
+// Observe does nothing.
+func (nullHistogramMetric) Observe(float64) {}
+
+// WriteDurationWrapper wraps a WriterConstructor so that the duration of
+// every attempt to write or flush to the drain is observed, in seconds, in the
+// histogram. It should be wrapped by RetryWrapper so that every retry is
+// observed separately. Writes rejected by an open circuit breaker are not
+// observed.
+func WriteDurationWrapper(
+	wc WriterConstructor,
+	histogram HistogramMetric,
+) WriterConstructor {
+	return WriterConstructor(func(
+		binding *URLBinding,
+		netConf NetworkTimeoutConfig,
+		skipCertVerify bool,
+		egressMetric pulseemitter.CounterMetric,
+	) WriteCloser {
+		writer := wc(
+			binding,
+			netConf,
+			skipCertVerify,
+			egressMetric,
+		)
+
+		return &WriteDurationWriter{
+			writerDelegate: writerDelegate{writer},
+			histogram:      histogram,
+		}
+	})
+}
+
+// WriteDurationWriter measures how long writes to a syslog writer take.
+type WriteDurationWriter struct {
+	writerDelegate
+	histogram HistogramMetric
+}
+
+// Write writes the envelope to the syslog writer and observes how long it
+// took.
+func (w *WriteDurationWriter) Write(e *loggregator_v2.Envelope) error {
+	return w.observe(func() error {
+		return w.writer.Write(e)
+	})
+}
+
+// Flush flushes the syslog writer if it buffers envelopes and observes how
+// long it took. Flushes that would not send anything are skipped so that
+// they do not show up as instant writes.
+func (w *WriteDurationWriter) Flush() error {
+	f, ok := w.writer.(Flusher)
+	if !ok || !flushPending(w.writer) {
+		return nil
+	}
+
+	return w.observe(f.Flush)
+}
+
+func (w *WriteDurationWriter) observe(write func() error) error {
+	start := time.Now()
+	err := write()
+	if err != ErrCircuitOpen {
+		w.histogram.Observe(time.Since(start).Seconds())
+	}
+
+	return err
+}
@@ -0,0 +1,109 @@
+package egress_test
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	v2 "code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteDurationWriter", func() {
+	var (
+		spy       *spyWriteCloser
+		histogram *spyHistogram
+		writer    egress.WriteCloser
+	)
+
+	BeforeEach(func() {
+		histogram = &spyHistogram{}
+	})
+
+	JustBeforeEach(func() {
+		constructor := egress.WriteDurationWrapper(
+			func(*egress.URLBinding, egress.NetworkTimeoutConfig, bool, pulseemitter.CounterMetric) egress.WriteCloser {
+				return spy
+			},
+			histogram,
+		)
+		writer = constructor(&egress.URLBinding{}, egress.NetworkTimeoutConfig{}, false, &testhelper.SpyMetric{})
+	})
+
+	Context("when writes succeed", func() {
+		BeforeEach(func() {
+			spy = &spyWriteCloser{}
+		})
+
+		It("observes the duration of writes in seconds", func() {
+			start := time.Now()
+			Expect(writer.Write(&v2.Envelope{})).To(Succeed())
+			elapsed := time.Since(start).Seconds()
+
+			Expect(histogram.Observations()).To(ConsistOf(
+				BeNumerically("<=", elapsed),
+			))
+		})
+
+		It("observes the duration of flushes", func() {
+			Expect(writer.(egress.Flusher).Flush()).To(Succeed())
+
+			Expect(spy.FlushAttempts()).To(Equal(1))
+			Expect(histogram.Observations()).To(HaveLen(1))
+		})
+
+		It("does not observe flushes that send nothing", func() {
+			pending := &spyPendingWriteCloser{}
+			writer := egress.WriteDurationWrapper(
+				func(*egress.URLBinding, egress.NetworkTimeoutConfig, bool, pulseemitter.CounterMetric) egress.WriteCloser {
+					return pending
+				},
+				histogram,
+			)(&egress.URLBinding{}, egress.NetworkTimeoutConfig{}, false, &testhelper.SpyMetric{})
+
+			Expect(writer.(egress.Flusher).Flush()).To(Succeed())
+			Expect(pending.FlushAttempts()).To(BeZero())
+			Expect(histogram.Observations()).To(BeEmpty())
+
+			pending.pending = true
+			Expect(writer.(egress.Flusher).Flush()).To(Succeed())
+			Expect(pending.FlushAttempts()).To(Equal(1))
+			Expect(histogram.Observations()).To(HaveLen(1))
+		})
+	})
+
+	Context("when the circuit breaker is open", func() {
+		BeforeEach(func() {
+			spy = &spyWriteCloser{
+				returnErrCount: 1,
+				writeErr:       egress.ErrCircuitOpen,
+			}
+		})
+
+		It("does not observe rejected writes", func() {
+			Expect(writer.Write(&v2.Envelope{})).To(MatchError(egress.ErrCircuitOpen))
+
+			Expect(histogram.Observations()).To(BeEmpty())
+		})
+	})
+})
+
+type spyHistogram struct {
+	mu           sync.Mutex
+	observations []float64
+}
+
+func (s *spyHistogram) Observe(v float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observations = append(s.observations, v)
+}
+
+func (s *spyHistogram) Observations() []float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]float64(nil), s.observations...)
+}
@@ -0,0 +1,55 @@
+package egress
+
+import (
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+)
+
+// WriteResultWrapper wraps a WriterConstructor so that every write is
+// counted in either the success or the failure metric. It should wrap
+// RetryWrapper so that an envelope that is written after retries counts as a
+// single success.
+func WriteResultWrapper(
+	wc WriterConstructor,
+	success pulseemitter.CounterMetric,
+	failure pulseemitter.CounterMetric,
+) WriterConstructor {
+	return WriterConstructor(func(
+		binding *URLBinding,
+		netConf NetworkTimeoutConfig,
+		skipCertVerify bool,
+		egressMetric pulseemitter.CounterMetric,
+	) WriteCloser {
+		writer := wc(
+			binding,
+			netConf,
+			skipCertVerify,
+			egressMetric,
+		)
+
+		return &WriteResultWriter{
+			writerDelegate: writerDelegate{writer},
+			success:        success,
+			failure:        failure,
+		}
+	})
+}
+
+// WriteResultWriter counts the results of writes to a syslog writer.
+type WriteResultWriter struct {
+	writerDelegate
+	success pulseemitter.CounterMetric
+	failure pulseemitter.CounterMetric
+}
+
+// Write writes the envelope to the syslog writer and counts the result.
+func (w *WriteResultWriter) Write(e *loggregator_v2.Envelope) error {
+	err := w.writer.Write(e)
+	if err != nil {
+		w.failure.Increment(1)
+		return err
+	}
+	w.success.Increment(1)
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package egress_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	v2 "code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteResultWriter", func() {
+	var (
+		spy     *spyWriteCloser
+		success *testhelper.SpyMetric
+		failure *testhelper.SpyMetric
+		writer  egress.WriteCloser
+	)
+
+	BeforeEach(func() {
+		spy = &spyWriteCloser{}
+		success = &testhelper.SpyMetric{}
+		failure = &testhelper.SpyMetric{}
+
+		constructor := egress.WriteResultWrapper(
+			func(*egress.URLBinding, egress.NetworkTimeoutConfig, bool, pulseemitter.CounterMetric) egress.WriteCloser {
+				return spy
+			},
+			success,
+			failure,
+		)
+		writer = constructor(&egress.URLBinding{}, egress.NetworkTimeoutConfig{}, false, &testhelper.SpyMetric{})
+	})
+
+	It("counts successful writes", func() {
+		Expect(writer.Write(&v2.Envelope{})).To(Succeed())
+
+		Expect(success.Delta()).To(Equal(uint64(1)))
+		Expect(failure.Delta()).To(BeZero())
+	})
+
+	It("counts failed writes", func() {
+		spy.writeErr = errors.New("write error")
+		spy.returnErrCount = 1
+
+		Expect(writer.Write(&v2.Envelope{})).ToNot(Succeed())
+
+		Expect(success.Delta()).To(BeZero())
+		Expect(failure.Delta()).To(Equal(uint64(1)))
+	})
+
+	It("closes the syslog writer", func() {
+		Expect(writer.Close()).To(Succeed())
+
+		Expect(spy.closeCalled).To(BeTrue())
+	})
+})
@@ -1,8 +1,8 @@
 package ingress
 
 import (
-	"log"
 	"net/url"
+	"sync"
 	"time"
 
 	loggregator "code.cloudfoundry.org/go-loggregator"
@@ -10,6 +10,7 @@ import (
 	v2 "code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
 	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -52,6 +53,14 @@ func WithLogClient(logClient LogClient, sourceIndex string) SubscriberOption {
 	}
 }
 
+// WithLogger returns a SubscriberOption that writes the logs of the
+// subscriber to l. Logs are discarded by default.
+func WithLogger(l *logging.Logger) SubscriberOption {
+	return func(s *Subscriber) {
+		s.logger = l
+	}
+}
+
 // WithEnableMetricsToSyslog returns a SubscriberOption to override the
 // default setting for writing metrics to syslog. By default this feature is
 // disabled.
@@ -68,9 +77,13 @@ type Subscriber struct {
 	connector              SyslogConnector
 	ingressMetric          pulseemitter.CounterMetric
 	logClient              LogClient
+	logger                 *logging.Logger
 	streamOpenTimeout      time.Duration
 	sourceIndex            string
 	metricsToSyslogEnabled bool
+
+	mu      sync.Mutex
+	streams map[v1.Binding]int
 }
 
 type MetricClient interface {
@@ -99,6 +112,7 @@ func NewSubscriber(
 		logClient:              nullLogClient{},
 		streamOpenTimeout:      2 * time.Second,
 		metricsToSyslogEnabled: false,
+		streams:                make(map[v1.Binding]int),
 	}
 
 	for _, o := range opts {
@@ -143,9 +157,16 @@ func (s *Subscriber) attemptConnectAndRead(ctx context.Context, binding *v1.Bind
 	ctx, cancel = context.WithCancel(ctx)
 	defer cancel()
 
-	writer, err := s.connector.Connect(ctx, binding)
+	logger := s.logger.With(logging.BindingFields(binding.AppId, binding.Drain))
+
+	// The writer is only stopped once reading has stopped so that it can
+	// drain every envelope it was given.
+	writerCtx, stopWriter := context.WithCancel(context.Background())
+	defer stopWriter()
+
+	writer, err := s.connector.Connect(writerCtx, binding)
 	if err != nil {
-		log.Printf("Failed connecting to syslog: %s", err)
+		logger.Errorf("failed connecting to syslog drain: %s", err)
 		return false
 	}
 
@@ -181,7 +202,10 @@ func (s *Subscriber) attemptConnectAndRead(ctx context.Context, binding *v1.Bind
 	}
 	defer batchReceiver.CloseSend()
 
-	if err := s.batchReadWriteLoop(binding.AppId, batchReceiver, writer); err != nil {
+	s.streamOpened(binding)
+	defer s.streamClosed(binding)
+
+	if err := s.batchReadWriteLoop(binding.AppId, batchReceiver, writer, logger); err != nil {
 		loopStatus, ok := status.FromError(err)
 		if ok && loopStatus.Code() == codes.ResourceExhausted {
 			time.Sleep(20 * time.Millisecond)
@@ -192,13 +216,38 @@ func (s *Subscriber) attemptConnectAndRead(ctx context.Context, binding *v1.Bind
 		if ok && (loopStatus.Code() == codes.Canceled || loopStatus.Code() == codes.Unavailable) {
 			return true
 		}
-		log.Printf("Subscriber read/write loop has unexpectedly closed: %s", err)
+		logger.Warnf("subscriber read/write loop has unexpectedly closed: %s", err)
 	}
 
 	return true
 }
 
-func (s *Subscriber) readWriteLoop(sourceId string, r v2.Egress_ReceiverClient, w egress.Writer) error {
+// Streaming reports whether a stream of the logs of the binding is open.
+func (s *Subscriber) Streaming(binding *v1.Binding) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.streams[*binding] > 0
+}
+
+func (s *Subscriber) streamOpened(binding *v1.Binding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.streams[*binding]++
+}
+
+func (s *Subscriber) streamClosed(binding *v1.Binding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.streams[*binding]--
+	if s.streams[*binding] <= 0 {
+		delete(s.streams, *binding)
+	}
+}
+
+func (s *Subscriber) readWriteLoop(sourceId string, r v2.Egress_ReceiverClient, w egress.Writer, logger *logging.Logger) error {
 	for {
 		env, err := r.Recv()
 		if err != nil {
@@ -210,7 +259,7 @@ func (s *Subscriber) readWriteLoop(sourceId string, r v2.Egress_ReceiverClient,
 		// defensive and hopefully the logs provider will do its job, but
 		// better safe than sorry.
 		if env.GetSourceId() != sourceId {
-			log.Print("Warning! Logs provider gave us an unexpected app-id!")
+			logger.Warnf("logs provider gave us an unexpected app ID: %s", env.GetSourceId())
 			continue
 		}
 
@@ -222,7 +271,7 @@ func (s *Subscriber) readWriteLoop(sourceId string, r v2.Egress_ReceiverClient,
 	}
 }
 
-func (s *Subscriber) batchReadWriteLoop(sourceId string, r v2.Egress_BatchedReceiverClient, w egress.Writer) error {
+func (s *Subscriber) batchReadWriteLoop(sourceId string, r v2.Egress_BatchedReceiverClient, w egress.Writer, logger *logging.Logger) error {
 	for {
 		envBatch, err := r.Recv()
 		if err != nil {
@@ -238,7 +287,7 @@ func (s *Subscriber) batchReadWriteLoop(sourceId string, r v2.Egress_BatchedRece
 			// defensive and hopefully the logs provider will do its job, but
 			// better safe than sorry.
 			if env.GetSourceId() != sourceId {
-				log.Print("Warning! Logs provider gave us an unexpected app-id!")
+				logger.Warnf("logs provider gave us an unexpected app ID: %s", env.GetSourceId())
 				continue
 			}
 			// We decided to ignore the error from the writer since in most
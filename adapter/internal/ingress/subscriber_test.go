@@ -210,6 +210,31 @@ var _ = Describe("Subscriber", func() {
 		Eventually(receiverCtx.Done).Should(BeClosed())
 	})
 
+	It("reports a binding as streaming while a stream of its logs is open", func() {
+		receiver := newBlockingReceiverClient()
+		client.batchedReceiverClient = receiver
+		subscriber := ingress.NewSubscriber(
+			context.TODO(),
+			clientPool,
+			syslogConnector,
+			spyEmitter,
+			ingress.WithStreamOpenTimeout(500*time.Millisecond),
+		)
+		Expect(subscriber.Streaming(binding)).To(BeFalse())
+
+		cancel := subscriber.Start(binding)
+
+		streaming := func() bool {
+			return subscriber.Streaming(binding)
+		}
+		Eventually(streaming).Should(BeTrue())
+
+		cancel()
+		close(receiver.done)
+
+		Eventually(streaming).Should(BeFalse())
+	})
+
 	It("emits ingress metrics", func() {
 		batchedReceiverClient.recv = buildBatchedLogs(1)
 		client.batchedReceiverClient = batchedReceiverClient
@@ -671,6 +696,26 @@ func (i *envelopeReceiver) Recv() (*v2.Envelope, error) {
 	return &v2.Envelope{}, nil
 }
 
+type blockingReceiver struct {
+	done chan struct{}
+	grpc.ClientStream
+}
+
+func newBlockingReceiverClient() *blockingReceiver {
+	return &blockingReceiver{
+		done: make(chan struct{}),
+	}
+}
+
+func (b *blockingReceiver) CloseSend() error {
+	return nil
+}
+
+func (b *blockingReceiver) Recv() (*v2.EnvelopeBatch, error) {
+	<-b.done
+	return nil, errors.New("stream closed")
+}
+
 type errorReceiver struct {
 	mu         sync.Mutex
 	recvCalls_ int
@@ -13,7 +13,9 @@ import (
 	"code.cloudfoundry.org/go-loggregator"
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
 	"code.cloudfoundry.org/scalable-syslog/adapter/app"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
 	"code.cloudfoundry.org/scalable-syslog/internal/api"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 )
 
 func main() {
@@ -21,6 +23,11 @@ func main() {
 
 	cfg := app.LoadConfig()
 
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+	logger := logging.New(os.Stderr, level).With(logging.Fields{
+		"component": "adapter",
+	})
+
 	tlsConfig, err := api.NewMutualTLSConfig(
 		cfg.CertFile,
 		cfg.KeyFile,
@@ -28,7 +35,7 @@ func main() {
 		cfg.CommonName,
 	)
 	if err != nil {
-		log.Fatalf("Invalid TLS config: %s", err)
+		logger.Fatalf("Invalid TLS config: %s", err)
 	}
 
 	rlpTlsConfig, err := api.NewMutualTLSConfig(
@@ -38,7 +45,7 @@ func main() {
 		cfg.RLPCommonName,
 	)
 	if err != nil {
-		log.Fatalf("Invalid RLP TLS config: %s", err)
+		logger.Fatalf("Invalid RLP TLS config: %s", err)
 	}
 
 	metricIngressTLS, err := api.NewMutualTLSConfig(
@@ -48,7 +55,31 @@ func main() {
 		cfg.MetricIngressCN,
 	)
 	if err != nil {
-		log.Fatalf("Invalid Metric Ingress TLS config: %s", err)
+		logger.Fatalf("Invalid Metric Ingress TLS config: %s", err)
+	}
+
+	schedulerTLSConfig, err := api.NewMutualTLSConfig(
+		cfg.CertFile,
+		cfg.KeyFile,
+		cfg.CAFile,
+		cfg.SchedulerCommonName,
+	)
+	if err != nil {
+		logger.Fatalf("Invalid scheduler TLS config: %s", err)
+	}
+
+	syslogTLSConfig, err := api.NewTLSConfigWithPolicy(
+		cfg.SyslogTLSMinVersion,
+		cfg.SyslogTLSCipherSuites,
+		cfg.SyslogTLSCurves,
+	)
+	if err != nil {
+		logger.Fatalf("Invalid syslog TLS config: %s", err)
+	}
+
+	retryBackoff, err := egress.BackoffByName(cfg.SyslogRetryBackoff)
+	if err != nil {
+		logger.Fatalf("Invalid syslog retry config: %s", err)
 	}
 
 	logClient, err := loggregator.NewIngressClient(
@@ -57,7 +88,7 @@ func main() {
 		loggregator.WithAddr(cfg.MetricIngressAddr),
 	)
 	if err != nil {
-		log.Fatalf("Couldn't connect to metric ingress server: %s", err)
+		logger.Fatalf("Couldn't connect to metric ingress server: %s", err)
 	}
 
 	metricClient := pulseemitter.New(
@@ -66,7 +97,7 @@ func main() {
 		pulseemitter.WithSourceID("drain_adapter"),
 	)
 
-	go startPprof(cfg.PprofHostport)
+	go startPprof(cfg.PprofHostport, logger)
 
 	adapter := app.NewAdapter(
 		cfg.LogsAPIAddr,
@@ -82,8 +113,19 @@ func main() {
 		app.WithSyslogDialTimeout(cfg.SyslogDialTimeout),
 		app.WithSyslogIOTimeout(cfg.SyslogIOTimeout),
 		app.WithSyslogSkipCertVerify(cfg.SyslogSkipCertVerify),
+		app.WithSyslogTLSConfig(syslogTLSConfig),
 		app.WithMetricsToSyslogEnabled(cfg.MetricsToSyslogEnabled),
 		app.WithMaxBindings(cfg.MaxBindings),
+		app.WithDiskBuffer(cfg.DiskBufferDir, cfg.DiskBufferMaxBytes),
+		app.WithBufferSize(cfg.BufferSize, cfg.MaxBufferSize),
+		app.WithMemoryBudget(cfg.MemoryBudgetBytes),
+		app.WithCircuitBreaker(cfg.CircuitBreakerFailures, cfg.CircuitBreakerDuration),
+		app.WithRetryBackoff(retryBackoff),
+		app.WithRetryLimits(cfg.SyslogRetryBase, cfg.SyslogRetryCap, cfg.SyslogRetryMax),
+		app.WithDrainErrorLogInterval(cfg.DrainErrorLogInterval),
+		app.WithDrainFlushTimeout(cfg.DrainFlushTimeout),
+		app.WithSchedulerWatch(cfg.SchedulerAddr, cfg.AdvertiseAddr, schedulerTLSConfig),
+		app.WithLogger(logger),
 	)
 	go adapter.Start()
 	defer adapter.Stop()
@@ -93,12 +135,13 @@ func main() {
 	<-killSignal
 }
 
-func startPprof(hostport string) {
+func startPprof(hostport string, logger *logging.Logger) {
 	lis, err := net.Listen("tcp", hostport)
 	if err != nil {
-		log.Printf("Error creating pprof listener: %s", err)
+		logger.Errorf("error creating pprof listener: %s", err)
+		return
 	}
 
-	log.Printf("Starting pprof server on: %s", lis.Addr().String())
-	log.Println(http.Serve(lis, nil))
+	logger.Infof("starting pprof server on: %s", lis.Addr().String())
+	logger.Errorf("pprof server closing: %s", http.Serve(lis, nil))
 }
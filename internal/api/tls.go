@@ -15,6 +15,31 @@ var supportedCipherSuites = []uint16{
 	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
 }
 
+var tlsVersions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+var cipherSuites = map[string]uint16{
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":    tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":  tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+}
+
+var curves = map[string]tls.CurveID{
+	"P256":   tls.CurveP256,
+	"P384":   tls.CurveP384,
+	"P521":   tls.CurveP521,
+	"X25519": tls.X25519,
+}
+
 type CASignatureError string
 
 func (e CASignatureError) Error() string {
@@ -30,6 +55,47 @@ func NewTLSConfig() *tls.Config {
 	}
 }
 
+// NewTLSConfigWithPolicy returns a TLS config like NewTLSConfig with the
+// minimum version, cipher suites and curve preferences given by name, e.g.
+// "TLS12", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256" and "X25519". Empty values
+// keep the defaults of NewTLSConfig.
+func NewTLSConfigWithPolicy(
+	minVersion string,
+	cipherSuiteNames []string,
+	curveNames []string,
+) (*tls.Config, error) {
+	tlsConfig := NewTLSConfig()
+
+	if minVersion != "" {
+		v, ok := tlsVersions[minVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS version: %s", minVersion)
+		}
+		tlsConfig.MinVersion = v
+	}
+
+	if len(cipherSuiteNames) > 0 {
+		tlsConfig.CipherSuites = nil
+		for _, name := range cipherSuiteNames {
+			c, ok := cipherSuites[name]
+			if !ok {
+				return nil, fmt.Errorf("unsupported cipher suite: %s", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, c)
+		}
+	}
+
+	for _, name := range curveNames {
+		c, ok := curves[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve: %s", name)
+		}
+		tlsConfig.CurvePreferences = append(tlsConfig.CurvePreferences, c)
+	}
+
+	return tlsConfig, nil
+}
+
 // NewMutualTLSConfig returns a tls.Config with certs loaded from files and
 // the ServerName set.
 func NewMutualTLSConfig(certFile, keyFile, caCertFile, serverName string) (*tls.Config, error) {
@@ -116,6 +116,46 @@ var _ = Describe("TLS", func() {
 			Expect(tlsConf.CipherSuites).To(ContainElement(tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384))
 		})
 	})
+
+	Context("NewTLSConfigWithPolicy", func() {
+		It("returns the basic TLS config when no policy is given", func() {
+			tlsConf, err := api.NewTLSConfigWithPolicy("", nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tlsConf).To(Equal(api.NewTLSConfig()))
+		})
+
+		It("applies the minimum version, cipher suites and curves", func() {
+			tlsConf, err := api.NewTLSConfigWithPolicy(
+				"TLS13",
+				[]string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"},
+				[]string{"X25519", "P256"},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tlsConf.MinVersion).To(Equal(uint16(tls.VersionTLS13)))
+			Expect(tlsConf.CipherSuites).To(Equal([]uint16{
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			}))
+			Expect(tlsConf.CurvePreferences).To(Equal([]tls.CurveID{
+				tls.X25519,
+				tls.CurveP256,
+			}))
+		})
+
+		It("returns an error for an unknown version", func() {
+			_, err := api.NewTLSConfigWithPolicy("SSL3", nil, nil)
+			Expect(err).To(MatchError("unsupported TLS version: SSL3"))
+		})
+
+		It("returns an error for an unknown cipher suite", func() {
+			_, err := api.NewTLSConfigWithPolicy("", []string{"TLS_RSA_WITH_RC4_128_SHA"}, nil)
+			Expect(err).To(MatchError("unsupported cipher suite: TLS_RSA_WITH_RC4_128_SHA"))
+		})
+
+		It("returns an error for an unknown curve", func() {
+			_, err := api.NewTLSConfigWithPolicy("", nil, []string{"P224"})
+			Expect(err).To(MatchError("unsupported curve: P224"))
+		})
+	})
 })
 
 func writeFile(data string) string {
@@ -16,6 +16,15 @@ It has these top-level messages:
 	CreateBindingResponse
 	DeleteBindingRequest
 	DeleteBindingResponse
+	ListBindingStatsRequest
+	ListBindingStatsResponse
+	BindingStats
+	BindingStreamingRequest
+	BindingStreamingResponse
+	SyncBindingsRequest
+	SyncBindingsResponse
+	WatchBindingsRequest
+	WatchBindingsResponse
 */
 package scalablesyslog
 
@@ -43,6 +52,9 @@ type Binding struct {
 	AppId    string `protobuf:"bytes,1,opt,name=appId" json:"appId,omitempty"`
 	Hostname string `protobuf:"bytes,2,opt,name=hostname" json:"hostname,omitempty"`
 	Drain    string `protobuf:"bytes,3,opt,name=drain" json:"drain,omitempty"`
+	Cert     string `protobuf:"bytes,4,opt,name=cert" json:"cert,omitempty"`
+	Key      string `protobuf:"bytes,5,opt,name=key" json:"key,omitempty"`
+	Ca       string `protobuf:"bytes,6,opt,name=ca" json:"ca,omitempty"`
 }
 
 func (m *Binding) Reset()                    { *m = Binding{} }
@@ -71,6 +83,27 @@ func (m *Binding) GetDrain() string {
 	return ""
 }
 
+func (m *Binding) GetCert() string {
+	if m != nil {
+		return m.Cert
+	}
+	return ""
+}
+
+func (m *Binding) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Binding) GetCa() string {
+	if m != nil {
+		return m.Ca
+	}
+	return ""
+}
+
 type ListBindingsRequest struct {
 }
 
@@ -143,6 +176,246 @@ func (m *DeleteBindingResponse) String() string            { return proto.Compac
 func (*DeleteBindingResponse) ProtoMessage()               {}
 func (*DeleteBindingResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
+type ListBindingStatsRequest struct {
+}
+
+func (m *ListBindingStatsRequest) Reset()                    { *m = ListBindingStatsRequest{} }
+func (m *ListBindingStatsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListBindingStatsRequest) ProtoMessage()               {}
+func (*ListBindingStatsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+type ListBindingStatsResponse struct {
+	Stats []*BindingStats `protobuf:"bytes,1,rep,name=stats" json:"stats,omitempty"`
+}
+
+func (m *ListBindingStatsResponse) Reset()                    { *m = ListBindingStatsResponse{} }
+func (m *ListBindingStatsResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListBindingStatsResponse) ProtoMessage()               {}
+func (*ListBindingStatsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *ListBindingStatsResponse) GetStats() []*BindingStats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+type BindingStats struct {
+	Binding            *Binding `protobuf:"bytes,1,opt,name=binding" json:"binding,omitempty"`
+	Ingress            uint64   `protobuf:"varint,2,opt,name=ingress" json:"ingress,omitempty"`
+	Egress             uint64   `protobuf:"varint,3,opt,name=egress" json:"egress,omitempty"`
+	Dropped            uint64   `protobuf:"varint,4,opt,name=dropped" json:"dropped,omitempty"`
+	LastWriteTimestamp int64    `protobuf:"varint,5,opt,name=lastWriteTimestamp" json:"lastWriteTimestamp,omitempty"`
+	LastError          string   `protobuf:"bytes,6,opt,name=lastError" json:"lastError,omitempty"`
+	LastErrorTimestamp int64    `protobuf:"varint,7,opt,name=lastErrorTimestamp" json:"lastErrorTimestamp,omitempty"`
+	RetryAttempt       uint32   `protobuf:"varint,8,opt,name=retryAttempt" json:"retryAttempt,omitempty"`
+	State              string   `protobuf:"bytes,9,opt,name=state" json:"state,omitempty"`
+	MaxLag             int64    `protobuf:"varint,10,opt,name=maxLag" json:"maxLag,omitempty"`
+}
+
+func (m *BindingStats) Reset()                    { *m = BindingStats{} }
+func (m *BindingStats) String() string            { return proto.CompactTextString(m) }
+func (*BindingStats) ProtoMessage()               {}
+func (*BindingStats) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *BindingStats) GetBinding() *Binding {
+	if m != nil {
+		return m.Binding
+	}
+	return nil
+}
+
+func (m *BindingStats) GetIngress() uint64 {
+	if m != nil {
+		return m.Ingress
+	}
+	return 0
+}
+
+func (m *BindingStats) GetEgress() uint64 {
+	if m != nil {
+		return m.Egress
+	}
+	return 0
+}
+
+func (m *BindingStats) GetDropped() uint64 {
+	if m != nil {
+		return m.Dropped
+	}
+	return 0
+}
+
+func (m *BindingStats) GetLastWriteTimestamp() int64 {
+	if m != nil {
+		return m.LastWriteTimestamp
+	}
+	return 0
+}
+
+func (m *BindingStats) GetLastError() string {
+	if m != nil {
+		return m.LastError
+	}
+	return ""
+}
+
+func (m *BindingStats) GetLastErrorTimestamp() int64 {
+	if m != nil {
+		return m.LastErrorTimestamp
+	}
+	return 0
+}
+
+func (m *BindingStats) GetRetryAttempt() uint32 {
+	if m != nil {
+		return m.RetryAttempt
+	}
+	return 0
+}
+
+func (m *BindingStats) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *BindingStats) GetMaxLag() int64 {
+	if m != nil {
+		return m.MaxLag
+	}
+	return 0
+}
+
+type BindingStreamingRequest struct {
+	Binding *Binding `protobuf:"bytes,1,opt,name=binding" json:"binding,omitempty"`
+}
+
+func (m *BindingStreamingRequest) Reset()                    { *m = BindingStreamingRequest{} }
+func (m *BindingStreamingRequest) String() string            { return proto.CompactTextString(m) }
+func (*BindingStreamingRequest) ProtoMessage()               {}
+func (*BindingStreamingRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *BindingStreamingRequest) GetBinding() *Binding {
+	if m != nil {
+		return m.Binding
+	}
+	return nil
+}
+
+type BindingStreamingResponse struct {
+	Streaming bool `protobuf:"varint,1,opt,name=streaming" json:"streaming,omitempty"`
+}
+
+func (m *BindingStreamingResponse) Reset()                    { *m = BindingStreamingResponse{} }
+func (m *BindingStreamingResponse) String() string            { return proto.CompactTextString(m) }
+func (*BindingStreamingResponse) ProtoMessage()               {}
+func (*BindingStreamingResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *BindingStreamingResponse) GetStreaming() bool {
+	if m != nil {
+		return m.Streaming
+	}
+	return false
+}
+
+type SyncBindingsRequest struct {
+	Bindings []*Binding `protobuf:"bytes,1,rep,name=bindings" json:"bindings,omitempty"`
+}
+
+func (m *SyncBindingsRequest) Reset()                    { *m = SyncBindingsRequest{} }
+func (m *SyncBindingsRequest) String() string            { return proto.CompactTextString(m) }
+func (*SyncBindingsRequest) ProtoMessage()               {}
+func (*SyncBindingsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *SyncBindingsRequest) GetBindings() []*Binding {
+	if m != nil {
+		return m.Bindings
+	}
+	return nil
+}
+
+type SyncBindingsResponse struct {
+	Added    uint32 `protobuf:"varint,1,opt,name=added" json:"added,omitempty"`
+	Deleted  uint32 `protobuf:"varint,2,opt,name=deleted" json:"deleted,omitempty"`
+	Rejected uint32 `protobuf:"varint,3,opt,name=rejected" json:"rejected,omitempty"`
+}
+
+func (m *SyncBindingsResponse) Reset()                    { *m = SyncBindingsResponse{} }
+func (m *SyncBindingsResponse) String() string            { return proto.CompactTextString(m) }
+func (*SyncBindingsResponse) ProtoMessage()               {}
+func (*SyncBindingsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *SyncBindingsResponse) GetAdded() uint32 {
+	if m != nil {
+		return m.Added
+	}
+	return 0
+}
+
+func (m *SyncBindingsResponse) GetDeleted() uint32 {
+	if m != nil {
+		return m.Deleted
+	}
+	return 0
+}
+
+func (m *SyncBindingsResponse) GetRejected() uint32 {
+	if m != nil {
+		return m.Rejected
+	}
+	return 0
+}
+
+type WatchBindingsRequest struct {
+	Adapter string `protobuf:"bytes,1,opt,name=adapter" json:"adapter,omitempty"`
+}
+
+func (m *WatchBindingsRequest) Reset()                    { *m = WatchBindingsRequest{} }
+func (m *WatchBindingsRequest) String() string            { return proto.CompactTextString(m) }
+func (*WatchBindingsRequest) ProtoMessage()               {}
+func (*WatchBindingsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *WatchBindingsRequest) GetAdapter() string {
+	if m != nil {
+		return m.Adapter
+	}
+	return ""
+}
+
+type WatchBindingsResponse struct {
+	Snapshot bool       `protobuf:"varint,1,opt,name=snapshot" json:"snapshot,omitempty"`
+	Added    []*Binding `protobuf:"bytes,2,rep,name=added" json:"added,omitempty"`
+	Deleted  []*Binding `protobuf:"bytes,3,rep,name=deleted" json:"deleted,omitempty"`
+}
+
+func (m *WatchBindingsResponse) Reset()                    { *m = WatchBindingsResponse{} }
+func (m *WatchBindingsResponse) String() string            { return proto.CompactTextString(m) }
+func (*WatchBindingsResponse) ProtoMessage()               {}
+func (*WatchBindingsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *WatchBindingsResponse) GetSnapshot() bool {
+	if m != nil {
+		return m.Snapshot
+	}
+	return false
+}
+
+func (m *WatchBindingsResponse) GetAdded() []*Binding {
+	if m != nil {
+		return m.Added
+	}
+	return nil
+}
+
+func (m *WatchBindingsResponse) GetDeleted() []*Binding {
+	if m != nil {
+		return m.Deleted
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Binding)(nil), "scalablesyslog.Binding")
 	proto.RegisterType((*ListBindingsRequest)(nil), "scalablesyslog.ListBindingsRequest")
@@ -151,6 +424,15 @@ func init() {
 	proto.RegisterType((*CreateBindingResponse)(nil), "scalablesyslog.CreateBindingResponse")
 	proto.RegisterType((*DeleteBindingRequest)(nil), "scalablesyslog.DeleteBindingRequest")
 	proto.RegisterType((*DeleteBindingResponse)(nil), "scalablesyslog.DeleteBindingResponse")
+	proto.RegisterType((*ListBindingStatsRequest)(nil), "scalablesyslog.ListBindingStatsRequest")
+	proto.RegisterType((*ListBindingStatsResponse)(nil), "scalablesyslog.ListBindingStatsResponse")
+	proto.RegisterType((*BindingStats)(nil), "scalablesyslog.BindingStats")
+	proto.RegisterType((*BindingStreamingRequest)(nil), "scalablesyslog.BindingStreamingRequest")
+	proto.RegisterType((*BindingStreamingResponse)(nil), "scalablesyslog.BindingStreamingResponse")
+	proto.RegisterType((*SyncBindingsRequest)(nil), "scalablesyslog.SyncBindingsRequest")
+	proto.RegisterType((*SyncBindingsResponse)(nil), "scalablesyslog.SyncBindingsResponse")
+	proto.RegisterType((*WatchBindingsRequest)(nil), "scalablesyslog.WatchBindingsRequest")
+	proto.RegisterType((*WatchBindingsResponse)(nil), "scalablesyslog.WatchBindingsResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -167,6 +449,9 @@ type AdapterClient interface {
 	ListBindings(ctx context.Context, in *ListBindingsRequest, opts ...grpc.CallOption) (*ListBindingsResponse, error)
 	CreateBinding(ctx context.Context, in *CreateBindingRequest, opts ...grpc.CallOption) (*CreateBindingResponse, error)
 	DeleteBinding(ctx context.Context, in *DeleteBindingRequest, opts ...grpc.CallOption) (*DeleteBindingResponse, error)
+	ListBindingStats(ctx context.Context, in *ListBindingStatsRequest, opts ...grpc.CallOption) (*ListBindingStatsResponse, error)
+	BindingStreaming(ctx context.Context, in *BindingStreamingRequest, opts ...grpc.CallOption) (*BindingStreamingResponse, error)
+	SyncBindings(ctx context.Context, in *SyncBindingsRequest, opts ...grpc.CallOption) (*SyncBindingsResponse, error)
 }
 
 type adapterClient struct {
@@ -204,12 +489,42 @@ func (c *adapterClient) DeleteBinding(ctx context.Context, in *DeleteBindingRequ
 	return out, nil
 }
 
+func (c *adapterClient) ListBindingStats(ctx context.Context, in *ListBindingStatsRequest, opts ...grpc.CallOption) (*ListBindingStatsResponse, error) {
+	out := new(ListBindingStatsResponse)
+	err := grpc.Invoke(ctx, "/scalablesyslog.Adapter/ListBindingStats", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adapterClient) BindingStreaming(ctx context.Context, in *BindingStreamingRequest, opts ...grpc.CallOption) (*BindingStreamingResponse, error) {
+	out := new(BindingStreamingResponse)
+	err := grpc.Invoke(ctx, "/scalablesyslog.Adapter/BindingStreaming", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adapterClient) SyncBindings(ctx context.Context, in *SyncBindingsRequest, opts ...grpc.CallOption) (*SyncBindingsResponse, error) {
+	out := new(SyncBindingsResponse)
+	err := grpc.Invoke(ctx, "/scalablesyslog.Adapter/SyncBindings", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Adapter service
 
 type AdapterServer interface {
 	ListBindings(context.Context, *ListBindingsRequest) (*ListBindingsResponse, error)
 	CreateBinding(context.Context, *CreateBindingRequest) (*CreateBindingResponse, error)
 	DeleteBinding(context.Context, *DeleteBindingRequest) (*DeleteBindingResponse, error)
+	ListBindingStats(context.Context, *ListBindingStatsRequest) (*ListBindingStatsResponse, error)
+	BindingStreaming(context.Context, *BindingStreamingRequest) (*BindingStreamingResponse, error)
+	SyncBindings(context.Context, *SyncBindingsRequest) (*SyncBindingsResponse, error)
 }
 
 func RegisterAdapterServer(s *grpc.Server, srv AdapterServer) {
@@ -270,6 +585,60 @@ func _Adapter_DeleteBinding_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Adapter_ListBindingStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBindingStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdapterServer).ListBindingStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/scalablesyslog.Adapter/ListBindingStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdapterServer).ListBindingStats(ctx, req.(*ListBindingStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Adapter_BindingStreaming_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BindingStreamingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdapterServer).BindingStreaming(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/scalablesyslog.Adapter/BindingStreaming",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdapterServer).BindingStreaming(ctx, req.(*BindingStreamingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Adapter_SyncBindings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncBindingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdapterServer).SyncBindings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/scalablesyslog.Adapter/SyncBindings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdapterServer).SyncBindings(ctx, req.(*SyncBindingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Adapter_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "scalablesyslog.Adapter",
 	HandlerType: (*AdapterServer)(nil),
@@ -286,31 +655,160 @@ var _Adapter_serviceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteBinding",
 			Handler:    _Adapter_DeleteBinding_Handler,
 		},
+		{
+			MethodName: "ListBindingStats",
+			Handler:    _Adapter_ListBindingStats_Handler,
+		},
+		{
+			MethodName: "BindingStreaming",
+			Handler:    _Adapter_BindingStreaming_Handler,
+		},
+		{
+			MethodName: "SyncBindings",
+			Handler:    _Adapter_SyncBindings_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "adapter.proto",
 }
 
+// Client API for Scheduler service
+
+type SchedulerClient interface {
+	WatchBindings(ctx context.Context, in *WatchBindingsRequest, opts ...grpc.CallOption) (Scheduler_WatchBindingsClient, error)
+}
+
+type schedulerClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewSchedulerClient(cc *grpc.ClientConn) SchedulerClient {
+	return &schedulerClient{cc}
+}
+
+func (c *schedulerClient) WatchBindings(ctx context.Context, in *WatchBindingsRequest, opts ...grpc.CallOption) (Scheduler_WatchBindingsClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Scheduler_serviceDesc.Streams[0], c.cc, "/scalablesyslog.Scheduler/WatchBindings", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &schedulerWatchBindingsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Scheduler_WatchBindingsClient interface {
+	Recv() (*WatchBindingsResponse, error)
+	grpc.ClientStream
+}
+
+type schedulerWatchBindingsClient struct {
+	grpc.ClientStream
+}
+
+func (x *schedulerWatchBindingsClient) Recv() (*WatchBindingsResponse, error) {
+	m := new(WatchBindingsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for Scheduler service
+
+type SchedulerServer interface {
+	WatchBindings(*WatchBindingsRequest, Scheduler_WatchBindingsServer) error
+}
+
+func RegisterSchedulerServer(s *grpc.Server, srv SchedulerServer) {
+	s.RegisterService(&_Scheduler_serviceDesc, srv)
+}
+
+func _Scheduler_WatchBindings_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchBindingsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SchedulerServer).WatchBindings(m, &schedulerWatchBindingsServer{stream})
+}
+
+type Scheduler_WatchBindingsServer interface {
+	Send(*WatchBindingsResponse) error
+	grpc.ServerStream
+}
+
+type schedulerWatchBindingsServer struct {
+	grpc.ServerStream
+}
+
+func (x *schedulerWatchBindingsServer) Send(m *WatchBindingsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Scheduler_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "scalablesyslog.Scheduler",
+	HandlerType: (*SchedulerServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchBindings",
+			Handler:       _Scheduler_WatchBindings_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "adapter.proto",
+}
+
 func init() { proto.RegisterFile("adapter.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 284 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x92, 0xbd, 0x4e, 0xc3, 0x30,
-	0x14, 0x85, 0x9b, 0x56, 0x90, 0x72, 0xa1, 0x0c, 0x26, 0x55, 0xa3, 0x4c, 0x95, 0x01, 0xa9, 0x53,
-	0x24, 0xda, 0x27, 0xe0, 0x67, 0xa9, 0x60, 0x21, 0x2b, 0x12, 0x92, 0x43, 0xae, 0x4a, 0xa4, 0x60,
-	0x9b, 0x5c, 0x33, 0xf0, 0x42, 0x3c, 0x27, 0x6a, 0xec, 0x56, 0xcd, 0x8f, 0xc2, 0xc2, 0x78, 0xed,
-	0x73, 0xbf, 0xe3, 0x73, 0x12, 0x98, 0x88, 0x4c, 0x68, 0x83, 0x65, 0xac, 0x4b, 0x65, 0x14, 0x3b,
-	0xa7, 0x37, 0x51, 0x88, 0xb4, 0x40, 0xfa, 0xa6, 0x42, 0x6d, 0xf8, 0x33, 0xf8, 0x77, 0xb9, 0xcc,
-	0x72, 0xb9, 0x61, 0x01, 0x1c, 0x09, 0xad, 0xd7, 0x59, 0xe8, 0xcd, 0xbd, 0xc5, 0x49, 0x62, 0x07,
-	0x16, 0xc1, 0xf8, 0x5d, 0x91, 0x91, 0xe2, 0x03, 0xc3, 0x61, 0x75, 0xb1, 0x9f, 0xb7, 0x1b, 0x59,
-	0x29, 0x72, 0x19, 0x8e, 0xec, 0x46, 0x35, 0xf0, 0x29, 0x5c, 0x3c, 0xe5, 0x64, 0x1c, 0x96, 0x12,
-	0xfc, 0xfc, 0x42, 0x32, 0xfc, 0x11, 0x82, 0xfa, 0x31, 0x69, 0x25, 0x09, 0xd9, 0x0a, 0xc6, 0xa9,
-	0x3b, 0x0b, 0xbd, 0xf9, 0x68, 0x71, 0xba, 0x9c, 0xc5, 0xf5, 0x47, 0xc6, 0x6e, 0x27, 0xd9, 0x0b,
-	0xf9, 0x1a, 0x82, 0xfb, 0x12, 0x85, 0xc1, 0xdd, 0x95, 0x35, 0x61, 0x37, 0xe0, 0x3b, 0x4d, 0x95,
-	0xa2, 0x87, 0xb5, 0xd3, 0xf1, 0x19, 0x4c, 0x1b, 0x28, 0xfb, 0xb0, 0xad, 0xc7, 0x03, 0x16, 0xf8,
-	0x4f, 0x1e, 0x0d, 0x94, 0xf5, 0x58, 0xfe, 0x0c, 0xc1, 0xbf, 0xb5, 0x1f, 0x88, 0xbd, 0xc0, 0xd9,
-	0x61, 0x41, 0xec, 0xb2, 0x89, 0xed, 0x68, 0x35, 0xba, 0xea, 0x17, 0xb9, 0x28, 0x03, 0xf6, 0x0a,
-	0x93, 0x5a, 0x4a, 0xd6, 0x5a, 0xec, 0xea, 0x33, 0xba, 0xfe, 0x43, 0x75, 0xc8, 0xaf, 0x25, 0x6c,
-	0xf3, 0xbb, 0xba, 0x6c, 0xf3, 0x3b, 0x6b, 0xe2, 0x83, 0xf4, 0xb8, 0xfa, 0x7d, 0x57, 0xbf, 0x01,
-	0x00, 0x00, 0xff, 0xff, 0xba, 0x87, 0x9a, 0x05, 0xcf, 0x02, 0x00, 0x00,
+	// 690 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x56, 0x4d, 0x6f, 0xd3, 0x4c,
+	0x10, 0x7e, 0x1d, 0xa7, 0x4d, 0x32, 0x6f, 0x52, 0x55, 0xdb, 0x94, 0x2c, 0x56, 0x0f, 0x95, 0x69,
+	0x45, 0x2e, 0x44, 0x6d, 0x7a, 0xe1, 0x5a, 0x3e, 0x0e, 0x85, 0x8a, 0x83, 0x8b, 0xd4, 0x43, 0x25,
+	0xc4, 0xc6, 0x1e, 0x25, 0x86, 0xf8, 0x83, 0xdd, 0xad, 0x44, 0x7e, 0x01, 0xbf, 0x80, 0x9f, 0xc6,
+	0x91, 0xff, 0x82, 0xbc, 0x5e, 0x3b, 0xfe, 0x48, 0x1d, 0xa9, 0x70, 0xcb, 0x33, 0x1f, 0xcf, 0xcc,
+	0xac, 0x67, 0x1e, 0x05, 0x06, 0xcc, 0x63, 0xb1, 0x44, 0x3e, 0x89, 0x79, 0x24, 0x23, 0xb2, 0x27,
+	0x5c, 0xb6, 0x64, 0xb3, 0x25, 0x8a, 0x95, 0x58, 0x46, 0x73, 0xfb, 0x87, 0x01, 0x9d, 0x57, 0x7e,
+	0xe8, 0xf9, 0xe1, 0x9c, 0x0c, 0x61, 0x87, 0xc5, 0xf1, 0x95, 0x47, 0x8d, 0x63, 0x63, 0xdc, 0x73,
+	0x52, 0x40, 0x2c, 0xe8, 0x2e, 0x22, 0x21, 0x43, 0x16, 0x20, 0x6d, 0x29, 0x47, 0x8e, 0x93, 0x0c,
+	0x8f, 0x33, 0x3f, 0xa4, 0x66, 0x9a, 0xa1, 0x00, 0x21, 0xd0, 0x76, 0x91, 0x4b, 0xda, 0x56, 0x46,
+	0xf5, 0x9b, 0xec, 0x83, 0xf9, 0x15, 0x57, 0x74, 0x47, 0x99, 0x92, 0x9f, 0x64, 0x0f, 0x5a, 0x2e,
+	0xa3, 0xbb, 0xca, 0xd0, 0x72, 0x99, 0x7d, 0x08, 0x07, 0xd7, 0xbe, 0x90, 0xba, 0x19, 0xe1, 0xe0,
+	0xb7, 0x7b, 0x14, 0xd2, 0x7e, 0x0f, 0xc3, 0xb2, 0x59, 0xc4, 0x51, 0x28, 0x90, 0x5c, 0x40, 0x77,
+	0xa6, 0x6d, 0xd4, 0x38, 0x36, 0xc7, 0xff, 0x4f, 0x47, 0x93, 0xf2, 0x6c, 0x13, 0x9d, 0xe3, 0xe4,
+	0x81, 0xf6, 0x15, 0x0c, 0x5f, 0x73, 0x64, 0x12, 0x33, 0x57, 0x5a, 0x84, 0x9c, 0x43, 0x47, 0xc7,
+	0xa8, 0xd9, 0x1b, 0xb8, 0xb2, 0x38, 0x7b, 0x04, 0x87, 0x15, 0xaa, 0xb4, 0xb1, 0xa4, 0xc6, 0x1b,
+	0x5c, 0xe2, 0x3f, 0xaa, 0x51, 0xa1, 0xd2, 0x35, 0x9e, 0xc2, 0xa8, 0xf0, 0x28, 0x37, 0x92, 0xc9,
+	0xfc, 0xbd, 0x3e, 0x00, 0xad, 0xbb, 0xf4, 0x9b, 0x4d, 0x61, 0x47, 0x24, 0x06, 0xfd, 0x60, 0x47,
+	0x0f, 0x34, 0x90, 0x26, 0xa5, 0xa1, 0xf6, 0xef, 0x16, 0xf4, 0x8b, 0xf6, 0x47, 0xcc, 0x41, 0x28,
+	0x74, 0xfc, 0x70, 0xce, 0x51, 0x08, 0xb5, 0x41, 0x6d, 0x27, 0x83, 0xe4, 0x09, 0xec, 0x62, 0xea,
+	0x30, 0x95, 0x43, 0xa3, 0x24, 0xc3, 0xe3, 0x51, 0x1c, 0xa3, 0xa7, 0xb6, 0xa8, 0xed, 0x64, 0x90,
+	0x4c, 0x80, 0x2c, 0x99, 0x90, 0xb7, 0xdc, 0x97, 0xf8, 0xd1, 0x0f, 0x50, 0x48, 0x16, 0xc4, 0x6a,
+	0xaf, 0x4c, 0x67, 0x83, 0x87, 0x1c, 0x41, 0x2f, 0xb1, 0xbe, 0xe5, 0x3c, 0xe2, 0x7a, 0xdb, 0xd6,
+	0x86, 0x8c, 0x4d, 0x81, 0x35, 0x5b, 0x67, 0xcd, 0x56, 0xf6, 0x10, 0x1b, 0xfa, 0x1c, 0x25, 0x5f,
+	0x5d, 0x4a, 0x89, 0x41, 0x2c, 0x69, 0xf7, 0xd8, 0x18, 0x0f, 0x9c, 0x92, 0x2d, 0x39, 0x8a, 0xe4,
+	0xe9, 0x90, 0xf6, 0xd2, 0xa3, 0x50, 0x20, 0x99, 0x34, 0x60, 0xdf, 0xaf, 0xd9, 0x9c, 0x82, 0x62,
+	0xd7, 0xc8, 0xbe, 0x86, 0x51, 0xfe, 0xbc, 0x1c, 0x59, 0xf0, 0x77, 0x1b, 0xf3, 0x12, 0x68, 0x9d,
+	0x4d, 0x7f, 0xfd, 0x23, 0xe8, 0x89, 0xcc, 0xa8, 0x08, 0xbb, 0xce, 0xda, 0x60, 0xbf, 0x83, 0x83,
+	0x9b, 0x55, 0xe8, 0x56, 0xce, 0xef, 0x71, 0x67, 0x36, 0x83, 0x61, 0x99, 0x4b, 0x77, 0x90, 0x08,
+	0x8c, 0xe7, 0x61, 0x2a, 0x30, 0x03, 0x27, 0x05, 0xea, 0x5b, 0xab, 0x2d, 0xf7, 0xd4, 0x76, 0x0c,
+	0x9c, 0x0c, 0x26, 0xd2, 0xc3, 0xf1, 0x0b, 0xba, 0x89, 0xcb, 0x54, 0xae, 0x1c, 0xdb, 0x67, 0x30,
+	0xbc, 0x65, 0xd2, 0x5d, 0x54, 0x1b, 0xa6, 0xd0, 0xd1, 0x8a, 0xa7, 0x65, 0x2c, 0x83, 0xf6, 0x4f,
+	0x03, 0x0e, 0x2b, 0x29, 0xba, 0x2f, 0x0b, 0xba, 0x22, 0x64, 0xb1, 0x58, 0x44, 0x52, 0x3f, 0x4c,
+	0x8e, 0xc9, 0x8b, 0xac, 0xe7, 0x56, 0xf3, 0xf4, 0x7a, 0x98, 0xf3, 0xf5, 0x30, 0x66, 0x73, 0x42,
+	0x16, 0x37, 0xfd, 0xd5, 0x86, 0xce, 0x65, 0xda, 0x23, 0xb9, 0x83, 0x7e, 0x51, 0xed, 0xc8, 0xb3,
+	0x6a, 0xf6, 0x06, 0x89, 0xb4, 0x4e, 0x9a, 0x83, 0xb4, 0x66, 0xfc, 0x47, 0x3e, 0xc1, 0xa0, 0x24,
+	0x59, 0xa4, 0x96, 0xb8, 0x49, 0x1c, 0xad, 0xd3, 0x2d, 0x51, 0x45, 0xfe, 0x92, 0x5c, 0xd5, 0xf9,
+	0x37, 0x09, 0xa3, 0x75, 0xba, 0x25, 0x2a, 0xe7, 0x9f, 0xc3, 0x7e, 0x55, 0xda, 0xc8, 0xf3, 0x86,
+	0xd9, 0x8b, 0xba, 0x68, 0x8d, 0xb7, 0x07, 0x16, 0x0b, 0x55, 0xaf, 0xa8, 0x5e, 0xe8, 0x81, 0xab,
+	0xb5, 0xc6, 0xdb, 0x03, 0xf3, 0x42, 0x77, 0xd0, 0x2f, 0x1e, 0x4a, 0xfd, 0x73, 0x6f, 0x38, 0x49,
+	0xeb, 0xa4, 0x39, 0x28, 0x23, 0x9f, 0x06, 0xd0, 0xbb, 0x71, 0x17, 0xe8, 0xdd, 0x2f, 0x91, 0x93,
+	0xcf, 0x30, 0x28, 0xed, 0x7e, 0xfd, 0xdb, 0x6c, 0xba, 0x26, 0xeb, 0x74, 0x4b, 0x54, 0x56, 0xec,
+	0xcc, 0x98, 0xed, 0xaa, 0x3f, 0x18, 0x17, 0x7f, 0x06, 0x00, 0x98, 0x87, 0x60, 0xd8, 0x71, 0x08,
+	0x00, 0x00,
 }
@@ -24,3 +24,9 @@ func (d *OneToOne) Next() *loggregator_v2.Envelope {
 	data := d.d.Next()
 	return (*loggregator_v2.Envelope)(data)
 }
+
+// TryNext returns the next envelope if one is available. It does not block.
+func (d *OneToOne) TryNext() (*loggregator_v2.Envelope, bool) {
+	data, ok := d.d.TryNext()
+	return (*loggregator_v2.Envelope)(data), ok
+}
@@ -0,0 +1,46 @@
+package diodes
+
+import (
+	"time"
+
+	gendiodes "code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+)
+
+// TimedOneToOne diode is optimized for a single writer and a single reader.
+// It remembers when each envelope was set so that the reader can tell how
+// long it waited.
+type TimedOneToOne struct {
+	d *gendiodes.OneToOne
+}
+
+type timedEnvelope struct {
+	env *loggregator_v2.Envelope
+	set time.Time
+}
+
+func NewTimedOneToOne(size int, alerter gendiodes.Alerter) *TimedOneToOne {
+	return &TimedOneToOne{
+		d: gendiodes.NewOneToOne(size, alerter),
+	}
+}
+
+// Set adds the envelope to the diode, along with the current time.
+func (d *TimedOneToOne) Set(data *loggregator_v2.Envelope) {
+	d.d.Set(gendiodes.GenericDataType(&timedEnvelope{
+		env: data,
+		set: time.Now(),
+	}))
+}
+
+// TryNext returns the next envelope and when it was set if one is available.
+// It does not block.
+func (d *TimedOneToOne) TryNext() (*loggregator_v2.Envelope, time.Time, bool) {
+	data, ok := d.d.TryNext()
+	if !ok {
+		return nil, time.Time{}, false
+	}
+
+	te := (*timedEnvelope)(data)
+	return te.env, te.set, true
+}
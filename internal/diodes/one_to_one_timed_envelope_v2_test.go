@@ -0,0 +1,35 @@
+package diodes_test
+
+import (
+	"time"
+
+	gendiodes "code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/internal/diodes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TimedOneToOne", func() {
+	It("returns the envelopes with the time they were set", func() {
+		d := diodes.NewTimedOneToOne(5, gendiodes.AlertFunc(func(int) {}))
+		env := &loggregator_v2.Envelope{SourceId: "some-id"}
+
+		before := time.Now()
+		d.Set(env)
+		after := time.Now()
+
+		next, set, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(next).To(Equal(env))
+		Expect(set).To(BeTemporally(">=", before))
+		Expect(set).To(BeTemporally("<=", after))
+	})
+
+	It("reports when no envelope is available", func() {
+		d := diodes.NewTimedOneToOne(5, gendiodes.AlertFunc(func(int) {}))
+
+		_, _, ok := d.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+})
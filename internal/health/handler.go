@@ -4,15 +4,17 @@ package health
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"sync"
+
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 )
 
 // The Health handler will report the number of drains back to user.
 type Health struct {
 	counts map[string]int
 	mu     sync.RWMutex
+	logger *logging.Logger
 }
 
 // NewHealth returns a new Health handler.
@@ -31,7 +33,7 @@ func (h *Health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.mu.RUnlock()
 
 	if err != nil {
-		log.Printf("unable to marshal counts: %s", err)
+		h.logger.Errorf("unable to marshal counts: %s", err)
 	}
 	w.Write(jsonCounts)
 }
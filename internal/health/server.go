@@ -1,16 +1,50 @@
 package health
 
 import (
-	"log"
 	"net"
 	"net/http"
 	"time"
+
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 )
 
-func StartServer(h *Health, addr string) string {
+type serverConfig struct {
+	router *http.ServeMux
+	logger *logging.Logger
+}
+
+// ServerOption allows the health server to be customized.
+type ServerOption func(*serverConfig)
+
+// WithHandler returns a ServerOption that serves the handler on the given
+// path next to the health endpoint.
+func WithHandler(path string, handler http.Handler) ServerOption {
+	return func(c *serverConfig) {
+		c.router.Handle(path, handler)
+	}
+}
+
+// WithLogger returns a ServerOption that writes the logs of the health
+// server to l. Logs are discarded by default, except for the reason the
+// process exits.
+func WithLogger(l *logging.Logger) ServerOption {
+	return func(c *serverConfig) {
+		c.logger = l
+	}
+}
+
+// StartServer serves the health endpoint on addr and returns the address it
+// is listening on. The health handler logs to the logger of the server.
+func StartServer(h *Health, addr string, opts ...ServerOption) string {
 	router := http.NewServeMux()
 	router.Handle("/health", h)
 
+	c := &serverConfig{router: router}
+	for _, o := range opts {
+		o(c)
+	}
+	h.logger = c.logger
+
 	server := http.Server{
 		Addr:         addr,
 		ReadTimeout:  5 * time.Second,
@@ -20,12 +54,12 @@ func StartServer(h *Health, addr string) string {
 
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
-		log.Fatalf("Unable to setup Health endpoint (%s): %s", addr, err)
+		c.logger.Fatalf("unable to setup health endpoint (%s): %s", addr, err)
 	}
 
 	go func() {
-		log.Printf("Health endpoint is listening on %s", lis.Addr().String())
-		log.Fatalf("Health server closing: %s", server.Serve(lis))
+		c.logger.Infof("health endpoint is listening on %s", lis.Addr().String())
+		c.logger.Fatalf("health server closing: %s", server.Serve(lis))
 	}()
 	return lis.Addr().String()
 }
@@ -0,0 +1,181 @@
+// Package logging writes structured, leveled logs as JSON lines.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log line.
+type Level int
+
+// Levels from the most to the least verbose.
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+}
+
+// String returns the name of the level.
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel returns the level with the given name: debug, info, warn or
+// error.
+func ParseLevel(name string) (Level, error) {
+	for l, n := range levelNames {
+		if strings.EqualFold(name, n) {
+			return l, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown log level: %q", name)
+}
+
+// Fields are added to every line written by a Logger.
+type Fields map[string]interface{}
+
+// BindingFields returns the fields that identify a binding: the app ID and
+// the host and scheme of the drain. The credentials of the drain are left
+// out.
+func BindingFields(appID, drain string) Fields {
+	fields := Fields{"app_id": appID}
+	if u, err := url.Parse(drain); err == nil {
+		fields["drain_host"] = u.Host
+		fields["scheme"] = u.Scheme
+	}
+
+	return fields
+}
+
+// Logger writes log lines at or above its level as JSON objects with a
+// timestamp, level, message and its fields. It is safe to use a nil
+// *Logger, which writes nothing.
+type Logger struct {
+	out    *output
+	level  Level
+	fields Fields
+}
+
+// output serializes writes from a Logger and the loggers derived from it.
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// New returns a Logger that writes lines at or above level to w.
+func New(w io.Writer, level Level) *Logger {
+	return &Logger{
+		out:   &output{w: w},
+		level: level,
+	}
+}
+
+// With returns a Logger that adds the fields to every line, in addition to
+// the fields of l.
+func (l *Logger) With(fields Fields) *Logger {
+	if l == nil {
+		return nil
+	}
+
+	merged := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	return &Logger{
+		out:    l.out,
+		level:  l.level,
+		fields: merged,
+	}
+}
+
+// Enabled reports whether lines at the level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return l != nil && level >= l.level
+}
+
+// Debugf writes a line at debug level.
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.logf(DebugLevel, format, args...)
+}
+
+// Infof writes a line at info level.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf(InfoLevel, format, args...)
+}
+
+// Warnf writes a line at warn level.
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logf(WarnLevel, format, args...)
+}
+
+// Errorf writes a line at error level.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logf(ErrorLevel, format, args...)
+}
+
+// Fatalf writes a line at error level and exits the process with status
+// code 1. A nil *Logger writes the line to stderr so that the reason the
+// process exited is not lost.
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	if l == nil {
+		l = New(os.Stderr, ErrorLevel)
+	}
+
+	l.logf(ErrorLevel, format, args...)
+	os.Exit(1)
+}
+
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	line := make(map[string]interface{}, len(l.fields)+3)
+	for k, v := range l.fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		line[k] = v
+	}
+	line["timestamp"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line["level"] = level.String()
+	line["message"] = fmt.Sprintf(format, args...)
+
+	b, err := json.Marshal(line)
+	if err != nil {
+		b, _ = json.Marshal(map[string]string{
+			"timestamp": line["timestamp"].(string),
+			"level":     ErrorLevel.String(),
+			"message":   fmt.Sprintf("unable to marshal log line: %s", err),
+		})
+	}
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+
+	l.out.w.Write(append(b, '\n'))
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import "code.cloudfoundry.org/go-loggregator/pulseemitter"
+
+// GaugeClient creates gauge metrics.
+type GaugeClient interface {
+	NewGaugeMetric(name, unit string, opts ...pulseemitter.MetricOption) pulseemitter.GaugeMetric
+}
+
+// Client creates counter and gauge metrics.
+type Client interface {
+	GaugeClient
+	NewCounterMetric(name string, opts ...pulseemitter.MetricOption) pulseemitter.CounterMetric
+}
+
+// FanOutGaugeClient creates gauges in several clients at once. It is used by
+// components that only create gauges.
+type FanOutGaugeClient struct {
+	clients []GaugeClient
+}
+
+// NewFanOutGaugeClient returns a FanOutGaugeClient that creates every gauge
+// in each of the clients.
+func NewFanOutGaugeClient(clients ...GaugeClient) *FanOutGaugeClient {
+	return &FanOutGaugeClient{
+		clients: clients,
+	}
+}
+
+// NewGaugeMetric returns a gauge that sets the gauges of the same name in
+// every client.
+func (c *FanOutGaugeClient) NewGaugeMetric(name, unit string, opts ...pulseemitter.MetricOption) pulseemitter.GaugeMetric {
+	gauges := make(fanOutGauge, 0, len(c.clients))
+	for _, client := range c.clients {
+		gauges = append(gauges, client.NewGaugeMetric(name, unit, opts...))
+	}
+
+	return gauges
+}
+
+// FanOutClient creates metrics in several clients at once, e.g. to send a
+// metric to Loggregator and expose it to Prometheus.
+type FanOutClient struct {
+	*FanOutGaugeClient
+	clients []Client
+}
+
+// NewFanOutClient returns a FanOutClient that creates every metric in each
+// of the clients.
+func NewFanOutClient(clients ...Client) *FanOutClient {
+	gaugeClients := make([]GaugeClient, 0, len(clients))
+	for _, c := range clients {
+		gaugeClients = append(gaugeClients, c)
+	}
+
+	return &FanOutClient{
+		FanOutGaugeClient: NewFanOutGaugeClient(gaugeClients...),
+		clients:           clients,
+	}
+}
+
+// NewCounterMetric returns a counter that increments the counters of the
+// same name in every client.
+func (c *FanOutClient) NewCounterMetric(name string, opts ...pulseemitter.MetricOption) pulseemitter.CounterMetric {
+	counters := make(fanOutCounter, 0, len(c.clients))
+	for _, client := range c.clients {
+		counters = append(counters, client.NewCounterMetric(name, opts...))
+	}
+
+	return counters
+}
+
+type fanOutCounter []pulseemitter.CounterMetric
+
+// Increment increments every counter.
+func (f fanOutCounter) Increment(n uint64) {
+	for _, c := range f {
+		c.Increment(n)
+	}
+}
+
+// Emit emits every counter.
+func (f fanOutCounter) Emit(lc pulseemitter.LogClient) {
+	for _, c := range f {
+		c.Emit(lc)
+	}
+}
+
+type fanOutGauge []pulseemitter.GaugeMetric
+
+// Set sets every gauge.
+func (f fanOutGauge) Set(n float64) {
+	for _, g := range f {
+		g.Set(n)
+	}
+}
+
+// Emit emits every gauge.
+func (f fanOutGauge) Emit(lc pulseemitter.LogClient) {
+	for _, g := range f {
+		g.Emit(lc)
+	}
+}
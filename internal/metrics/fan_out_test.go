@@ -0,0 +1,49 @@
+package metrics_test
+
+import (
+	"code.cloudfoundry.org/scalable-syslog/internal/metrics"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FanOutClient", func() {
+	var (
+		first  *testhelper.SpyMetricClient
+		second *testhelper.SpyMetricClient
+		client *metrics.FanOutClient
+	)
+
+	BeforeEach(func() {
+		first = testhelper.NewMetricClient()
+		second = testhelper.NewMetricClient()
+		client = metrics.NewFanOutClient(first, second)
+	})
+
+	It("increments the counters of every client", func() {
+		client.NewCounterMetric("egress").Increment(2)
+
+		Expect(first.GetMetric("egress").Delta()).To(Equal(uint64(2)))
+		Expect(second.GetMetric("egress").Delta()).To(Equal(uint64(2)))
+	})
+
+	It("sets the gauges of every client", func() {
+		client.NewGaugeMetric("drains", "count").Set(4)
+
+		Expect(first.GetMetric("drains").GaugeValue()).To(Equal(4.0))
+		Expect(second.GetMetric("drains").GaugeValue()).To(Equal(4.0))
+	})
+})
+
+var _ = Describe("FanOutGaugeClient", func() {
+	It("sets the gauges of every client", func() {
+		first := testhelper.NewMetricClient()
+		second := testhelper.NewMetricClient()
+		client := metrics.NewFanOutGaugeClient(first, second)
+
+		client.NewGaugeMetric("drains", "count").Set(4)
+
+		Expect(first.GetMetric("drains").GaugeValue()).To(Equal(4.0))
+		Expect(second.GetMetric("drains").GaugeValue()).To(Equal(4.0))
+	})
+})
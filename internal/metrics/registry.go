@@ -0,0 +1,292 @@
+// Package metrics exposes the metrics of a component to Prometheus next to
+// the metrics sent to Loggregator.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+)
+
+// labelEscaper escapes label values for the text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// versionTag is the tag pulseemitter.WithVersion sets. It describes the
+// envelope rather than the metric and is not exposed as a label.
+const versionTag = "metric_version"
+
+// Registry keeps the current value of counters and gauges and serves them in
+// the Prometheus text exposition format. Metrics are named after the
+// namespace and the name they were created with. Their tags become labels.
+type Registry struct {
+	namespace string
+
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+type family struct {
+	name   string
+	kind   string
+	series map[string]*series
+}
+
+type series struct {
+	labels string
+	bits   uint64
+	hist   *histogram
+}
+
+type histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the buckets of a
+// histogram of network latencies.
+var DefaultLatencyBuckets = []float64{
+	0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// DefaultLagBuckets are the upper bounds, in seconds, of the buckets of a
+// histogram of how far behind a pipeline is.
+var DefaultLagBuckets = []float64{
+	0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900,
+}
+
+// NewRegistry returns a Registry that prefixes the name of every metric with
+// namespace.
+func NewRegistry(namespace string) *Registry {
+	return &Registry{
+		namespace: namespace,
+		families:  make(map[string]*family),
+	}
+}
+
+// NewCounterMetric returns a counter that is exposed as
+// <namespace>_<name>_total. Counters created with the same name and tags
+// share their value.
+func (r *Registry) NewCounterMetric(name string, opts ...pulseemitter.MetricOption) pulseemitter.CounterMetric {
+	return &counter{
+		series: r.series(sanitize(r.namespace+"_"+name+"_total"), "counter", opts),
+	}
+}
+
+// NewGaugeMetric returns a gauge that is exposed as <namespace>_<name>.
+func (r *Registry) NewGaugeMetric(name, unit string, opts ...pulseemitter.MetricOption) pulseemitter.GaugeMetric {
+	return &gauge{
+		series: r.series(sanitize(r.namespace+"_"+name), "gauge", opts),
+	}
+}
+
+// NewHistogramMetric returns a histogram that is exposed as
+// <namespace>_<name> with a bucket for each of the upper bounds in buckets,
+// which must be sorted. Histograms created with the same name and tags share
+// their buckets.
+func (r *Registry) NewHistogramMetric(name string, buckets []float64, opts ...pulseemitter.MetricOption) *Histogram {
+	s := r.series(sanitize(r.namespace+"_"+name), "histogram", opts)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s.hist == nil {
+		s.hist = &histogram{
+			buckets: buckets,
+			counts:  make([]uint64, len(buckets)),
+		}
+	}
+
+	return &Histogram{
+		histogram: s.hist,
+	}
+}
+
+// ServeHTTP writes the current value of every metric.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(r.expose())
+}
+
+func (r *Registry) series(name, kind string, opts []pulseemitter.MetricOption) *series {
+	tags := make(map[string]string)
+	for _, o := range opts {
+		o(tags)
+	}
+	labels := formatLabels(tags)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{
+			name:   name,
+			kind:   kind,
+			series: make(map[string]*series),
+		}
+		r.families[name] = f
+	}
+
+	s, ok := f.series[labels]
+	if !ok {
+		s = &series{labels: labels}
+		f.series[labels] = s
+	}
+
+	return s
+}
+
+func (r *Registry) expose() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", f.name, f.kind)
+
+		labels := make([]string, 0, len(f.series))
+		for l := range f.series {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+
+		for _, l := range labels {
+			s := f.series[l]
+			if s.hist != nil {
+				s.hist.write(&buf, f.name, l)
+				continue
+			}
+			fmt.Fprintf(&buf, "%s%s %s\n", f.name, l, s.value(f.kind))
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func (s *series) value(kind string) string {
+	bits := atomic.LoadUint64(&s.bits)
+	if kind == "counter" {
+		return strconv.FormatUint(bits, 10)
+	}
+
+	return strconv.FormatFloat(math.Float64frombits(bits), 'g', -1, 64)
+}
+
+type counter struct {
+	*series
+}
+
+// Increment adds n to the counter.
+func (c *counter) Increment(n uint64) {
+	atomic.AddUint64(&c.bits, n)
+}
+
+// Emit does nothing, the counter is read when it is exposed.
+func (c *counter) Emit(pulseemitter.LogClient) {}
+
+type gauge struct {
+	*series
+}
+
+// Set sets the value of the gauge.
+func (g *gauge) Set(n float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(n))
+}
+
+// Emit does nothing, the gauge is read when it is exposed.
+func (g *gauge) Emit(pulseemitter.LogClient) {}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	*histogram
+}
+
+// Observe adds v to the histogram.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// write writes the cumulative buckets, sum and count of the histogram.
+func (h *histogram) write(buf *bytes.Buffer, name, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, upper := range h.buckets {
+		le := strconv.FormatFloat(upper, 'g', -1, 64)
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", name, withLabel(labels, "le", le), h.counts[i])
+	}
+	fmt.Fprintf(buf, "%s_bucket%s %d\n", name, withLabel(labels, "le", "+Inf"), h.count)
+	fmt.Fprintf(buf, "%s_sum%s %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(buf, "%s_count%s %d\n", name, labels, h.count)
+}
+
+// withLabel adds a label to a formatted label set.
+func withLabel(labels, name, value string) string {
+	pair := fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(value))
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+// formatLabels returns the tags as a Prometheus label set, sorted by name.
+func formatLabels(tags map[string]string) string {
+	delete(tags, versionTag)
+	if len(tags) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, sanitize(name), labelEscaper.Replace(tags[name])))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sanitize replaces the characters Prometheus does not allow in metric and
+// label names with underscores.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,111 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	"code.cloudfoundry.org/scalable-syslog/internal/metrics"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+	var (
+		recorder *httptest.ResponseRecorder
+		registry *metrics.Registry
+	)
+
+	BeforeEach(func() {
+		recorder = httptest.NewRecorder()
+		registry = metrics.NewRegistry("component")
+	})
+
+	It("exposes counters and gauges in the text format", func() {
+		registry.NewCounterMetric("ingress").Increment(3)
+		registry.NewGaugeMetric("drains", "count").Set(2.5)
+
+		registry.ServeHTTP(recorder, new(http.Request))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(
+			Equal("text/plain; version=0.0.4; charset=utf-8"),
+		)
+		Expect(recorder.Body.String()).To(Equal(
+			"# TYPE component_drains gauge\n" +
+				"component_drains 2.5\n" +
+				"# TYPE component_ingress_total counter\n" +
+				"component_ingress_total 3\n",
+		))
+	})
+
+	It("exposes tags as sorted labels", func() {
+		registry.NewCounterMetric("writes",
+			pulseemitter.WithVersion(2, 0),
+			pulseemitter.WithTags(map[string]string{
+				"result":   "success",
+				"protocol": "syslog-tls",
+			}),
+		).Increment(1)
+		registry.NewCounterMetric("writes",
+			pulseemitter.WithTags(map[string]string{
+				"result":   "failure",
+				"protocol": "syslog-tls",
+			}),
+		).Increment(2)
+
+		registry.ServeHTTP(recorder, new(http.Request))
+
+		Expect(recorder.Body.String()).To(Equal(
+			"# TYPE component_writes_total counter\n" +
+				`component_writes_total{protocol="syslog-tls",result="failure"} 2` + "\n" +
+				`component_writes_total{protocol="syslog-tls",result="success"} 1` + "\n",
+		))
+	})
+
+	It("shares the value of counters with the same name and tags", func() {
+		tags := pulseemitter.WithTags(map[string]string{"protocol": "https"})
+		registry.NewCounterMetric("egress", tags).Increment(1)
+		registry.NewCounterMetric("egress", tags).Increment(2)
+
+		registry.ServeHTTP(recorder, new(http.Request))
+
+		Expect(recorder.Body.String()).To(ContainSubstring(
+			`component_egress_total{protocol="https"} 3` + "\n",
+		))
+	})
+
+	It("exposes histograms with cumulative buckets", func() {
+		h := registry.NewHistogramMetric("write_duration_seconds", []float64{0.1, 1},
+			pulseemitter.WithTags(map[string]string{"protocol": "https"}),
+		)
+		h.Observe(0.05)
+		h.Observe(0.5)
+		h.Observe(2)
+
+		registry.ServeHTTP(recorder, new(http.Request))
+
+		Expect(recorder.Body.String()).To(Equal(
+			"# TYPE component_write_duration_seconds histogram\n" +
+				`component_write_duration_seconds_bucket{protocol="https",le="0.1"} 1` + "\n" +
+				`component_write_duration_seconds_bucket{protocol="https",le="1"} 2` + "\n" +
+				`component_write_duration_seconds_bucket{protocol="https",le="+Inf"} 3` + "\n" +
+				`component_write_duration_seconds_sum{protocol="https"} 2.55` + "\n" +
+				`component_write_duration_seconds_count{protocol="https"} 3` + "\n",
+		))
+	})
+
+	It("sanitizes names and escapes label values", func() {
+		registry.NewGaugeMetric("drain-bindings", "count",
+			pulseemitter.WithTags(map[string]string{
+				"source.id": "a \"quoted\"\nvalue\\",
+			}),
+		).Set(1)
+
+		registry.ServeHTTP(recorder, new(http.Request))
+
+		Expect(recorder.Body.String()).To(ContainSubstring(
+			`component_drain_bindings{source_id="a \"quoted\"\nvalue\\"} 1` + "\n",
+		))
+	})
+})
@@ -2,11 +2,12 @@ package app
 
 import (
 	"fmt"
-	"log"
 	"net"
+	"os"
 	"time"
 
 	envstruct "code.cloudfoundry.org/go-envstruct"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/ingress"
 )
 
@@ -20,6 +21,12 @@ type Config struct {
 	APISkipCertVerify  bool          `env:"API_SKIP_CERT_VERIFY"`
 	APIPollingInterval time.Duration `env:"API_POLLING_INTERVAL"`
 	APIBatchSize       int           `env:"API_BATCH_SIZE"`
+	HandoffTimeout     time.Duration `env:"HANDOFF_TIMEOUT"`
+	WatchHostport      string        `env:"WATCH_HOSTPORT"`
+	WatchCertFile      string        `env:"WATCH_CERT_FILE_PATH"`
+	WatchKeyFile       string        `env:"WATCH_KEY_FILE_PATH"`
+	MaxSkippedTerms    int           `env:"MAX_SKIPPED_TERMS"`
+	MaxPartialFetches  int           `env:"MAX_PARTIAL_FETCHES"`
 
 	CAFile            string `env:"CA_FILE_PATH,        required"`
 	CertFile          string `env:"CERT_FILE_PATH,      required"`
@@ -36,6 +43,7 @@ type Config struct {
 	MetricEmitterInterval time.Duration `env:"METRIC_EMITTER_INTERVAL"`
 	HealthHostport        string        `env:"HEALTH_HOSTPORT"`
 	PprofHostport         string        `env:"PPROF_HOSTPORT"`
+	LogLevel              string        `env:"LOG_LEVEL"`
 }
 
 // LoadConfig will load and validate the config from the current environment.
@@ -50,15 +58,33 @@ func LoadConfig(args []string) (*Config, error) {
 		MetricEmitterInterval: time.Minute,
 		Blacklist:             &ingress.BlacklistRanges{},
 		APIBatchSize:          1000,
+		HandoffTimeout:        time.Minute,
+		MaxSkippedTerms:       3,
+		MaxPartialFetches:     3,
+		LogLevel:              "info",
 	}
+	logger := logging.New(os.Stderr, logging.InfoLevel)
 
 	if err := envstruct.Load(&cfg); err != nil {
-		log.Fatalf("failed to load config from environment: %s", err)
+		logger.Fatalf("failed to load config from environment: %s", err)
 	}
 
 	hostports, err := resolveAddrs(cfg.AdapterAddrs, cfg.AdapterPort)
 	if err != nil {
-		log.Fatalf("failed to resolve adapter addrs: %s", err)
+		logger.Fatalf("failed to resolve adapter addrs: %s", err)
+	}
+
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		logger.Fatalf("invalid LOG_LEVEL: %s", err)
+	}
+	if cfg.WatchHostport != "" && (cfg.WatchCertFile == "" || cfg.WatchKeyFile == "") {
+		logger.Fatalf("WATCH_CERT_FILE_PATH and WATCH_KEY_FILE_PATH are required to serve WATCH_HOSTPORT")
+	}
+	if cfg.MaxSkippedTerms < 0 {
+		logger.Fatalf("MAX_SKIPPED_TERMS must not be negative, got %d", cfg.MaxSkippedTerms)
+	}
+	if cfg.MaxPartialFetches < 0 {
+		logger.Fatalf("MAX_PARTIAL_FETCHES must not be negative, got %d", cfg.MaxPartialFetches)
 	}
 	cfg.AdapterAddrs = hostports
 
@@ -2,12 +2,17 @@ package app
 
 import (
 	"crypto/tls"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	loggregator "code.cloudfoundry.org/go-loggregator"
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
 	"code.cloudfoundry.org/scalable-syslog/internal/health"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
+	"code.cloudfoundry.org/scalable-syslog/internal/metrics"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/egress"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/ingress"
 
@@ -20,18 +25,26 @@ import (
 // and/or streaming events from the cloud controller about syslog drains and
 // updating a pool of adapters to service those drains.
 type Scheduler struct {
-	apiURL           string
-	apiBatchSize     int
-	adapterAddrs     []string
-	adapterTLSConfig *tls.Config
-	healthAddr       string
-	health           *health.Health
-	emitter          Emitter
-	client           *http.Client
-	interval         time.Duration
-	fetcher          *ingress.FilteredBindingFetcher
-	logClient        LogClient
-	blacklist        *ingress.BlacklistRanges
+	mu                sync.Mutex
+	apiURL            string
+	apiBatchSize      int
+	adapterAddrs      []string
+	adapterTLSConfig  *tls.Config
+	healthAddr        string
+	watchAddr         string
+	watchTLSConfig    *tls.Config
+	health            *health.Health
+	emitter           Emitter
+	client            *http.Client
+	interval          time.Duration
+	handoffTimeout    time.Duration
+	maxSkippedTerms   int
+	maxPartialFetches int
+	fetcher           *ingress.FilteredBindingFetcher
+	logClient         LogClient
+	blacklist         *ingress.BlacklistRanges
+	registry          *metrics.Registry
+	logger            *logging.Logger
 }
 
 // Emitter sends gauge metrics
@@ -61,11 +74,14 @@ func NewScheduler(
 		healthAddr:       ":8080",
 		client:           http.DefaultClient,
 		interval:         15 * time.Second,
+		handoffTimeout:   time.Minute,
+		maxSkippedTerms:  3,
 		blacklist:        &ingress.BlacklistRanges{},
 		health:           health.NewHealth(),
 		logClient:        logClient,
-		emitter:          e,
+		registry:         metrics.NewRegistry("drain_scheduler"),
 	}
+	s.emitter = metrics.NewFanOutGaugeClient(e, s.registry)
 	for _, o := range opts {
 		o(s)
 	}
@@ -106,6 +122,52 @@ func WithAPIBatchSize(size int) func(*Scheduler) {
 	}
 }
 
+// WithLogger sets where the scheduler writes its logs. Logs are discarded by
+// default.
+func WithLogger(l *logging.Logger) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.logger = l
+	}
+}
+
+// WithHandoffTimeout sets how long a binding that moves to another adapter
+// is kept on its old adapter while the new adapter has not opened a stream
+// of its logs. It defaults to a minute.
+func WithHandoffTimeout(d time.Duration) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.handoffTimeout = d
+	}
+}
+
+// WithMaxSkippedTerms sets how many polling intervals in a row the
+// scheduler does not orchestrate because neither the bindings nor the
+// reachable adapters changed. It defaults to 3. Zero orchestrates on every
+// polling interval.
+func WithMaxSkippedTerms(n int) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.maxSkippedTerms = n
+	}
+}
+
+// WithMaxPartialFetches sets how many polls in a row only request the first
+// page of bindings when it was not modified, reusing the other pages. It
+// defaults to 0, which requests every page on every poll.
+func WithMaxPartialFetches(n int) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.maxPartialFetches = n
+	}
+}
+
+// WithWatchAddr sets the address for the gRPC server that streams the
+// bindings of each adapter to the adapter to bind to, and the TLS config it
+// serves with. The server is not started by default.
+func WithWatchAddr(addr string, tlsConfig *tls.Config) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.watchAddr = addr
+		s.watchTLSConfig = tlsConfig
+	}
+}
+
 // WithBlacklist sets the blacklist for the syslog IPs.
 func WithBlacklist(r *ingress.BlacklistRanges) func(*Scheduler) {
 	return func(s *Scheduler) {
@@ -130,9 +192,15 @@ func (s *Scheduler) setupIngress() {
 			Addr:      s.apiURL,
 			BatchSize: s.apiBatchSize,
 		},
+		ingress.WithMaxPartialFetches(s.maxPartialFetches),
 	)
 
-	s.fetcher = ingress.NewFilteredBindingFetcher(s.blacklist, fetcher, s.logClient)
+	s.fetcher = ingress.NewFilteredBindingFetcher(
+		s.blacklist,
+		fetcher,
+		s.logClient,
+		ingress.WithLogger(s.logger),
+	)
 }
 
 func (s *Scheduler) startEgress() {
@@ -147,11 +215,70 @@ func (s *Scheduler) startEgress() {
 	pool := egress.NewAdapterPool(s.adapterAddrs, s.health,
 		grpc.WithTransportCredentials(creds),
 		grpc.WithKeepaliveParams(kp),
+		egress.WithPoolLogger(s.logger),
+	)
+	syncerOpts := []egress.SyncerOption{egress.WithSyncerLogger(s.logger)}
+	if s.watchAddr != "" {
+		publisher := egress.NewPublisher(pool, egress.WithPublisherLogger(s.logger))
+		s.serveWatch(publisher)
+		syncerOpts = append(syncerOpts, egress.WithSyncerPublisher(publisher))
+	}
+
+	syncer := egress.NewSyncer(pool, syncerOpts...)
+	handoff := egress.NewHandoff(
+		syncer,
+		pool,
+		s.handoffTimeout,
+		egress.WithHandoffLogger(s.logger),
+	)
+	orchestrator := egress.NewOrchestrator(
+		pool,
+		s.fetcher,
+		handoff,
+		s.health,
+		s.emitter,
+		egress.WithLogger(s.logger),
+		egress.WithMaxSkippedTerms(s.maxSkippedTerms),
 	)
-	orchestrator := egress.NewOrchestrator(pool, s.fetcher, pool, s.health, s.emitter)
 	go orchestrator.Run(s.interval)
 }
 
+// serveWatch serves the bindings of each adapter to the adapters that watch
+// them.
+func (s *Scheduler) serveWatch(p *egress.Publisher) {
+	lis, err := net.Listen("tcp", s.watchAddr)
+	if err != nil {
+		s.logger.Fatalf("failed to listen: %s", err)
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(s.watchTLSConfig)),
+	)
+	v1.RegisterSchedulerServer(grpcServer, p)
+
+	s.mu.Lock()
+	s.watchAddr = lis.Addr().String()
+	s.mu.Unlock()
+
+	s.logger.Infof("watch server is listening on %s", lis.Addr().String())
+	go func() {
+		s.logger.Errorf("watch server closing: %s", grpcServer.Serve(lis))
+	}()
+}
+
+// WatchAddr returns the address the watch server listens on.
+func (s *Scheduler) WatchAddr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.watchAddr
+}
+
 func (s *Scheduler) serveHealth() string {
-	return health.StartServer(s.health, s.healthAddr)
+	return health.StartServer(
+		s.health,
+		s.healthAddr,
+		health.WithHandler("/metrics", s.registry),
+		health.WithLogger(s.logger),
+	)
 }
@@ -1,6 +1,7 @@
 package app_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -53,6 +54,32 @@ var _ = Describe("Scheduler", func() {
 			`))
 	})
 
+	It("exposes its metrics to Prometheus", func() {
+		dataSource := httptest.NewServer(&fakeCC{
+			results: results{
+				"9be15160-4845-4f05-b089-40e827ba61f1": appBindings{
+					Hostname: "org.space.name",
+					Drains:   []string{"syslog://1.1.1.1/"},
+				},
+			},
+		})
+		healthAddr, _ := startScheduler(dataSource.URL, 1, defaultOps())
+
+		f := func() string {
+			resp, err := http.Get(fmt.Sprintf("http://%s/metrics", healthAddr))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return string(body)
+		}
+		Eventually(f, 3*time.Second, 500*time.Millisecond).Should(And(
+			ContainSubstring("drain_scheduler_drains 1\n"),
+			ContainSubstring("drain_scheduler_adapters 1\n"),
+		))
+	})
+
 	It("ignores blacklisted syslog URLs", func() {
 		dataSource := httptest.NewServer(&fakeCC{
 			results: results{
@@ -172,6 +199,57 @@ var _ = Describe("Scheduler", func() {
 		}))
 	})
 
+	It("serves the bindings of each adapter with its own certificate", func() {
+		dataSource := httptest.NewServer(&fakeCC{
+			results: results{
+				"9be15160-4845-4f05-b089-40e827ba61f1": appBindings{
+					Hostname: "org.space.name",
+					Drains:   []string{"syslog://1.1.1.1/"},
+				},
+			},
+		})
+		watchTLSConfig, err := api.NewMutualTLSConfig(
+			Cert("scheduler.crt"),
+			Cert("scheduler.key"),
+			Cert("scalable-syslog-ca.crt"),
+			"",
+		)
+		Expect(err).ToNot(HaveOccurred())
+		scheduler, _, adapterAddrs := newScheduler(
+			dataSource.URL,
+			1,
+			append(defaultOps(), app.WithWatchAddr("localhost:0", watchTLSConfig)),
+		)
+		scheduler.Start()
+
+		adapterTLSConfig, err := api.NewMutualTLSConfig(
+			Cert("adapter.crt"),
+			Cert("adapter.key"),
+			Cert("scalable-syslog-ca.crt"),
+			"scheduler",
+		)
+		Expect(err).ToNot(HaveOccurred())
+		conn, err := grpc.Dial(
+			scheduler.WatchAddr(),
+			grpc.WithTransportCredentials(credentials.NewTLS(adapterTLSConfig)),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stream, err := v1.NewSchedulerClient(conn).WatchBindings(ctx, &v1.WatchBindingsRequest{
+			Adapter: adapterAddrs[0],
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		resp, err := stream.Recv()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Snapshot).To(BeTrue())
+		Expect(resp.Added).To(HaveLen(1))
+		Expect(resp.Added[0].Drain).To(Equal("syslog://1.1.1.1/"))
+	})
+
 	It("sends a binding to only two adapters", func() {
 		dataSource := httptest.NewServer(&fakeCC{
 			results: results{
@@ -296,7 +374,12 @@ func defaultOps() []app.SchedulerOption {
 }
 
 func startScheduler(dataSourceURL string, adapterCount int, opts []app.SchedulerOption) (string, []*spyAdapterServer) {
+	scheduler, spyAdapterServers, _ := newScheduler(dataSourceURL, adapterCount, opts)
 
+	return scheduler.Start(), spyAdapterServers
+}
+
+func newScheduler(dataSourceURL string, adapterCount int, opts []app.SchedulerOption) (*app.Scheduler, []*spyAdapterServer, []string) {
 	adapterTLSConfig, err := api.NewMutualTLSConfig(
 		Cert("adapter.crt"),
 		Cert("adapter.key"),
@@ -341,7 +424,8 @@ func startScheduler(dataSourceURL string, adapterCount int, opts []app.Scheduler
 		&spyLogClient{},
 		opts...,
 	)
-	return scheduler.Start(), spyAdapterServers
+
+	return scheduler, spyAdapterServers, adapterAddrs
 }
 
 type spyLogClient struct{}
@@ -4,6 +4,8 @@ import (
 	"sync"
 
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
 )
@@ -54,3 +56,26 @@ func (t *spyAdapterServer) DeleteBinding(c context.Context, r *v1.DeleteBindingR
 
 	return new(v1.DeleteBindingResponse), nil
 }
+
+func (t *spyAdapterServer) ListBindingStats(context.Context, *v1.ListBindingStatsRequest) (*v1.ListBindingStatsResponse, error) {
+	return new(v1.ListBindingStatsResponse), nil
+}
+
+// SyncBindings is not supported by the spy so that the scheduler creates and
+// deletes bindings one at a time.
+func (t *spyAdapterServer) SyncBindings(c context.Context, r *v1.SyncBindingsRequest) (*v1.SyncBindingsResponse, error) {
+	return nil, grpc.Errorf(codes.Unimplemented, "unknown method SyncBindings")
+}
+
+func (t *spyAdapterServer) BindingStreaming(c context.Context, r *v1.BindingStreamingRequest) (*v1.BindingStreamingResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, b := range t.Bindings {
+		if *b == *r.Binding {
+			return &v1.BindingStreamingResponse{Streaming: true}, nil
+		}
+	}
+
+	return new(v1.BindingStreamingResponse), nil
+}
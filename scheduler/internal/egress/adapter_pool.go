@@ -1,20 +1,42 @@
 package egress
 
 import (
-	"log"
-
 	"context"
+	"errors"
 
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
 )
 
+// ErrSyncUnsupported is returned by AdapterPool.Sync for adapters that do
+// not know the SyncBindings call.
+var ErrSyncUnsupported = errors.New("adapter does not support syncing bindings")
+
 type AdapterPool map[string]v1.AdapterClient
 
+// adapterConn is an AdapterClient that knows the state of its connection.
+type adapterConn struct {
+	v1.AdapterClient
+	conn *grpc.ClientConn
+}
+
+// NewAdapterPool dials every adapter. Adapters that cannot be dialed are
+// left out of the pool.
 func NewAdapterPool(addrs []string, h HealthEmitter, opts ...grpc.DialOption) AdapterPool {
 	pool := AdapterPool{}
 
+	var logger *logging.Logger
+	for _, o := range opts {
+		if l, ok := o.(poolLogger); ok {
+			logger = l.logger
+		}
+	}
+
 	for _, addr := range addrs {
 		_, ok := pool[addr]
 		if ok {
@@ -23,10 +45,13 @@ func NewAdapterPool(addrs []string, h HealthEmitter, opts ...grpc.DialOption) Ad
 
 		conn, err := grpc.Dial(addr, opts...)
 		if err != nil {
-			log.Printf("error dialing adapter: %v", err)
+			logger.With(logging.Fields{"adapter": addr}).Errorf("error dialing adapter: %v", err)
 			continue
 		}
-		pool[addr] = v1.NewAdapterClient(conn)
+		pool[addr] = adapterConn{
+			AdapterClient: v1.NewAdapterClient(conn),
+			conn:          conn,
+		}
 	}
 
 	if h != nil {
@@ -36,6 +61,36 @@ func NewAdapterPool(addrs []string, h HealthEmitter, opts ...grpc.DialOption) Ad
 	return pool
 }
 
+// WithPoolLogger returns a grpc.DialOption that makes NewAdapterPool log
+// the adapters it cannot dial to l. It does not change how adapters are
+// dialed. Logs are discarded by default.
+func WithPoolLogger(l *logging.Logger) grpc.DialOption {
+	return poolLogger{logger: l}
+}
+
+type poolLogger struct {
+	grpc.EmptyDialOption
+	logger *logging.Logger
+}
+
+// reachable returns the addresses of the adapters whose connection is not
+// failing. Adapters that do not know the state of their connection are
+// assumed to be reachable.
+func (p AdapterPool) reachable() map[interface{}]bool {
+	reachable := make(map[interface{}]bool, len(p))
+	for addr, client := range p {
+		if c, ok := client.(adapterConn); ok {
+			switch c.conn.GetState() {
+			case connectivity.TransientFailure, connectivity.Shutdown:
+				continue
+			}
+		}
+		reachable[addr] = true
+	}
+
+	return reachable
+}
+
 func (p AdapterPool) List(ctx context.Context, adapter interface{}) ([]interface{}, error) {
 	results, err := adapter.(v1.AdapterClient).ListBindings(ctx, &v1.ListBindingsRequest{})
 	if err != nil {
@@ -67,3 +122,43 @@ func (p AdapterPool) Remove(ctx context.Context, adapter, task interface{}) erro
 
 	return err
 }
+
+// Sync replaces the bindings of the adapter with the given bindings. It
+// returns how many of them the adapter rejected because it has the maximum
+// number of bindings.
+func (p AdapterPool) Sync(ctx context.Context, adapter interface{}, tasks []interface{}) (int, error) {
+	bindings := make([]*v1.Binding, 0, len(tasks))
+	for _, t := range tasks {
+		b := t.(v1.Binding)
+		bindings = append(bindings, &b)
+	}
+
+	resp, err := adapter.(v1.AdapterClient).SyncBindings(ctx, &v1.SyncBindingsRequest{
+		Bindings: bindings,
+	})
+	if s, ok := status.FromError(err); ok && s.Code() == codes.Unimplemented {
+		return 0, ErrSyncUnsupported
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return int(resp.Rejected), nil
+}
+
+// Streaming reports whether the adapter streams the logs of the binding.
+// Adapters that do not know the BindingStreaming call are assumed to.
+func (p AdapterPool) Streaming(ctx context.Context, adapter, task interface{}) (bool, error) {
+	b := task.(v1.Binding)
+	resp, err := adapter.(v1.AdapterClient).BindingStreaming(ctx, &v1.BindingStreamingRequest{
+		Binding: &b,
+	})
+	if s, ok := status.FromError(err); ok && s.Code() == codes.Unimplemented {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return resp.Streaming, nil
+}
@@ -93,6 +93,48 @@ var _ = Describe("AdapterPool", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(results).To(HaveLen(2))
 	})
+
+	It("reports whether an adapter streams a binding", func() {
+		addr, cleanup := startGRPCServer()
+		defer cleanup()
+
+		pool := egress.NewAdapterPool([]string{addr}, nil, grpc.WithInsecure())
+		b := v1.Binding{AppId: "some-app-id"}
+
+		streaming, err := pool.Streaming(context.Background(), pool[addr], b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(streaming).To(BeFalse())
+
+		err = pool.Add(context.Background(), pool[addr], b)
+		Expect(err).ToNot(HaveOccurred())
+
+		streaming, err = pool.Streaming(context.Background(), pool[addr], b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(streaming).To(BeTrue())
+	})
+
+	It("syncs the bindings of an adapter", func() {
+		addr, cleanup := startGRPCServer()
+		defer cleanup()
+
+		pool := egress.NewAdapterPool([]string{addr}, nil, grpc.WithInsecure())
+		err := pool.Add(context.Background(), pool[addr], v1.Binding{AppId: "removed"})
+		Expect(err).ToNot(HaveOccurred())
+
+		rejected, err := pool.Sync(context.Background(), pool[addr], []interface{}{
+			v1.Binding{AppId: "app-a"},
+			v1.Binding{AppId: "app-b"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rejected).To(Equal(0))
+
+		bindings, err := pool.List(context.Background(), pool[addr])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bindings).To(ConsistOf(
+			v1.Binding{AppId: "app-a"},
+			v1.Binding{AppId: "app-b"},
+		))
+	})
 })
 
 func startGRPCServer() (string, func()) {
@@ -0,0 +1,285 @@
+package egress
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
+)
+
+// defaultHandoffCallTimeout is how long a single call to an adapter made
+// while completing handoffs may take unless configured otherwise.
+const defaultHandoffCallTimeout = 10 * time.Second
+
+// StreamChecker reports whether an adapter streams the logs of a binding.
+type StreamChecker interface {
+	Streaming(ctx context.Context, adapter, binding interface{}) (bool, error)
+}
+
+// Handoff is a Communicator that moves bindings between adapters without a
+// gap in their logs. When a binding is removed from an adapter it is kept
+// there until every adapter that keeps or takes over the binding streams its
+// logs, or until the handoff times out. Removals are carried out by
+// CompleteTerm at the end of every term.
+type Handoff struct {
+	comm        Communicator
+	streams     StreamChecker
+	timeout     time.Duration
+	callTimeout time.Duration
+	logger      *logging.Logger
+
+	mu       sync.Mutex
+	holders  map[interface{}]map[interface{}]bool
+	removals map[removal]*pendingRemoval
+}
+
+type removal struct {
+	adapter interface{}
+	binding interface{}
+}
+
+type pendingRemoval struct {
+	since     time.Time
+	requested bool
+}
+
+// handoffCheck is a removal that was asked for in the last term, along with
+// the adapters that keep the binding.
+type handoffCheck struct {
+	removal
+	pending  *pendingRemoval
+	timedOut bool
+	keepers  []interface{}
+}
+
+// HandoffOption allows a Handoff to be customized.
+type HandoffOption func(*Handoff)
+
+// WithHandoffLogger writes the logs of the Handoff to l. Logs are discarded
+// by default.
+func WithHandoffLogger(l *logging.Logger) HandoffOption {
+	return func(h *Handoff) {
+		h.logger = l
+	}
+}
+
+// WithHandoffCallTimeout sets how long each call to an adapter made while
+// completing handoffs may take. It defaults to 10 seconds.
+func WithHandoffCallTimeout(d time.Duration) HandoffOption {
+	return func(h *Handoff) {
+		h.callTimeout = d
+	}
+}
+
+// NewHandoff returns a Handoff that lists, adds and removes bindings with c
+// and asks s whether adapters stream them. Bindings are removed anyway once
+// their removal has been pending for timeout.
+func NewHandoff(
+	c Communicator,
+	s StreamChecker,
+	timeout time.Duration,
+	opts ...HandoffOption,
+) *Handoff {
+	h := &Handoff{
+		comm:        c,
+		streams:     s,
+		timeout:     timeout,
+		callTimeout: defaultHandoffCallTimeout,
+		holders:     make(map[interface{}]map[interface{}]bool),
+		removals:    make(map[removal]*pendingRemoval),
+	}
+
+	for _, o := range opts {
+		o(h)
+	}
+
+	return h
+}
+
+// List returns the bindings of the adapter and remembers that the adapter
+// has them for the rest of the term.
+func (h *Handoff) List(ctx context.Context, adapter interface{}) ([]interface{}, error) {
+	bindings, err := h.comm.List(ctx, adapter)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, b := range bindings {
+		h.hold(adapter, b)
+	}
+
+	return bindings, nil
+}
+
+// Add adds the binding to the adapter. A pending removal of the binding from
+// the adapter is cancelled.
+func (h *Handoff) Add(ctx context.Context, adapter, binding interface{}) error {
+	err := h.comm.Add(ctx, adapter, binding)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.hold(adapter, binding)
+	delete(h.removals, removal{adapter: adapter, binding: binding})
+
+	return nil
+}
+
+// Remove marks the binding for removal from the adapter. The orchestrator
+// asks for the removal again in every term until it is carried out.
+func (h *Handoff) Remove(ctx context.Context, adapter, binding interface{}) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r := removal{adapter: adapter, binding: binding}
+	p, ok := h.removals[r]
+	if !ok {
+		p = &pendingRemoval{since: time.Now()}
+		h.removals[r] = p
+	}
+	p.requested = true
+
+	return nil
+}
+
+// Pending reports whether removals are waiting for their handoff to
+// complete.
+func (h *Handoff) Pending() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.removals) > 0
+}
+
+// CompleteTerm removes the bindings whose handoff is complete or timed out
+// from their adapters. Removals that were not asked for again in this term
+// are forgotten. The term is then completed by the wrapped Communicator if
+// it defers work until the end of a term as well.
+func (h *Handoff) CompleteTerm(ctx context.Context) {
+	h.completeHandoffs(ctx)
+
+	if t, ok := h.comm.(termCompleter); ok {
+		t.CompleteTerm(ctx)
+	}
+}
+
+// completeHandoffs carries out the removals whose handoff is complete or
+// timed out. Adapters are called without holding the lock, so that a slow
+// adapter does not block the rest of the Handoff.
+func (h *Handoff) completeHandoffs(ctx context.Context) {
+	for _, c := range h.checks() {
+		logger := h.bindingLogger(c.binding)
+		if !h.handedOver(ctx, c) {
+			if !c.timedOut {
+				continue
+			}
+			logger.Warnf("handoff timed out after %s, removing binding anyway", h.timeout)
+		}
+
+		if !h.stillPending(c) {
+			continue
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, h.callTimeout)
+		err := h.comm.Remove(callCtx, c.adapter, c.binding)
+		cancel()
+		if err != nil {
+			logger.Warnf("failed to remove binding from adapter: %s", err)
+			continue
+		}
+
+		h.mu.Lock()
+		if h.removals[c.removal] == c.pending {
+			delete(h.removals, c.removal)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// checks returns the removals that were asked for in the last term and
+// forgets the others. The adapters that hold each binding are reset for the
+// next term.
+func (h *Handoff) checks() []handoffCheck {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var checks []handoffCheck
+	for r, p := range h.removals {
+		if !p.requested {
+			delete(h.removals, r)
+			continue
+		}
+		p.requested = false
+
+		c := handoffCheck{
+			removal:  r,
+			pending:  p,
+			timedOut: time.Since(p.since) >= h.timeout,
+		}
+		for adapter := range h.holders[r.binding] {
+			if _, ok := h.removals[removal{adapter: adapter, binding: r.binding}]; ok {
+				continue
+			}
+			c.keepers = append(c.keepers, adapter)
+		}
+		checks = append(checks, c)
+	}
+
+	h.holders = make(map[interface{}]map[interface{}]bool)
+
+	return checks
+}
+
+// stillPending reports whether the removal was not cancelled by an Add since
+// it was checked.
+func (h *Handoff) stillPending(c handoffCheck) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.removals[c.removal] == c.pending
+}
+
+// handedOver reports whether every adapter that keeps the binding streams
+// its logs.
+func (h *Handoff) handedOver(ctx context.Context, c handoffCheck) bool {
+	for _, adapter := range c.keepers {
+		callCtx, cancel := context.WithTimeout(ctx, h.callTimeout)
+		streaming, err := h.streams.Streaming(callCtx, adapter, c.binding)
+		cancel()
+		if err != nil {
+			h.bindingLogger(c.binding).Warnf("failed to check whether adapter streams binding: %s", err)
+			return false
+		}
+		if !streaming {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (h *Handoff) hold(adapter, binding interface{}) {
+	adapters, ok := h.holders[binding]
+	if !ok {
+		adapters = make(map[interface{}]bool)
+		h.holders[binding] = adapters
+	}
+	adapters[adapter] = true
+}
+
+func (h *Handoff) bindingLogger(binding interface{}) *logging.Logger {
+	b, ok := binding.(v1.Binding)
+	if !ok {
+		return h.logger
+	}
+
+	return h.logger.With(logging.BindingFields(b.AppId, b.Drain))
+}
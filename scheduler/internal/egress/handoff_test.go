@@ -0,0 +1,184 @@
+package egress_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/egress"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handoff", func() {
+	var (
+		comm    *spyCommunicator
+		streams *spyStreamChecker
+		b       v1.Binding
+	)
+
+	BeforeEach(func() {
+		comm = newSpyCommunicator()
+		comm.listResults = map[interface{}][]interface{}{
+			"adapter-1": {v1.Binding{AppId: "some-app-id"}},
+		}
+		streams = &spyStreamChecker{
+			streaming: make(map[interface{}]bool),
+		}
+		b = v1.Binding{AppId: "some-app-id"}
+	})
+
+	// moveBinding runs a term in which the binding moves from adapter-1 to
+	// adapter-2.
+	moveBinding := func(h *egress.Handoff) {
+		ctx := context.Background()
+		_, err := h.List(ctx, "adapter-1")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = h.List(ctx, "adapter-2")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(h.Remove(ctx, "adapter-1", b)).To(Succeed())
+		Expect(h.Add(ctx, "adapter-2", b)).To(Succeed())
+		h.CompleteTerm(ctx)
+	}
+
+	It("removes a binding once the adapter that takes it over streams it", func() {
+		h := egress.NewHandoff(comm, streams, time.Minute)
+
+		moveBinding(h)
+		Expect(comm.adds["adapter-2"]).To(ConsistOf(b))
+		Expect(comm.removes).To(BeEmpty())
+
+		comm.listResults["adapter-2"] = []interface{}{b}
+		streams.streaming["adapter-2"] = true
+		moveBinding(h)
+
+		Expect(comm.removes["adapter-1"]).To(ConsistOf(b))
+	})
+
+	It("reports pending removals until the handoff completes", func() {
+		h := egress.NewHandoff(comm, streams, time.Minute)
+		Expect(h.Pending()).To(BeFalse())
+
+		moveBinding(h)
+		Expect(h.Pending()).To(BeTrue())
+
+		comm.listResults["adapter-2"] = []interface{}{b}
+		streams.streaming["adapter-2"] = true
+		moveBinding(h)
+
+		Expect(h.Pending()).To(BeFalse())
+	})
+
+	It("removes a binding anyway once the handoff timed out", func() {
+		h := egress.NewHandoff(comm, streams, 0)
+
+		moveBinding(h)
+
+		Expect(comm.removes["adapter-1"]).To(ConsistOf(b))
+	})
+
+	It("does not remove a binding if the stream check fails", func() {
+		streams.err = errors.New("some-error")
+		h := egress.NewHandoff(comm, streams, time.Minute)
+
+		moveBinding(h)
+
+		Expect(comm.removes).To(BeEmpty())
+	})
+
+	It("removes a binding that no other adapter keeps right away", func() {
+		h := egress.NewHandoff(comm, streams, time.Minute)
+
+		ctx := context.Background()
+		_, err := h.List(ctx, "adapter-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(h.Remove(ctx, "adapter-1", b)).To(Succeed())
+		h.CompleteTerm(ctx)
+
+		Expect(comm.removes["adapter-1"]).To(ConsistOf(b))
+	})
+
+	It("forgets a removal that is not asked for again", func() {
+		h := egress.NewHandoff(comm, streams, time.Minute)
+		moveBinding(h)
+
+		ctx := context.Background()
+		h.CompleteTerm(ctx)
+
+		streams.streaming["adapter-2"] = true
+		h.CompleteTerm(ctx)
+
+		Expect(comm.removes).To(BeEmpty())
+	})
+
+	It("cancels a removal when the binding is added back", func() {
+		h := egress.NewHandoff(comm, streams, 0)
+
+		ctx := context.Background()
+		Expect(h.Remove(ctx, "adapter-1", b)).To(Succeed())
+		Expect(h.Add(ctx, "adapter-1", b)).To(Succeed())
+		h.CompleteTerm(ctx)
+
+		Expect(comm.removes).To(BeEmpty())
+	})
+
+	Context("when an adapter does not respond", func() {
+		BeforeEach(func() {
+			streams.started = make(chan struct{}, 1)
+		})
+
+		It("gives up on the stream check after the call timeout", func() {
+			h := egress.NewHandoff(comm, streams, time.Minute, egress.WithHandoffCallTimeout(50*time.Millisecond))
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				moveBinding(h)
+				close(done)
+			}()
+
+			Eventually(done).Should(BeClosed())
+			Expect(comm.removes).To(BeEmpty())
+		})
+
+		It("does not block other calls while it checks the adapter", func() {
+			h := egress.NewHandoff(comm, streams, time.Minute, egress.WithHandoffCallTimeout(time.Second))
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				moveBinding(h)
+				close(done)
+			}()
+			Eventually(streams.started).Should(Receive())
+
+			start := time.Now()
+			Expect(h.Remove(context.Background(), "adapter-3", b)).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+
+			Eventually(done, 2*time.Second).Should(BeClosed())
+		})
+	})
+})
+
+type spyStreamChecker struct {
+	streaming map[interface{}]bool
+	err       error
+
+	// started is sent to once a check starts if it is set. The check then
+	// waits until its context is done.
+	started chan struct{}
+}
+
+func (s *spyStreamChecker) Streaming(ctx context.Context, adapter, binding interface{}) (bool, error) {
+	if s.started != nil {
+		s.started <- struct{}{}
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+
+	return s.streaming[adapter], s.err
+}
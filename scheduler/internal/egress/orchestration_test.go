@@ -3,12 +3,18 @@ package egress_test
 import (
 	"errors"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 
 	"context"
 
+	loggregator "code.cloudfoundry.org/go-loggregator"
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
 	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/ingress"
+	"google.golang.org/grpc"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -201,6 +207,133 @@ var _ = Describe("Orchestration", func() {
 	})
 })
 
+var _ = Describe("Orchestration with unchanged bindings", func() {
+	var (
+		comm   *spyCommunicator
+		reader *changeSpyReader
+		orch   *egress.Orchestrator
+		client *spyClient
+	)
+
+	BeforeEach(func() {
+		client = &spyClient{}
+		comm = newSpyCommunicator()
+		reader = &changeSpyReader{
+			spyReader: &spyReader{drains: []v1.Binding{{AppId: "a"}}},
+			changed:   true,
+		}
+		orch = egress.NewOrchestrator(
+			egress.AdapterPool{"test-addr-1": client},
+			reader,
+			comm,
+			&spyHealthEmitter{},
+			testhelper.NewMetricClient(),
+			egress.WithMaxSkippedTerms(1),
+		)
+	})
+
+	It("skips a term if the bindings did not change", func() {
+		orch.NextTerm()
+		Expect(comm.adds[client]).To(HaveLen(1))
+
+		reader.changed = false
+		orch.NextTerm()
+
+		Expect(comm.adds[client]).To(HaveLen(1))
+	})
+
+	It("runs a term after the maximum number of skipped terms", func() {
+		orch.NextTerm()
+		reader.changed = false
+
+		orch.NextTerm()
+		orch.NextTerm()
+
+		Expect(comm.adds[client]).To(HaveLen(2))
+	})
+
+	It("does not skip the first term", func() {
+		reader.changed = false
+		orch.NextTerm()
+
+		Expect(comm.adds[client]).To(HaveLen(1))
+	})
+
+	It("runs a term if the fetched bindings changed", func() {
+		orch.NextTerm()
+		reader.changed = false
+
+		reader.drains = []v1.Binding{{AppId: "a"}, {AppId: "b"}}
+		orch.NextTerm()
+
+		Expect(comm.adds[client]).To(HaveLen(3))
+	})
+
+	It("runs a term while a handoff is pending", func() {
+		orch.NextTerm()
+		reader.changed = false
+
+		comm.pending = true
+		orch.NextTerm()
+
+		Expect(comm.adds[client]).To(HaveLen(2))
+	})
+
+	It("skips a term if a binding provider without ETags sent the same bindings", func() {
+		var requests int64
+		cc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&requests, 1)
+			w.Write([]byte(`{"results": {"app-1": {"drains": ["syslog://10.0.0.1:514"], "hostname": "org.space.one"}}}`))
+		}))
+		defer cc.Close()
+
+		fetcher := ingress.NewFilteredBindingFetcher(
+			&ingress.BlacklistRanges{},
+			ingress.NewBindingFetcher(ingress.APIClient{
+				Client:    http.DefaultClient,
+				Addr:      cc.URL,
+				BatchSize: 1000,
+			}),
+			nullLogClient{},
+		)
+		orch = egress.NewOrchestrator(
+			egress.AdapterPool{"test-addr-1": client},
+			fetcher,
+			comm,
+			&spyHealthEmitter{},
+			testhelper.NewMetricClient(),
+			egress.WithMaxSkippedTerms(3),
+		)
+
+		orch.NextTerm()
+		orch.NextTerm()
+		orch.NextTerm()
+
+		Expect(atomic.LoadInt64(&requests)).To(Equal(int64(3)))
+		Expect(comm.adds[client]).To(HaveLen(1))
+	})
+
+	It("runs a term when an adapter becomes unreachable", func() {
+		pool := egress.NewAdapterPool([]string{"127.0.0.1:1"}, nil, grpc.WithInsecure())
+		orch = egress.NewOrchestrator(
+			pool,
+			reader,
+			comm,
+			&spyHealthEmitter{},
+			testhelper.NewMetricClient(),
+			egress.WithMaxSkippedTerms(1000),
+		)
+		adapter := pool["127.0.0.1:1"]
+		orch.NextTerm()
+		reader.changed = false
+
+		Eventually(func() int {
+			orch.NextTerm()
+			return len(comm.adds[adapter])
+		}).Should(BeNumerically(">", 1))
+	})
+})
+
 func hasDuplicate(bindings []interface{}) bool {
 	for i, b := range bindings {
 		for j, bb := range bindings {
@@ -219,6 +352,9 @@ type spyCommunicator struct {
 	removesErr  map[interface{}]error
 	adds        map[interface{}][]interface{}
 	removes     map[interface{}][]interface{}
+	syncs       map[interface{}][]interface{}
+	syncErrs    map[interface{}]error
+	pending     bool
 }
 
 type spyClient struct {
@@ -229,6 +365,7 @@ func newSpyCommunicator() *spyCommunicator {
 	return &spyCommunicator{
 		adds:    make(map[interface{}][]interface{}),
 		removes: make(map[interface{}][]interface{}),
+		syncs:   make(map[interface{}][]interface{}),
 	}
 }
 
@@ -246,6 +383,18 @@ func (s *spyCommunicator) Remove(ctx context.Context, worker, task interface{})
 	return s.removesErr[worker]
 }
 
+func (s *spyCommunicator) Pending() bool {
+	return s.pending
+}
+
+func (s *spyCommunicator) Sync(ctx context.Context, worker interface{}, tasks []interface{}) (int, error) {
+	if err := s.syncErrs[worker]; err != nil {
+		return 0, err
+	}
+	s.syncs[worker] = tasks
+	return 0, nil
+}
+
 type spyReader struct {
 	drains []v1.Binding
 	err    error
@@ -255,6 +404,19 @@ func (s *spyReader) FetchBindings() (appBindings []v1.Binding, invalid int, err
 	return s.randomizeOrder(s.drains), 0, s.err
 }
 
+type nullLogClient struct{}
+
+func (nullLogClient) EmitLog(string, ...loggregator.EmitLogOption) {}
+
+type changeSpyReader struct {
+	*spyReader
+	changed bool
+}
+
+func (s *changeSpyReader) BindingsChanged() bool {
+	return s.changed
+}
+
 func (s *spyReader) randomizeOrder(b []v1.Binding) []v1.Binding {
 	var result []v1.Binding
 	for _, n := range rand.Perm(len(b)) {
@@ -3,12 +3,12 @@ package egress
 
 import (
 	"context"
-	"log"
 	"time"
 
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
 	orchestrator "code.cloudfoundry.org/go-orchestrator"
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 )
 
 const maxAdapters = 2
@@ -17,6 +17,18 @@ type BindingReader interface {
 	FetchBindings() (appBindings []v1.Binding, invalid int, err error)
 }
 
+// changeReporter is implemented by BindingReaders that know whether the
+// bindings changed since they were last fetched.
+type changeReporter interface {
+	BindingsChanged() bool
+}
+
+// pendingReporter is implemented by Communicators that have work left to do
+// at the end of the next term.
+type pendingReporter interface {
+	Pending() bool
+}
+
 type HealthEmitter interface {
 	SetCounter(c map[string]int)
 }
@@ -25,9 +37,41 @@ type HealthEmitter interface {
 type Orchestrator struct {
 	reader     BindingReader
 	comm       Communicator
+	pool       AdapterPool
 	orch       *orchestrator.Orchestrator
 	health     HealthEmitter
 	drainGauge pulseemitter.GaugeMetric
+	logger     *logging.Logger
+
+	maxSkippedTerms int
+	skippedTerms    int
+	hadTerm         bool
+
+	// lastBindings and lastAdapters are the bindings and the reachable
+	// adapters of the last term that was not skipped.
+	lastBindings map[interface{}]bool
+	lastAdapters map[interface{}]bool
+}
+
+// OrchestratorOption allows an Orchestrator to be customized.
+type OrchestratorOption func(*Orchestrator)
+
+// WithLogger writes the logs of the Orchestrator to l. Logs are discarded by
+// default.
+func WithLogger(l *logging.Logger) OrchestratorOption {
+	return func(o *Orchestrator) {
+		o.logger = l
+	}
+}
+
+// WithMaxSkippedTerms sets how many terms in a row are skipped because
+// nothing changed since the last term. A term is run after that many skipped
+// terms anyway, so that adapters that lost their bindings get them back.
+// Terms are never skipped by default.
+func WithMaxSkippedTerms(n int) OrchestratorOption {
+	return func(o *Orchestrator) {
+		o.maxSkippedTerms = n
+	}
 }
 
 type Communicator interface {
@@ -46,6 +90,12 @@ type Communicator interface {
 	Remove(ctx context.Context, adapter, binding interface{}) error
 }
 
+// termCompleter is implemented by Communicators that defer adding or
+// removing bindings until the end of a term.
+type termCompleter interface {
+	CompleteTerm(ctx context.Context)
+}
+
 type MetricEmitter interface {
 	NewGaugeMetric(name, unit string, opts ...pulseemitter.MetricOption) pulseemitter.GaugeMetric
 }
@@ -57,6 +107,7 @@ func NewOrchestrator(
 	c Communicator,
 	h HealthEmitter,
 	m MetricEmitter,
+	opts ...OrchestratorOption,
 ) *Orchestrator {
 	// metric-documentation-v2: (scheduler.drains) Number of drains being
 	// serviced by scalable syslog.
@@ -77,19 +128,25 @@ func NewOrchestrator(
 		orch.AddWorker(client)
 	}
 
-	return &Orchestrator{
+	o := &Orchestrator{
 		reader:     r,
 		comm:       c,
+		pool:       clients,
 		health:     h,
 		drainGauge: drainGauge,
 		orch:       orch,
 	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
 }
 
 func (o *Orchestrator) NextTerm() {
 	freshBindings, blacklisted, err := o.reader.FetchBindings()
 	if err != nil {
-		log.Printf("fetch bindings failed with error: %s", err)
+		o.logger.Errorf("fetch bindings failed with error: %s", err)
 		return
 	}
 
@@ -99,6 +156,11 @@ func (o *Orchestrator) NextTerm() {
 	})
 	o.drainGauge.Set(float64(len(freshBindings)))
 
+	if o.skipTerm(freshBindings) {
+		o.logger.Debugf("bindings and adapters did not change, skipping term")
+		return
+	}
+
 	var tasks []orchestrator.Task
 	for _, b := range freshBindings {
 		tasks = append(tasks, orchestrator.Task{
@@ -107,8 +169,55 @@ func (o *Orchestrator) NextTerm() {
 		})
 	}
 
+	ctx := context.Background()
 	o.orch.UpdateTasks(tasks)
-	o.orch.NextTerm(context.Background())
+	o.orch.NextTerm(ctx)
+
+	if t, ok := o.comm.(termCompleter); ok {
+		t.CompleteTerm(ctx)
+	}
+}
+
+// skipTerm reports whether the term can be skipped because nothing changed
+// since the last term: the bindings are the same after filtering, the same
+// adapters are reachable and no handoff is pending.
+func (o *Orchestrator) skipTerm(freshBindings []v1.Binding) bool {
+	bindings := make(map[interface{}]bool, len(freshBindings))
+	for _, b := range freshBindings {
+		bindings[b] = true
+	}
+	adapters := o.pool.reachable()
+
+	if o.hadTerm &&
+		o.skippedTerms < o.maxSkippedTerms &&
+		!o.bindingsChanged() &&
+		!o.handoffPending() &&
+		equalSets(bindings, o.lastBindings) &&
+		equalSets(adapters, o.lastAdapters) {
+		o.skippedTerms++
+		return true
+	}
+
+	o.skippedTerms = 0
+	o.hadTerm = true
+	o.lastBindings = bindings
+	o.lastAdapters = adapters
+
+	return false
+}
+
+// bindingsChanged reports whether the BindingReader saw the bindings change
+// since they were last fetched. Readers that cannot tell, such as readers of
+// a binding provider that sends no ETags, report no change, as the fetched
+// bindings are compared as well.
+func (o *Orchestrator) bindingsChanged() bool {
+	c, ok := o.reader.(changeReporter)
+	return ok && c.BindingsChanged()
+}
+
+func (o *Orchestrator) handoffPending() bool {
+	p, ok := o.comm.(pendingReporter)
+	return ok && p.Pending()
 }
 
 // Run starts the orchestrator.
@@ -0,0 +1,208 @@
+package egress
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
+)
+
+// watchBufferSize is how many updates a watching adapter can fall behind
+// before its stream is closed. The adapter is sent a new snapshot when it
+// watches again.
+const watchBufferSize = 100
+
+// Publisher streams the bindings the scheduler assigns to each adapter of
+// the pool to the adapters that watch them. An adapter that starts watching
+// is sent a snapshot of its bindings and then every binding that is added to
+// or deleted from it. Adapters are not sent a snapshot before their bindings
+// are known, so that an adapter does not lose its bindings while the
+// scheduler starts up.
+type Publisher struct {
+	pool   AdapterPool
+	logger *logging.Logger
+
+	mu       sync.Mutex
+	assigned map[string]map[v1.Binding]bool
+	watchers map[string]map[*watcher]bool
+}
+
+type watcher struct {
+	updates chan *v1.WatchBindingsResponse
+	behind  chan struct{}
+	synced  bool
+}
+
+// PublisherOption allows a Publisher to be customized.
+type PublisherOption func(*Publisher)
+
+// WithPublisherLogger writes the logs of the Publisher to l. Logs are
+// discarded by default.
+func WithPublisherLogger(l *logging.Logger) PublisherOption {
+	return func(p *Publisher) {
+		p.logger = l
+	}
+}
+
+// NewPublisher returns a Publisher for the adapters of the pool. Adapters
+// watch their bindings by the address they have in the pool.
+func NewPublisher(pool AdapterPool, opts ...PublisherOption) *Publisher {
+	p := &Publisher{
+		pool:     pool,
+		assigned: make(map[string]map[v1.Binding]bool),
+		watchers: make(map[string]map[*watcher]bool),
+	}
+
+	for _, o := range opts {
+		o(p)
+	}
+
+	return p
+}
+
+// Publish records the bindings assigned to the adapter and sends the
+// bindings that were added or deleted since they were last published to
+// every adapter that watches them.
+func (p *Publisher) Publish(adapter interface{}, bindings []interface{}) {
+	addr, ok := p.addr(adapter)
+	if !ok {
+		return
+	}
+
+	assigned := make(map[v1.Binding]bool, len(bindings))
+	for _, b := range bindings {
+		assigned[b.(v1.Binding)] = true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	previous := p.assigned[addr]
+	p.assigned[addr] = assigned
+
+	update := &v1.WatchBindingsResponse{}
+	for b := range assigned {
+		if !previous[b] {
+			update.Added = append(update.Added, bindingRef(b))
+		}
+	}
+	for b := range previous {
+		if !assigned[b] {
+			update.Deleted = append(update.Deleted, bindingRef(b))
+		}
+	}
+
+	for w := range p.watchers[addr] {
+		if !w.synced {
+			p.send(addr, w, p.snapshot(addr))
+			continue
+		}
+
+		if len(update.Added) > 0 || len(update.Deleted) > 0 {
+			p.send(addr, w, update)
+		}
+	}
+}
+
+// WatchBindings streams the bindings of the adapter that is given by its
+// address in the pool until the adapter stops watching or falls behind.
+func (p *Publisher) WatchBindings(req *v1.WatchBindingsRequest, stream v1.Scheduler_WatchBindingsServer) error {
+	if _, ok := p.pool[req.Adapter]; !ok {
+		return grpc.Errorf(codes.NotFound, "unknown adapter: %s", req.Adapter)
+	}
+
+	logger := p.logger.With(logging.Fields{"adapter": req.Adapter})
+	logger.Infof("adapter started watching its bindings")
+
+	w := p.watch(req.Adapter)
+	defer p.unwatch(req.Adapter, w)
+
+	for {
+		select {
+		case update := <-w.updates:
+			if err := stream.Send(update); err != nil {
+				logger.Warnf("failed to send binding update to adapter: %s", err)
+				return err
+			}
+		case <-w.behind:
+			logger.Warnf("adapter fell behind on binding updates")
+			return grpc.Errorf(codes.ResourceExhausted, "adapter fell behind on binding updates")
+		case <-stream.Context().Done():
+			logger.Infof("adapter stopped watching its bindings")
+			return nil
+		}
+	}
+}
+
+// watch registers a watcher of the adapter. It is sent a snapshot right away
+// if the bindings of the adapter are known.
+func (p *Publisher) watch(addr string) *watcher {
+	w := &watcher{
+		updates: make(chan *v1.WatchBindingsResponse, watchBufferSize),
+		behind:  make(chan struct{}),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	watchers, ok := p.watchers[addr]
+	if !ok {
+		watchers = make(map[*watcher]bool)
+		p.watchers[addr] = watchers
+	}
+	watchers[w] = true
+
+	if _, ok := p.assigned[addr]; ok {
+		p.send(addr, w, p.snapshot(addr))
+	}
+
+	return w
+}
+
+func (p *Publisher) unwatch(addr string, w *watcher) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.watchers[addr], w)
+}
+
+// send queues the update for the watcher. A watcher that fell behind is
+// dropped. The caller must hold the lock.
+func (p *Publisher) send(addr string, w *watcher, update *v1.WatchBindingsResponse) {
+	select {
+	case w.updates <- update:
+		w.synced = true
+	default:
+		delete(p.watchers[addr], w)
+		close(w.behind)
+	}
+}
+
+// snapshot returns the bindings assigned to the adapter. The caller must
+// hold the lock.
+func (p *Publisher) snapshot(addr string) *v1.WatchBindingsResponse {
+	update := &v1.WatchBindingsResponse{Snapshot: true}
+	for b := range p.assigned[addr] {
+		update.Added = append(update.Added, bindingRef(b))
+	}
+
+	return update
+}
+
+// addr returns the address of the adapter in the pool.
+func (p *Publisher) addr(adapter interface{}) (string, bool) {
+	for addr, client := range p.pool {
+		if client == adapter {
+			return addr, true
+		}
+	}
+
+	return "", false
+}
+
+func bindingRef(b v1.Binding) *v1.Binding {
+	return &b
+}
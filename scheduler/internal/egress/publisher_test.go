@@ -0,0 +1,140 @@
+package egress_test
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/egress"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Publisher", func() {
+	var (
+		ctx       context.Context
+		cancel    func()
+		adapter   *spyClient
+		publisher *egress.Publisher
+		stream    *spyWatchBindingsServer
+
+		bindingA v1.Binding
+		bindingB v1.Binding
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		adapter = &spyClient{}
+		publisher = egress.NewPublisher(egress.AdapterPool{
+			"10.0.0.1:4443": adapter,
+		})
+		stream = newSpyWatchBindingsServer(ctx)
+
+		bindingA = v1.Binding{AppId: "app-a"}
+		bindingB = v1.Binding{AppId: "app-b"}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	watch := func() chan error {
+		errs := make(chan error, 1)
+		go func() {
+			errs <- publisher.WatchBindings(
+				&v1.WatchBindingsRequest{Adapter: "10.0.0.1:4443"},
+				stream,
+			)
+		}()
+
+		return errs
+	}
+
+	It("sends a snapshot of the bindings of the adapter", func() {
+		publisher.Publish(adapter, []interface{}{bindingA, bindingB})
+		watch()
+
+		var update *v1.WatchBindingsResponse
+		Eventually(stream.sent).Should(Receive(&update))
+		Expect(update.Snapshot).To(BeTrue())
+		Expect(update.Added).To(ConsistOf(&bindingA, &bindingB))
+	})
+
+	It("does not send a snapshot before the bindings are published", func() {
+		watch()
+		Consistently(stream.sent).ShouldNot(Receive())
+
+		publisher.Publish(adapter, []interface{}{bindingA})
+
+		var update *v1.WatchBindingsResponse
+		Eventually(stream.sent).Should(Receive(&update))
+		Expect(update.Snapshot).To(BeTrue())
+		Expect(update.Added).To(ConsistOf(&bindingA))
+	})
+
+	It("sends the bindings that are added and deleted", func() {
+		publisher.Publish(adapter, []interface{}{bindingA})
+		watch()
+		Eventually(stream.sent).Should(Receive())
+
+		publisher.Publish(adapter, []interface{}{bindingB})
+
+		var update *v1.WatchBindingsResponse
+		Eventually(stream.sent).Should(Receive(&update))
+		Expect(update.Snapshot).To(BeFalse())
+		Expect(update.Added).To(ConsistOf(&bindingB))
+		Expect(update.Deleted).To(ConsistOf(&bindingA))
+	})
+
+	It("does not send an update if the bindings did not change", func() {
+		publisher.Publish(adapter, []interface{}{bindingA})
+		watch()
+		Eventually(stream.sent).Should(Receive())
+
+		publisher.Publish(adapter, []interface{}{bindingA})
+
+		Consistently(stream.sent).ShouldNot(Receive())
+	})
+
+	It("rejects an unknown adapter", func() {
+		err := publisher.WatchBindings(
+			&v1.WatchBindingsRequest{Adapter: "10.0.0.2:4443"},
+			stream,
+		)
+
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	It("stops streaming when the adapter stops watching", func() {
+		errs := watch()
+		cancel()
+
+		Eventually(errs).Should(Receive(BeNil()))
+	})
+})
+
+type spyWatchBindingsServer struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *v1.WatchBindingsResponse
+}
+
+func newSpyWatchBindingsServer(ctx context.Context) *spyWatchBindingsServer {
+	return &spyWatchBindingsServer{
+		ctx:  ctx,
+		sent: make(chan *v1.WatchBindingsResponse, 100),
+	}
+}
+
+func (s *spyWatchBindingsServer) Send(update *v1.WatchBindingsResponse) error {
+	s.sent <- update
+	return nil
+}
+
+func (s *spyWatchBindingsServer) Context() context.Context {
+	return s.ctx
+}
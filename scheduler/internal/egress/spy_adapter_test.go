@@ -53,3 +53,28 @@ func (t *spyAdapterServer) DeleteBinding(c context.Context, r *v1.DeleteBindingR
 
 	return new(v1.DeleteBindingResponse), nil
 }
+
+func (t *spyAdapterServer) ListBindingStats(context.Context, *v1.ListBindingStatsRequest) (*v1.ListBindingStatsResponse, error) {
+	return new(v1.ListBindingStatsResponse), nil
+}
+
+func (t *spyAdapterServer) SyncBindings(c context.Context, r *v1.SyncBindingsRequest) (*v1.SyncBindingsResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Bindings = r.Bindings
+
+	return new(v1.SyncBindingsResponse), nil
+}
+
+func (t *spyAdapterServer) BindingStreaming(c context.Context, r *v1.BindingStreamingRequest) (*v1.BindingStreamingResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, b := range t.Bindings {
+		if *b == *r.Binding {
+			return &v1.BindingStreamingResponse{Streaming: true}, nil
+		}
+	}
+
+	return new(v1.BindingStreamingResponse), nil
+}
@@ -0,0 +1,230 @@
+package egress
+
+import (
+	"context"
+	"sync"
+
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
+)
+
+// SyncCommunicator is a Communicator that can also replace all the bindings
+// of an adapter at once.
+type SyncCommunicator interface {
+	Communicator
+
+	// Sync replaces the bindings of the adapter and returns how many of them
+	// were rejected. It returns ErrSyncUnsupported if the adapter cannot
+	// replace its bindings at once.
+	Sync(ctx context.Context, adapter interface{}, bindings []interface{}) (int, error)
+}
+
+// BindingPublisher is told the bindings of an adapter whenever they are
+// assigned or removed during a term, and again at the end of the term.
+type BindingPublisher interface {
+	Publish(adapter interface{}, bindings []interface{})
+}
+
+// nullPublisher does not publish bindings.
+type nullPublisher struct{}
+
+// Publish does nothing.
+func (nullPublisher) Publish(interface{}, []interface{}) {}
+
+// Syncer is a Communicator that sends every adapter whose bindings change
+// during a term its complete set of bindings in a single call at the end of
+// the term, instead of a call for every binding that is added or removed.
+// The adapter reconciles its bindings at once, so a failed call leaves them
+// as they were rather than half updated. Adapters that do not support
+// syncing and adapters whose bindings were not listed in the term get a call
+// for every binding instead.
+type Syncer struct {
+	comm      SyncCommunicator
+	publisher BindingPublisher
+	logger    *logging.Logger
+
+	mu      sync.Mutex
+	listed  map[interface{}]map[interface{}]bool
+	desired map[interface{}]map[interface{}]bool
+}
+
+// SyncerOption allows a Syncer to be customized.
+type SyncerOption func(*Syncer)
+
+// WithSyncerLogger writes the logs of the Syncer to l. Logs are discarded by
+// default.
+func WithSyncerLogger(l *logging.Logger) SyncerOption {
+	return func(s *Syncer) {
+		s.logger = l
+	}
+}
+
+// WithSyncerPublisher publishes the bindings of every adapter that was
+// listed in a term to p as soon as a binding is added to or removed from
+// the adapter, and again at the end of the term before the adapter is
+// synced.
+func WithSyncerPublisher(p BindingPublisher) SyncerOption {
+	return func(s *Syncer) {
+		s.publisher = p
+	}
+}
+
+// NewSyncer returns a Syncer that lists and syncs bindings with c.
+func NewSyncer(c SyncCommunicator, opts ...SyncerOption) *Syncer {
+	s := &Syncer{
+		comm:      c,
+		publisher: nullPublisher{},
+		listed:    make(map[interface{}]map[interface{}]bool),
+		desired:   make(map[interface{}]map[interface{}]bool),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+// List returns the bindings of the adapter. The bindings added to or removed
+// from the adapter for the rest of the term are applied to them.
+func (s *Syncer) List(ctx context.Context, adapter interface{}) ([]interface{}, error) {
+	bindings, err := s.comm.List(ctx, adapter)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		delete(s.listed, adapter)
+		delete(s.desired, adapter)
+		return nil, err
+	}
+
+	listed := make(map[interface{}]bool, len(bindings))
+	desired := make(map[interface{}]bool, len(bindings))
+	for _, b := range bindings {
+		listed[b] = true
+		desired[b] = true
+	}
+	s.listed[adapter] = listed
+	s.desired[adapter] = desired
+
+	return bindings, nil
+}
+
+// Add adds the binding to the bindings the adapter is synced to at the end of
+// the term and publishes them. If the bindings of the adapter were not
+// listed in the term the binding is added right away.
+func (s *Syncer) Add(ctx context.Context, adapter, binding interface{}) error {
+	s.mu.Lock()
+	desired, ok := s.desired[adapter]
+	if ok && !desired[binding] {
+		desired[binding] = true
+		s.publisher.Publish(adapter, setToSlice(desired))
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return s.comm.Add(ctx, adapter, binding)
+	}
+
+	return nil
+}
+
+// Remove removes the binding from the bindings the adapter is synced to at
+// the end of the term and publishes them. If the bindings of the adapter
+// were not listed in the term the binding is removed right away.
+func (s *Syncer) Remove(ctx context.Context, adapter, binding interface{}) error {
+	s.mu.Lock()
+	desired, ok := s.desired[adapter]
+	if ok && desired[binding] {
+		delete(desired, binding)
+		s.publisher.Publish(adapter, setToSlice(desired))
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return s.comm.Remove(ctx, adapter, binding)
+	}
+
+	return nil
+}
+
+// CompleteTerm publishes the bindings of every adapter that was listed in
+// the term and syncs the adapters whose bindings changed. Adapters that do
+// not support syncing are sent the changes one binding at a time.
+func (s *Syncer) CompleteTerm(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for adapter, desired := range s.desired {
+		bindings := setToSlice(desired)
+		s.publisher.Publish(adapter, bindings)
+
+		listed := s.listed[adapter]
+		if equalSets(listed, desired) {
+			continue
+		}
+
+		rejected, err := s.comm.Sync(ctx, adapter, bindings)
+		switch err {
+		case nil:
+			if rejected > 0 {
+				s.logger.Warnf("adapter rejected %d bindings, it has the maximum number of bindings", rejected)
+			}
+		case ErrSyncUnsupported:
+			s.sendChanges(ctx, adapter, listed, desired)
+		default:
+			s.logger.With(logging.Fields{"bindings": len(bindings)}).
+				Warnf("failed to sync bindings of adapter: %s", err)
+		}
+	}
+
+	s.listed = make(map[interface{}]map[interface{}]bool)
+	s.desired = make(map[interface{}]map[interface{}]bool)
+}
+
+// sendChanges adds and removes the bindings that differ between listed and
+// desired one at a time.
+func (s *Syncer) sendChanges(ctx context.Context, adapter interface{}, listed, desired map[interface{}]bool) {
+	for b := range listed {
+		if desired[b] {
+			continue
+		}
+
+		if err := s.comm.Remove(ctx, adapter, b); err != nil {
+			s.logger.Warnf("failed to remove binding from adapter: %s", err)
+		}
+	}
+
+	for b := range desired {
+		if listed[b] {
+			continue
+		}
+
+		if err := s.comm.Add(ctx, adapter, b); err != nil {
+			s.logger.Warnf("failed to add binding to adapter: %s", err)
+		}
+	}
+}
+
+func setToSlice(set map[interface{}]bool) []interface{} {
+	s := make([]interface{}, 0, len(set))
+	for k := range set {
+		s = append(s, k)
+	}
+
+	return s
+}
+
+func equalSets(a, b map[interface{}]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,145 @@
+package egress_test
+
+import (
+	"context"
+	"errors"
+
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/egress"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Syncer", func() {
+	var (
+		ctx  context.Context
+		comm *spyCommunicator
+		s    *egress.Syncer
+
+		kept    v1.Binding
+		removed v1.Binding
+		added   v1.Binding
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kept = v1.Binding{AppId: "kept"}
+		removed = v1.Binding{AppId: "removed"}
+		added = v1.Binding{AppId: "added"}
+
+		comm = newSpyCommunicator()
+		comm.listResults = map[interface{}][]interface{}{
+			"adapter-1": {kept, removed},
+		}
+		comm.syncErrs = make(map[interface{}]error)
+		s = egress.NewSyncer(comm)
+	})
+
+	It("syncs the bindings of a changed adapter at the end of the term", func() {
+		bindings, err := s.List(ctx, "adapter-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bindings).To(ConsistOf(kept, removed))
+
+		Expect(s.Remove(ctx, "adapter-1", removed)).To(Succeed())
+		Expect(s.Add(ctx, "adapter-1", added)).To(Succeed())
+		Expect(comm.syncs).To(BeEmpty())
+
+		s.CompleteTerm(ctx)
+
+		Expect(comm.syncs["adapter-1"]).To(ConsistOf(kept, added))
+		Expect(comm.adds).To(BeEmpty())
+		Expect(comm.removes).To(BeEmpty())
+	})
+
+	It("does not sync an adapter whose bindings did not change", func() {
+		_, err := s.List(ctx, "adapter-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Add(ctx, "adapter-1", kept)).To(Succeed())
+
+		s.CompleteTerm(ctx)
+
+		Expect(comm.syncs).To(BeEmpty())
+	})
+
+	It("adds and removes bindings one at a time if the adapter does not support syncing", func() {
+		comm.syncErrs["adapter-1"] = egress.ErrSyncUnsupported
+		_, err := s.List(ctx, "adapter-1")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(s.Remove(ctx, "adapter-1", removed)).To(Succeed())
+		Expect(s.Add(ctx, "adapter-1", added)).To(Succeed())
+		s.CompleteTerm(ctx)
+
+		Expect(comm.adds["adapter-1"]).To(ConsistOf(added))
+		Expect(comm.removes["adapter-1"]).To(ConsistOf(removed))
+	})
+
+	It("does not add or remove bindings one at a time if syncing fails", func() {
+		comm.syncErrs["adapter-1"] = errors.New("some-error")
+		_, err := s.List(ctx, "adapter-1")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(s.Add(ctx, "adapter-1", added)).To(Succeed())
+		s.CompleteTerm(ctx)
+
+		Expect(comm.adds).To(BeEmpty())
+	})
+
+	It("adds and removes right away if the adapter was not listed in the term", func() {
+		Expect(s.Add(ctx, "adapter-2", added)).To(Succeed())
+		Expect(s.Remove(ctx, "adapter-2", removed)).To(Succeed())
+
+		Expect(comm.adds["adapter-2"]).To(ConsistOf(added))
+		Expect(comm.removes["adapter-2"]).To(ConsistOf(removed))
+
+		s.CompleteTerm(ctx)
+		Expect(comm.syncs).To(BeEmpty())
+	})
+
+	It("publishes the bindings of a listed adapter as soon as they change", func() {
+		publisher := &spyPublisher{published: make(map[interface{}][]interface{})}
+		s = egress.NewSyncer(comm, egress.WithSyncerPublisher(publisher))
+		_, err := s.List(ctx, "adapter-1")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(s.Add(ctx, "adapter-1", added)).To(Succeed())
+		Expect(publisher.published["adapter-1"]).To(ConsistOf(kept, removed, added))
+
+		Expect(s.Remove(ctx, "adapter-1", removed)).To(Succeed())
+		Expect(publisher.published["adapter-1"]).To(ConsistOf(kept, added))
+		Expect(comm.syncs).To(BeEmpty())
+	})
+
+	It("publishes the bindings of every listed adapter at the end of the term", func() {
+		publisher := &spyPublisher{published: make(map[interface{}][]interface{})}
+		s = egress.NewSyncer(comm, egress.WithSyncerPublisher(publisher))
+		_, err := s.List(ctx, "adapter-1")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(s.Add(ctx, "adapter-1", kept)).To(Succeed())
+		Expect(publisher.published).To(BeEmpty())
+		s.CompleteTerm(ctx)
+
+		Expect(publisher.published).To(HaveLen(1))
+		Expect(publisher.published["adapter-1"]).To(ConsistOf(kept, removed))
+	})
+
+	It("forgets the bindings of an adapter at the end of the term", func() {
+		_, err := s.List(ctx, "adapter-1")
+		Expect(err).ToNot(HaveOccurred())
+		s.CompleteTerm(ctx)
+
+		Expect(s.Add(ctx, "adapter-1", added)).To(Succeed())
+
+		Expect(comm.adds["adapter-1"]).To(ConsistOf(added))
+	})
+})
+
+type spyPublisher struct {
+	published map[interface{}][]interface{}
+}
+
+func (s *spyPublisher) Publish(adapter interface{}, bindings []interface{}) {
+	s.published[adapter] = bindings
+}
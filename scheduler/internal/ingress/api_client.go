@@ -18,3 +18,20 @@ type APIClient struct {
 func (w APIClient) Get(nextID int) (*http.Response, error) {
 	return w.Client.Get(fmt.Sprintf(pathTemplate, w.Addr, w.BatchSize, nextID))
 }
+
+// GetIfNoneMatch gets the page starting at nextID unless it still has the
+// given ETag, in which case the response has the status 304 Not Modified and
+// no body. It still makes a request for the page.
+func (w APIClient) GetIfNoneMatch(nextID int, etag string) (*http.Response, error) {
+	req, err := http.NewRequest(
+		http.MethodGet,
+		fmt.Sprintf(pathTemplate, w.Addr, w.BatchSize, nextID),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("If-None-Match", etag)
+
+	return w.Client.Do(req)
+}
@@ -15,69 +15,229 @@ type Getter interface {
 	Get(nextID int) (resp *http.Response, err error)
 }
 
+// ConditionalGetter is a Getter that can fetch a page only if it no longer
+// matches the ETag it had when it was last fetched.
+type ConditionalGetter interface {
+	GetIfNoneMatch(nextID int, etag string) (resp *http.Response, err error)
+}
+
 // BindingFetcher uses a Getter to fetch and decode Bindings
 type BindingFetcher struct {
 	getter     Getter
 	mu         sync.RWMutex
 	drainCount int
+	pages      map[int]page
+	changed    bool
+
+	// partialFetches is the number of fetches in a row that only requested
+	// the first page, up to maxPartialFetches.
+	partialFetches    int
+	maxPartialFetches int
+}
+
+// BindingFetcherOption allows a BindingFetcher to be customized.
+type BindingFetcherOption func(*BindingFetcher)
+
+// WithMaxPartialFetches lets the BindingFetcher reuse the pages after the
+// first one without requesting them when the first page was not modified,
+// for up to n fetches in a row. Every page is requested again after that, so
+// that changes to later pages are picked up. It defaults to 0, which
+// requests every page on every fetch.
+func WithMaxPartialFetches(n int) BindingFetcherOption {
+	return func(f *BindingFetcher) {
+		f.maxPartialFetches = n
+	}
+}
+
+// page is a page of bindings as it was last fetched, by the next_id it was
+// requested with.
+type page struct {
+	etag     string
+	bindings []v1.Binding
+	nextID   int
 }
 
 type response struct {
 	Results map[string]struct {
-		Drains   []string
+		Drains   []drain
 		Hostname string
 	}
 	NextID int `json:"next_id"`
 }
 
+// drain is a single drain of an app. The binding provider sends either the
+// drain URL as a string or an object with the URL and the PEM encoded client
+// certificate and key to use when connecting to it, and the CA certificates
+// to trust when verifying it.
+type drain struct {
+	URL  string `json:"url"`
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+	CA   string `json:"ca"`
+}
+
+// UnmarshalJSON decodes a drain from either a URL string or an object.
+func (d *drain) UnmarshalJSON(b []byte) error {
+	var url string
+	if err := json.Unmarshal(b, &url); err == nil {
+		d.URL = url
+		return nil
+	}
+
+	type plain drain
+	return json.Unmarshal(b, (*plain)(d))
+}
+
 // NewBindingFetcher returns a new BindingFetcher
-func NewBindingFetcher(g Getter) *BindingFetcher {
-	return &BindingFetcher{
+func NewBindingFetcher(g Getter, opts ...BindingFetcherOption) *BindingFetcher {
+	f := &BindingFetcher{
 		getter: g,
 	}
+	for _, o := range opts {
+		o(f)
+	}
+
+	return f
 }
 
 // FetchBindings reaches out to the syslog drain binding provider via the Getter and decodes
-// the response. If it does not get a 200, it returns an error.
+// the response. If it does not get a 200, it returns an error. If the Getter
+// is a ConditionalGetter, pages that have an ETag are only fetched again if
+// they changed, and the bindings they had before are returned otherwise.
+// When the first page did not change, the other pages are reused without
+// requesting them as allowed by WithMaxPartialFetches.
 func (f *BindingFetcher) FetchBindings() ([]v1.Binding, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
 	bindings := []v1.Binding{}
+	pages := make(map[int]page)
+	changed := len(f.pages) == 0
 	nextID := 0
 
 	for {
-		resp, err := f.getter.Get(nextID)
+		cached := f.pages[nextID]
+		p, modified, err := f.fetchPage(nextID)
 		if err != nil {
 			return nil, err
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("received %d status code from syslog drain binding API", resp.StatusCode)
+		if nextID == 0 && !modified && f.partialFetches < f.maxPartialFetches {
+			f.partialFetches++
+			f.changed = false
+			return f.cachedBindings(), nil
 		}
 
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
+		// Only pages with an ETag tell whether they changed. Pages without
+		// one are fetched in full every time and are not reported.
+		if modified && (p.etag != "" || cached.etag != "") {
+			changed = true
 		}
-		defer resp.Body.Close()
 
-		var r response
-		if err = json.Unmarshal(body, &r); err != nil {
-			return nil, fmt.Errorf("invalid API response body")
+		pages[nextID] = p
+		bindings = append(bindings, p.bindings...)
+
+		if p.nextID == 0 {
+			break
 		}
+		nextID = p.nextID
+	}
+
+	if len(pages) != len(f.pages) {
+		changed = true
+	}
+	f.pages = pages
+	f.changed = changed
+	f.partialFetches = 0
+
+	return bindings, nil
+}
+
+// cachedBindings returns the bindings of the pages that were last fetched,
+// in order. The caller must hold the lock.
+func (f *BindingFetcher) cachedBindings() []v1.Binding {
+	bindings := []v1.Binding{}
+	for nextID := 0; ; {
+		p := f.pages[nextID]
+		bindings = append(bindings, p.bindings...)
 
-		for appID, bindingData := range r.Results {
-			hostname := bindingData.Hostname
-			for _, drainURL := range bindingData.Drains {
-				bindings = append(bindings, v1.Binding{
-					Hostname: hostname,
-					Drain:    drainURL,
-					AppId:    appID,
-				})
-			}
+		if p.nextID == 0 {
+			return bindings
 		}
+		nextID = p.nextID
+	}
+}
+
+// BindingsChanged reports whether the binding provider told that the
+// bindings changed between the last two times they were fetched: a page with
+// an ETag was modified, or pages were added or removed. Changes to pages
+// without an ETag are not reported, so callers should compare the bindings
+// as well.
+func (f *BindingFetcher) BindingsChanged() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.changed
+}
+
+// fetchPage fetches the page requested with nextID. It returns whether the
+// page was modified since it was last fetched. The caller must hold the
+// lock.
+func (f *BindingFetcher) fetchPage(nextID int) (page, bool, error) {
+	cached, ok := f.pages[nextID]
+
+	resp, err := f.get(nextID, cached.etag)
+	if err != nil {
+		return page{}, false, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && ok {
+		resp.Body.Close()
+		return cached, false, nil
+	}
 
-		if r.NextID == 0 {
-			return bindings, nil
+	if resp.StatusCode != http.StatusOK {
+		return page{}, false, fmt.Errorf("received %d status code from syslog drain binding API", resp.StatusCode)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return page{}, false, err
+	}
+
+	var r response
+	if err = json.Unmarshal(body, &r); err != nil {
+		return page{}, false, fmt.Errorf("invalid API response body")
+	}
+
+	p := page{
+		etag:   resp.Header.Get("ETag"),
+		nextID: r.NextID,
+	}
+	for appID, bindingData := range r.Results {
+		hostname := bindingData.Hostname
+		for _, d := range bindingData.Drains {
+			p.bindings = append(p.bindings, v1.Binding{
+				Hostname: hostname,
+				Drain:    d.URL,
+				AppId:    appID,
+				Cert:     d.Cert,
+				Key:      d.Key,
+				Ca:       d.CA,
+			})
 		}
-		nextID = r.NextID
 	}
+
+	return p, true, nil
+}
+
+// get makes a conditional request for the page if it has an ETag and the
+// Getter supports it.
+func (f *BindingFetcher) get(nextID int, etag string) (*http.Response, error) {
+	if c, ok := f.getter.(ConditionalGetter); ok && etag != "" {
+		return c.GetIfNoneMatch(nextID, etag)
+	}
+
+	return f.getter.Get(nextID)
 }
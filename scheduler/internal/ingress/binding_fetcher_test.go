@@ -2,9 +2,12 @@ package ingress_test
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/ingress"
 	. "github.com/onsi/ginkgo"
@@ -79,6 +82,56 @@ var _ = Describe("BindingFetcher", func() {
 			})
 		})
 
+		Context("when a drain has a client certificate and CA", func() {
+			BeforeEach(func() {
+				getter.getResponses = []*http.Response{
+					&http.Response{
+						StatusCode: http.StatusOK,
+						Body: ioutil.NopCloser(strings.NewReader(`
+							{
+							  "results": {
+								"9be15160-4845-4f05-b089-40e827ba61f1": {
+								  "drains": [
+									"syslog://some.url",
+									{
+									  "url": "syslog-tls://some.other.url",
+									  "cert": "some-cert",
+									  "key": "some-key",
+									  "ca": "some-ca"
+									}
+								  ],
+								  "hostname": "org.space.logspinner"
+								}
+							  },
+							  "next_id": null
+							}
+					`)),
+					},
+				}
+			})
+
+			It("returns the certificate, key and CA with the binding", func() {
+				bindings, err := fetcher.FetchBindings()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bindings).To(HaveLen(2))
+
+				appID := "9be15160-4845-4f05-b089-40e827ba61f1"
+				Expect(bindings).To(ContainElement(v1.Binding{
+					AppId:    appID,
+					Hostname: "org.space.logspinner",
+					Drain:    "syslog://some.url",
+				}))
+				Expect(bindings).To(ContainElement(v1.Binding{
+					AppId:    appID,
+					Hostname: "org.space.logspinner",
+					Drain:    "syslog-tls://some.other.url",
+					Cert:     "some-cert",
+					Key:      "some-key",
+					Ca:       "some-ca",
+				}))
+			})
+		})
+
 		Context("when the status code is 200 and the body is invalid json", func() {
 			BeforeEach(func() {
 				getter.getResponses = []*http.Response{
@@ -118,8 +171,208 @@ var _ = Describe("BindingFetcher", func() {
 		})
 	})
 
+	It("reports the bindings as changed if the getter cannot make conditional requests", func() {
+		getter.getResponses = []*http.Response{
+			{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Etag": []string{`"v1"`}},
+				Body:       ioutil.NopCloser(strings.NewReader(`{ "results": { } }`)),
+			},
+			{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Etag": []string{`"v1"`}},
+				Body:       ioutil.NopCloser(strings.NewReader(`{ "results": { } }`)),
+			},
+		}
+
+		_, err := fetcher.FetchBindings()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = fetcher.FetchBindings()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fetcher.BindingsChanged()).To(BeTrue())
+	})
+
+	Context("when the binding provider supports ETags", func() {
+		var (
+			cc     *fakeCC
+			server *httptest.Server
+		)
+
+		BeforeEach(func() {
+			cc = newFakeCC(
+				`{"results": {"app-1": {"drains": ["syslog://one.url"], "hostname": "org.space.one"}}, "next_id": 1}`,
+				`{"results": {"app-2": {"drains": ["syslog://two.url"], "hostname": "org.space.two"}}}`,
+			)
+			server = httptest.NewServer(cc)
+
+			fetcher = ingress.NewBindingFetcher(ingress.APIClient{
+				Client:    http.DefaultClient,
+				Addr:      server.URL,
+				BatchSize: 1000,
+			})
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("returns the bindings of pages that did not change", func() {
+			first, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fetcher.BindingsChanged()).To(BeTrue())
+
+			second, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(second).To(ConsistOf(first))
+			Expect(second).To(HaveLen(2))
+			Expect(fetcher.BindingsChanged()).To(BeFalse())
+			Expect(cc.notModified()).To(Equal(2))
+		})
+
+		It("fetches the pages that changed", func() {
+			_, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			cc.setPage(1, `{"results": {"app-3": {"drains": ["syslog://three.url"], "hostname": "org.space.three"}}}`)
+			bindings, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fetcher.BindingsChanged()).To(BeTrue())
+			Expect(cc.notModified()).To(Equal(1))
+			Expect(bindings).To(ConsistOf(
+				v1.Binding{AppId: "app-1", Hostname: "org.space.one", Drain: "syslog://one.url"},
+				v1.Binding{AppId: "app-3", Hostname: "org.space.three", Drain: "syslog://three.url"},
+			))
+		})
+
+		It("reuses the other pages while the first page did not change", func() {
+			fetcher = ingress.NewBindingFetcher(
+				ingress.APIClient{
+					Client:    http.DefaultClient,
+					Addr:      server.URL,
+					BatchSize: 1000,
+				},
+				ingress.WithMaxPartialFetches(1),
+			)
+			first, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			cc.setPage(1, `{"results": {"app-3": {"drains": ["syslog://three.url"], "hostname": "org.space.three"}}}`)
+			second, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(second).To(ConsistOf(first))
+			Expect(fetcher.BindingsChanged()).To(BeFalse())
+			Expect(cc.requestCount()).To(Equal(3))
+
+			third, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fetcher.BindingsChanged()).To(BeTrue())
+			Expect(cc.requestCount()).To(Equal(5))
+			Expect(third).To(ConsistOf(
+				v1.Binding{AppId: "app-1", Hostname: "org.space.one", Drain: "syslog://one.url"},
+				v1.Binding{AppId: "app-3", Hostname: "org.space.three", Drain: "syslog://three.url"},
+			))
+		})
+	})
+
+	It("does not report the bindings as changed if the binding provider sends no ETags", func() {
+		cc := newFakeCC(
+			`{"results": {"app-1": {"drains": ["syslog://one.url"], "hostname": "org.space.one"}}, "next_id": 1}`,
+			`{"results": {"app-2": {"drains": ["syslog://two.url"], "hostname": "org.space.two"}}}`,
+		)
+		cc.noETags = true
+		server := httptest.NewServer(cc)
+		defer server.Close()
+		fetcher = ingress.NewBindingFetcher(
+			ingress.APIClient{
+				Client:    http.DefaultClient,
+				Addr:      server.URL,
+				BatchSize: 1000,
+			},
+			ingress.WithMaxPartialFetches(1),
+		)
+
+		_, err := fetcher.FetchBindings()
+		Expect(err).ToNot(HaveOccurred())
+		bindings, err := fetcher.FetchBindings()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bindings).To(HaveLen(2))
+		Expect(fetcher.BindingsChanged()).To(BeFalse())
+		Expect(cc.requestCount()).To(Equal(4))
+	})
 })
 
+// fakeCC serves pages of bindings with an ETag that changes whenever the
+// page does, and answers conditional requests for unchanged pages with 304
+// Not Modified.
+type fakeCC struct {
+	mu           sync.Mutex
+	pages        []string
+	versions     []int
+	noETags      bool
+	requests     int
+	notModifieds int
+}
+
+func newFakeCC(pages ...string) *fakeCC {
+	return &fakeCC{
+		pages:    pages,
+		versions: make([]int, len(pages)),
+	}
+}
+
+func (f *fakeCC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests++
+	var page int
+	fmt.Sscanf(r.URL.Query().Get("next_id"), "%d", &page)
+	if r.URL.Path != "/internal/v4/syslog_drain_urls" || page >= len(f.pages) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%d-%d"`, page, f.versions[page])
+	if r.Header.Get("If-None-Match") == etag {
+		f.notModifieds++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if !f.noETags {
+		w.Header().Set("ETag", etag)
+	}
+	w.Write([]byte(f.pages[page]))
+}
+
+func (f *fakeCC) setPage(page int, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pages[page] = body
+	f.versions[page]++
+}
+
+func (f *fakeCC) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.requests
+}
+
+func (f *fakeCC) notModified() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.notModifieds
+}
+
 type SpyGetter struct {
 	currentResponse int
 	getCalled       int
@@ -2,14 +2,14 @@ package ingress
 
 import (
 	"fmt"
-	"log"
 	"net"
 
 	loggregator "code.cloudfoundry.org/go-loggregator"
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 )
 
-var allowedSchemes = []string{"syslog", "syslog-tls", "https"}
+var allowedSchemes = []string{"syslog", "syslog-tls", "syslog-udp", "https"}
 
 type BindingReader interface {
 	FetchBindings() (appBindings []v1.Binding, err error)
@@ -30,14 +30,52 @@ type FilteredBindingFetcher struct {
 	ipChecker IPChecker
 	br        BindingReader
 	logClient LogClient
+	logger    *logging.Logger
 }
 
-func NewFilteredBindingFetcher(c IPChecker, b BindingReader, lc LogClient) *FilteredBindingFetcher {
-	return &FilteredBindingFetcher{
+// FilteredBindingFetcherOption allows a FilteredBindingFetcher to be
+// customized.
+type FilteredBindingFetcherOption func(*FilteredBindingFetcher)
+
+// WithLogger writes why bindings are filtered out to l. Logs are discarded
+// by default.
+func WithLogger(l *logging.Logger) FilteredBindingFetcherOption {
+	return func(f *FilteredBindingFetcher) {
+		f.logger = l
+	}
+}
+
+func NewFilteredBindingFetcher(
+	c IPChecker,
+	b BindingReader,
+	lc LogClient,
+	opts ...FilteredBindingFetcherOption,
+) *FilteredBindingFetcher {
+	f := &FilteredBindingFetcher{
 		ipChecker: c,
 		br:        b,
 		logClient: lc,
 	}
+	for _, o := range opts {
+		o(f)
+	}
+
+	return f
+}
+
+// changeReporter is implemented by BindingReaders that know whether the
+// bindings changed since they were last fetched.
+type changeReporter interface {
+	BindingsChanged() bool
+}
+
+// BindingsChanged reports whether the BindingReader saw its bindings change
+// between the last two fetches. Bindings of a BindingReader that cannot tell
+// are not reported as changed, so callers should compare the bindings as
+// well.
+func (f *FilteredBindingFetcher) BindingsChanged() bool {
+	c, ok := f.br.(changeReporter)
+	return ok && c.BindingsChanged()
 }
 
 func (f *FilteredBindingFetcher) FetchBindings() ([]v1.Binding, int, error) {
@@ -48,9 +86,11 @@ func (f *FilteredBindingFetcher) FetchBindings() ([]v1.Binding, int, error) {
 	newBindings := []v1.Binding{}
 
 	for _, binding := range sourceBindings {
+		logger := f.logger.With(logging.BindingFields(binding.AppId, binding.Drain))
+
 		scheme, host, err := f.ipChecker.ParseHost(binding.Drain)
 		if err != nil {
-			log.Println(err)
+			logger.Warnf("invalid syslog drain URL: %s", err)
 			f.emitErrorLog(binding.AppId, "Invalid syslog drain URL: parse failure")
 			continue
 		}
@@ -62,7 +102,7 @@ func (f *FilteredBindingFetcher) FetchBindings() ([]v1.Binding, int, error) {
 		ip, err := f.ipChecker.ResolveAddr(host)
 		if err != nil {
 			msg := fmt.Sprintf("Failed to resolve syslog drain host: %s", host)
-			log.Println(msg, err)
+			logger.Warnf("%s: %s", msg, err)
 			f.emitErrorLog(binding.AppId, msg)
 			continue
 		}
@@ -70,7 +110,7 @@ func (f *FilteredBindingFetcher) FetchBindings() ([]v1.Binding, int, error) {
 		err = f.ipChecker.CheckBlacklist(ip)
 		if err != nil {
 			msg := fmt.Sprintf("Syslog drain blacklisted: %s (%s)", host, ip)
-			log.Println(msg, err)
+			logger.Warnf("%s: %s", msg, err)
 			f.emitErrorLog(binding.AppId, msg)
 			continue
 		}
@@ -6,10 +6,12 @@ import (
 	"net/url"
 
 	loggregator "code.cloudfoundry.org/go-loggregator"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/ingress"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 
 	v2 "code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
@@ -42,6 +44,21 @@ var _ = Describe("FilteredBindingFetcher", func() {
 		Expect(actual).To(BeNil())
 	})
 
+	It("reports whether the bindings of the binding reader changed", func() {
+		bindingReader := &ChangeSpyBindingReader{changed: false}
+		filter := ingress.NewFilteredBindingFetcher(&spyIPChecker{}, bindingReader, &spyLogClient{})
+		Expect(filter.BindingsChanged()).To(BeFalse())
+
+		bindingReader.changed = true
+		Expect(filter.BindingsChanged()).To(BeTrue())
+	})
+
+	It("does not report bindings as changed if the binding reader cannot tell", func() {
+		filter := ingress.NewFilteredBindingFetcher(&spyIPChecker{}, &SpyBindingReader{}, &spyLogClient{})
+
+		Expect(filter.BindingsChanged()).To(BeFalse())
+	})
+
 	Context("when syslog drain has invalid host", func() {
 		var (
 			filter    *ingress.FilteredBindingFetcher
@@ -90,6 +107,7 @@ var _ = Describe("FilteredBindingFetcher", func() {
 				v1.Binding{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog://10.10.10.10"},
 				v1.Binding{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog-tls://10.10.10.10"},
 				v1.Binding{AppId: "app-id", Hostname: "we.dont.care", Drain: "https://10.10.10.10"},
+				v1.Binding{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog-udp://10.10.10.10"},
 				v1.Binding{AppId: "app-id", Hostname: "we.dont.care", Drain: "bad-scheme://10.10.10.10"},
 				v1.Binding{AppId: "app-id", Hostname: "we.dont.care", Drain: "blah://10.10.10.10"},
 			}
@@ -105,7 +123,7 @@ var _ = Describe("FilteredBindingFetcher", func() {
 			actual, removed, err := filter.FetchBindings()
 
 			Expect(err).ToNot(HaveOccurred())
-			Expect(actual).To(Equal(input[:3]))
+			Expect(actual).To(Equal(input[:4]))
 			Expect(removed).To(Equal(2))
 		})
 	})
@@ -154,6 +172,7 @@ var _ = Describe("FilteredBindingFetcher", func() {
 		var (
 			filter    *ingress.FilteredBindingFetcher
 			logClient *spyLogClient
+			logs      *gbytes.Buffer
 		)
 
 		BeforeEach(func() {
@@ -162,6 +181,7 @@ var _ = Describe("FilteredBindingFetcher", func() {
 			}
 
 			logClient = &spyLogClient{}
+			logs = gbytes.NewBuffer()
 
 			filter = ingress.NewFilteredBindingFetcher(
 				&spyIPChecker{
@@ -171,6 +191,7 @@ var _ = Describe("FilteredBindingFetcher", func() {
 				},
 				&SpyBindingReader{bindings: input},
 				logClient,
+				ingress.WithLogger(logging.New(logs, logging.InfoLevel)),
 			)
 		})
 
@@ -189,6 +210,13 @@ var _ = Describe("FilteredBindingFetcher", func() {
 			Expect(logClient.appID).To(Equal("app-id"))
 			Expect(logClient.sourceType).To(Equal("LGR"))
 		})
+
+		It("logs the blacklisted binding", func() {
+			_, _, _ = filter.FetchBindings()
+
+			Expect(logs).To(gbytes.Say(`"app_id":"app-id","drain_host":"some.invalid.host"`))
+			Expect(string(logs.Contents())).To(ContainSubstring("Syslog drain blacklisted"))
+		})
 	})
 })
 
@@ -244,3 +272,12 @@ type SpyBindingReader struct {
 func (s *SpyBindingReader) FetchBindings() ([]v1.Binding, error) {
 	return s.bindings, s.err
 }
+
+type ChangeSpyBindingReader struct {
+	SpyBindingReader
+	changed bool
+}
+
+func (s *ChangeSpyBindingReader) BindingsChanged() bool {
+	return s.changed
+}
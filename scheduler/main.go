@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/tls"
 	"log"
 	"net"
 	"os"
@@ -12,6 +13,7 @@ import (
 	loggregator "code.cloudfoundry.org/go-loggregator"
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
 	"code.cloudfoundry.org/scalable-syslog/internal/api"
+	"code.cloudfoundry.org/scalable-syslog/internal/logging"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/app"
 )
 
@@ -20,9 +22,14 @@ func main() {
 
 	cfg, err := app.LoadConfig(os.Args[1:])
 	if err != nil {
-		log.Fatalf("Invalid config: %s", err)
+		logging.New(os.Stderr, logging.ErrorLevel).Fatalf("Invalid config: %s", err)
 	}
 
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+	logger := logging.New(os.Stderr, level).With(logging.Fields{
+		"component": "scheduler",
+	})
+
 	apiTLSConfig, err := api.NewMutualTLSConfig(
 		cfg.APICertFile,
 		cfg.APIKeyFile,
@@ -30,7 +37,7 @@ func main() {
 		cfg.APICommonName,
 	)
 	if err != nil {
-		log.Fatalf("Invalid TLS config: %s", err)
+		logger.Fatalf("Invalid TLS config: %s", err)
 	}
 	apiTLSConfig.InsecureSkipVerify = cfg.APISkipCertVerify
 
@@ -41,7 +48,20 @@ func main() {
 		cfg.AdapterCommonName,
 	)
 	if err != nil {
-		log.Fatalf("Invalid TLS config: %s", err)
+		logger.Fatalf("Invalid TLS config: %s", err)
+	}
+
+	var watchTLSConfig *tls.Config
+	if cfg.WatchHostport != "" {
+		watchTLSConfig, err = api.NewMutualTLSConfig(
+			cfg.WatchCertFile,
+			cfg.WatchKeyFile,
+			cfg.CAFile,
+			"",
+		)
+		if err != nil {
+			logger.Fatalf("Invalid watch TLS config: %s", err)
+		}
 	}
 
 	metricIngressTLS, err := api.NewMutualTLSConfig(
@@ -51,7 +71,7 @@ func main() {
 		cfg.MetricIngressCN,
 	)
 	if err != nil {
-		log.Fatalf("Invalid Metric Ingress TLS config: %s", err)
+		logger.Fatalf("Invalid Metric Ingress TLS config: %s", err)
 	}
 
 	logClient, err := loggregator.NewIngressClient(
@@ -60,7 +80,7 @@ func main() {
 		loggregator.WithAddr(cfg.MetricIngressAddr),
 	)
 	if err != nil {
-		log.Fatalf("Couldn't connect to metric ingress server: %s", err)
+		logger.Fatalf("Couldn't connect to metric ingress server: %s", err)
 	}
 
 	// metric-documentation-v2: setup function
@@ -80,14 +100,19 @@ func main() {
 		app.WithHTTPClient(api.NewHTTPSClient(apiTLSConfig, 5*time.Second)),
 		app.WithBlacklist(cfg.Blacklist),
 		app.WithPollingInterval(cfg.APIPollingInterval),
+		app.WithHandoffTimeout(cfg.HandoffTimeout),
+		app.WithWatchAddr(cfg.WatchHostport, watchTLSConfig),
+		app.WithMaxSkippedTerms(cfg.MaxSkippedTerms),
+		app.WithMaxPartialFetches(cfg.MaxPartialFetches),
+		app.WithLogger(logger),
 	)
 	scheduler.Start()
 
 	lis, err := net.Listen("tcp", cfg.PprofHostport)
 	if err != nil {
-		log.Printf("Error creating pprof listener: %s", err)
+		logger.Fatalf("error creating pprof listener: %s", err)
 	}
 
-	log.Printf("Starting pprof server on: %s", lis.Addr().String())
-	log.Println(http.Serve(lis, nil))
+	logger.Infof("starting pprof server on: %s", lis.Addr().String())
+	logger.Errorf("pprof server closing: %s", http.Serve(lis, nil))
 }